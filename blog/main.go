@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"github.com/Jerry20000730/Gjango/web"
 	context "github.com/Jerry20000730/Gjango/web/Context"
-	"log"
+	"github.com/Jerry20000730/Gjango/web/OpenAPI"
 	"net/http"
 )
 
@@ -27,6 +27,7 @@ func main() {
 	engine := web.NewEngine()
 	fmt.Println("[INFO] Gjango is listening on port: " + engine.GetPort())
 	g := engine.Router.NewGroup("user")
+	g.MiddlewareRegister(web.Recovery())
 	g.MiddlewareRegister(func(next web.Handler) web.Handler {
 		return func(ctx *context.Context) {
 			// pre-middleware
@@ -53,35 +54,26 @@ func main() {
 	})
 
 	engine.PreLoadTemplate("template/*.html")
-	g.Get("/template", func(ctx *context.Context) {
+	g.Get("/template", web.Fallible(func(ctx *context.Context) error {
 		user := &User{
 			Name: "jerry",
 		}
-		err := ctx.HTMLTemplate(&engine.HTMLPreloader, http.StatusOK, "login.html", user)
-		if err != nil {
-			log.Println(err)
-		}
-	})
+		return ctx.HTMLTemplate(&engine.HTMLPreloader, http.StatusOK, "login.html", user)
+	}))
 
-	g.Get("/json", func(ctx *context.Context) {
+	g.Get("/json", web.Fallible(func(ctx *context.Context) error {
 		user := &User{
 			Name: "jerry",
 		}
-		err := ctx.JSON(http.StatusOK, user)
-		if err != nil {
-			log.Println(err)
-		}
-	})
+		return ctx.JSON(http.StatusOK, user)
+	}))
 
-	g.Get("/xml", func(ctx *context.Context) {
+	g.Get("/xml", web.Fallible(func(ctx *context.Context) error {
 		user := &User{
 			Name: "jerry",
 		}
-		err := ctx.XML(http.StatusOK, user)
-		if err != nil {
-			log.Println(err)
-		}
-	})
+		return ctx.XML(http.StatusOK, user)
+	}))
 
 	g.Get("/download", func(ctx *context.Context) {
 		engine.FileManager.FileAttachment(ctx, "template/test.xlsx", "xxxx.xlsx")
@@ -89,13 +81,10 @@ func main() {
 	g.Get("/fs", func(ctx *context.Context) {
 		engine.FileManager.FileFromFileSystem(ctx, "test.xlsx", http.Dir("template"))
 	})
-	g.Get("/redirect", func(ctx *context.Context) {
+	g.Get("/redirect", web.Fallible(func(ctx *context.Context) error {
 		// status must be 302
-		err := ctx.Redirect(http.StatusFound, "/user/template")
-		if err != nil {
-			log.Println(err)
-		}
-	})
+		return ctx.Redirect(http.StatusFound, "/user/template")
+	}))
 	g.Get("/string", func(ctx *context.Context) {
 		_ = ctx.String(http.StatusOK, "Test %s gjango web framework, int can also be passed: %d", "self-designed", 1)
 	})
@@ -111,31 +100,31 @@ func main() {
 		m, _ := ctx.GetPostFormMap("user")
 		ctx.JSON(http.StatusOK, m)
 	})
-	g.Post("/file", func(ctx *context.Context) {
+	g.Post("/file", web.Fallible(func(ctx *context.Context) error {
 		file, err := ctx.FormFile("file")
 		if err != nil {
-			log.Println(err)
-		}
-		err = ctx.SaveUploadedFile(file, "./upload/"+file.Filename)
-		if err != nil {
-			log.Println(err)
+			return web.NewHTTPError(http.StatusBadRequest, "file_missing", "a file is required").Wrap(err)
 		}
-	})
+		_, err = ctx.SaveUploadedFileIn(file, context.SaveOptions{BaseDir: "./upload"})
+		return err
+	}))
 	g.Post("/multiFile", func(ctx *context.Context) {
 		m, _ := ctx.GetPostFormMap("user")
 		headers := ctx.FormFiles("file")
 		for _, file := range headers {
-			ctx.SaveUploadedFile(file, "./upload/"+file.Filename)
+			ctx.SaveUploadedFileIn(file, context.SaveOptions{BaseDir: "./upload"})
 		}
 		ctx.JSON(http.StatusOK, m)
 	})
-	g.Post("/jsonParse", func(ctx *context.Context) {
-		user := &User{}
-		err := ctx.ParseJSON(user, true, true)
-		if err != nil {
-			log.Println(err)
-		}
-		ctx.JSON(http.StatusOK, user)
+	web.Handle(g, http.MethodPost, "/jsonParse", func(ctx *context.Context, user User) (User, error) {
+		return user, nil
+	}, web.HandleOptions{TypedOptions: web.TypedOptions{DisallowUnknownFields: true}})
+	g.Describe(http.MethodPost, "/jsonParse", web.RouteDoc{Summary: "Echo a user"})
+	rpc := g.JSONRPC("/rpc", nil)
+	rpc.Register("echo", func(ctx *context.Context, user User) (User, error) {
+		return user, nil
 	})
+	docs := engine.Router.NewGroup("docs")
+	docs.Get("/openapi.json", engine.OpenAPIHandler(OpenAPI.Info{Title: "Blog", Version: "1.0.0"}))
 	engine.Run()
 }
@@ -0,0 +1,186 @@
+package Binding
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldInfo is the binding metadata of a single struct field, computed once per type.
+type fieldInfo struct {
+	name     string       // name is the key of the field in requests (json tag or Go name).
+	formName string       // formName is the key of the field in query and form values (form tag or name).
+	position int          // position is the position of the field in structInfo.fields.
+	index    []int        // index is the path to the field, through embedded structs if needed.
+	typ      reflect.Type // typ is the type of the field.
+	tag      reflect.StructTag
+	rules    []Rule // rules are the parsed gjango rules, without "required".
+	required bool   // required is true if the field must be present in the request.
+	untagged bool   // untagged is true if the field has no json tag.
+	quoted   bool   // quoted is true if the value is encoded within a JSON string (the ",string" option).
+}
+
+// structInfo is the binding metadata of a struct type.
+type structInfo struct {
+	fields []*fieldInfo
+	byName map[string]*fieldInfo
+	// presence reports whether the presence of some fields is checked: the required and the
+	// untagged ones.
+	presence bool
+}
+
+// lookup finds the field bound to key, preferring an exact match and falling back to
+// a case-insensitive one, like encoding/json does.
+func (s *structInfo) lookup(key string) *fieldInfo {
+	if f, ok := s.byName[key]; ok {
+		return f
+	}
+	for _, f := range s.fields {
+		if strings.EqualFold(f.name, key) {
+			return f
+		}
+	}
+	return nil
+}
+
+// structCache maps a reflect.Type to its *structInfo.
+var structCache sync.Map
+
+// cachedStructInfo returns the metadata of struct type t, computing it on first use.
+func cachedStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{byName: make(map[string]*fieldInfo)}
+	collectFields(info, t, nil)
+	for _, f := range info.fields {
+		info.presence = info.presence || f.required || f.untagged
+	}
+	actual, _ := structCache.LoadOrStore(t, info)
+	return actual.(*structInfo)
+}
+
+// collectFields adds the fields of t to info. Fields of embedded structs without a json tag
+// are promoted, unless a field with the same name has already been declared by an outer struct.
+// Fields bound from the path, the query or the headers (see SOURCE_TAGS) are left out.
+func collectFields(info *structInfo, t reflect.Type, index []int) {
+	embedded := make([]reflect.StructField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName, jsonOptions, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Anonymous && jsonName == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				field.Index = fieldIndex
+				embedded = append(embedded, field)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if source, _ := SourceTag(field); source != "" {
+			// bound by BindSources
+			continue
+		}
+		name := FieldName(field)
+		if _, ok := info.byName[name]; ok {
+			continue
+		}
+		rules := ParseRules(field.Tag.Get("gjango"))
+		formName, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if formName == "" || formName == "-" {
+			formName = name
+		}
+		f := &fieldInfo{
+			name:     name,
+			formName: formName,
+			position: len(info.fields),
+			index:    fieldIndex,
+			typ:      field.Type,
+			tag:      field.Tag,
+			rules:    make([]Rule, 0, len(rules)),
+			untagged: jsonName == "",
+			quoted:   quotable(field.Type) && hasOption(jsonOptions, "string"),
+		}
+		for _, rule := range rules {
+			if rule.Name == "required" {
+				f.required = true
+				continue
+			}
+			f.rules = append(f.rules, rule)
+		}
+		info.fields = append(info.fields, f)
+		info.byName[name] = f
+	}
+	for _, field := range embedded {
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		collectFields(info, ft, field.Index)
+	}
+}
+
+// quotable reports whether the ",string" option of encoding/json applies to the values of type t.
+func quotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// hasOption reports whether the comma-separated options of a tag include option.
+func hasOption(options string, option string) bool {
+	for options != "" {
+		var name string
+		name, options, _ = strings.Cut(options, ",")
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex returns the field of v at index, allocating nil embedded pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexNoAlloc is like fieldByIndex but reports false instead of allocating
+// when it meets a nil embedded pointer.
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,341 @@
+package Binding
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseNested decodes query or form values written in bracket notation into nested maps
+// and slices, following the conventions of Rails and PHP:
+//
+//	name=jerry                    {"name": "jerry"}
+//	user[address][city]=Paris     {"user": {"address": {"city": "Paris"}}}
+//	tags[]=a&tags[]=b             {"tags": ["a", "b"]}
+//	items[0][name]=pen            {"items": [{"name": "pen"}]}
+//	items[][name]=pen&items[][name]=ink
+//	                              {"items": [{"name": "pen"}, {"name": "ink"}]}
+//
+// Maps whose keys are all indexes become slices, ordered by index. A key given several times
+// without a trailing "[]" becomes a slice of all its values. Keys with unbalanced brackets
+// are kept as they are.
+func ParseNested(values url.Values) map[string]any {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// in lexical order, a key comes before the keys it prefixes ("user" before "user[name]"),
+	// so that nested keys win over a scalar of the same name, whatever the order of the map
+	sort.Strings(keys)
+	root := make(map[string]any)
+	for _, key := range keys {
+		segments := splitKey(key)
+		vals := values[key]
+		if len(vals) == 0 {
+			continue
+		}
+		if !hasInnerAppend(segments) {
+			insert(root, segments, leaf(segments, vals))
+			continue
+		}
+		// "items[][name]": the i-th value goes into the i-th element
+		for i, value := range vals {
+			insert(root, replaceAppend(segments, i), leaf(segments, []string{value}))
+		}
+	}
+	return compact(root).(map[string]any)
+}
+
+// splitKey splits "user[address][city]" into ["user", "address", "city"] and "tags[]" into ["tags", ""].
+func splitKey(key string) []string {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}
+	}
+	segments := []string{key[:i]}
+	rest := key[i:]
+	for rest != "" {
+		if rest[0] != '[' {
+			return []string{key}
+		}
+		j := strings.IndexByte(rest, ']')
+		if j < 0 {
+			return []string{key}
+		}
+		segments = append(segments, rest[1:j])
+		rest = rest[j+1:]
+	}
+	return segments
+}
+
+// hasInnerAppend reports whether an empty segment ("[]") appears before the last segment.
+func hasInnerAppend(segments []string) bool {
+	if len(segments) < 3 {
+		return false
+	}
+	for _, segment := range segments[1 : len(segments)-1] {
+		if segment == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceAppend replaces the inner empty segments by index.
+func replaceAppend(segments []string, index int) []string {
+	replaced := append([]string{}, segments...)
+	for i := 1; i < len(replaced)-1; i++ {
+		if replaced[i] == "" {
+			replaced[i] = strconv.Itoa(index)
+		}
+	}
+	return replaced
+}
+
+// leaf turns the values of a key into the value stored in the tree.
+func leaf(segments []string, values []string) any {
+	if len(segments) > 1 && segments[len(segments)-1] == "" {
+		list := make([]any, len(values))
+		for i, value := range values {
+			list[i] = value
+		}
+		return list
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	list := make([]any, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	return list
+}
+
+// insert stores value in the tree at the path given by segments.
+func insert(node map[string]any, segments []string, value any) {
+	if len(segments) > 1 && segments[len(segments)-1] == "" {
+		segments = segments[:len(segments)-1]
+	}
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := node[segment].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			node[segment] = child
+		}
+		node = child
+	}
+	last := segments[len(segments)-1]
+	if _, ok := node[last].(map[string]any); ok {
+		// a nested key has already been stored under this name
+		return
+	}
+	if existing, ok := node[last].([]any); ok {
+		if list, ok := value.([]any); ok {
+			node[last] = append(existing, list...)
+			return
+		}
+	}
+	node[last] = value
+}
+
+// compact turns maps whose keys are all indexes into slices, recursively.
+func compact(node any) any {
+	m, ok := node.(map[string]any)
+	if !ok {
+		return node
+	}
+	indexes := make([]int, 0, len(m))
+	for key, value := range m {
+		m[key] = compact(value)
+		if index, err := strconv.Atoi(key); err == nil && index >= 0 && indexes != nil {
+			indexes = append(indexes, index)
+		} else {
+			indexes = nil
+		}
+	}
+	if len(indexes) == 0 {
+		return m
+	}
+	sort.Ints(indexes)
+	list := make([]any, len(indexes))
+	for i, index := range indexes {
+		list[i] = m[strconv.Itoa(index)]
+	}
+	return list
+}
+
+// BindValues binds query or form values into obj, which must be a non-nil pointer.
+// Keys are decoded with ParseNested, so nested structs, slices and maps can be bound from
+// bracket notation. A struct field is bound from the key given by its form tag, or else by its
+// json tag, or else by its name. The rules of the gjango tags are checked while binding.
+//
+// Example:
+//
+//	type Filter struct {
+//		Name    string `form:"name" gjango:"required"`
+//		Address struct {
+//			City string `form:"city"`
+//		} `form:"address"`
+//		Items []struct {
+//			ID int `form:"id"`
+//		} `form:"items"`
+//	}
+//	// ?name=jerry&address[city]=Paris&items[0][id]=1&items[1][id]=2
+func BindValues(values url.Values, obj any) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return I18n.NewError(I18n.BIND_NOT_POINTER, nil)
+	}
+	return bindTree(value.Elem(), ParseNested(values), "")
+}
+
+func bindTree(v reflect.Value, tree any, path string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return bindTree(v.Elem(), tree, path)
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			s, ok := tree.(string)
+			if !ok {
+				return mismatch(path, v.Type(), treeKind(tree), nil)
+			}
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return mismatch(path, v.Type(), "string", err)
+			}
+			return nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(tree))
+		return nil
+	case reflect.Struct:
+		m, ok := tree.(map[string]any)
+		if !ok {
+			return mismatch(path, v.Type(), treeKind(tree), nil)
+		}
+		return bindStruct(v, m, path)
+	case reflect.Map:
+		if list, ok := tree.([]any); ok {
+			// index keys were compacted into a list, turn them back into keys
+			m := make(map[string]any, len(list))
+			for i, item := range list {
+				m[strconv.Itoa(i)] = item
+			}
+			tree = m
+		}
+		m, ok := tree.(map[string]any)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return mismatch(path, v.Type(), treeKind(tree), nil)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for key, subtree := range m {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := bindTree(elem, subtree, joinPath(path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		list, ok := tree.([]any)
+		if !ok {
+			// a single value binds into a slice of one element
+			list = []any{tree}
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(list), len(list)))
+		}
+		for i := 0; i < len(list) && i < v.Len(); i++ {
+			if err := bindTree(v.Index(i), list[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s, ok := tree.(string)
+	if !ok {
+		return mismatch(path, v.Type(), treeKind(tree), nil)
+	}
+	if err := setString(v, s); err != nil {
+		return mismatch(path, v.Type(), "string", err)
+	}
+	return nil
+}
+
+func bindStruct(v reflect.Value, m map[string]any, path string) error {
+	for _, f := range cachedStructInfo(v.Type()).fields {
+		subtree, ok := m[f.formName]
+		name := joinPath(path, f.formName)
+		if !ok {
+			if f.required {
+				return I18n.NewError(I18n.VALIDATE_REQUIRED, map[string]any{"field": name})
+			}
+			continue
+		}
+		fieldValue := fieldByIndex(v, f.index)
+		if err := bindTree(fieldValue, subtree, name); err != nil {
+			return err
+		}
+		if err := checkRules(f.rules, fieldValue, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setString parses s into the scalar value v.
+func setString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// treeKind describes a node of a tree built by ParseNested.
+func treeKind(tree any) string {
+	switch tree.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return "string"
+}
@@ -0,0 +1,100 @@
+package Binding
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseNested(t *testing.T) {
+	cases := map[string]string{
+		"name=jerry":              `{"name":"jerry"}`,
+		"user[id]=1&user[age]=29": `{"user":{"age":"29","id":"1"}}`,
+		"user[address][city]=Paris&user[address][zip]=75001": `{"user":{"address":{"city":"Paris","zip":"75001"}}}`,
+		"tags[]=a&tags[]=b":                     `{"tags":["a","b"]}`,
+		"ids=1&ids=2":                           `{"ids":["1","2"]}`,
+		"items[1][name]=ink&items[0][name]=pen": `{"items":[{"name":"pen"},{"name":"ink"}]}`,
+		"items[][name]=pen&items[][price]=1&items[][name]=ink&items[][price]=2": `{"items":[{"name":"pen","price":"1"},{"name":"ink","price":"2"}]}`,
+		"a=1&a[b]=2": `{"a":{"b":"2"}}`,
+		"broken[a=1": `{"broken[a":"1"}`,
+	}
+	for query, expected := range cases {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := json.Marshal(ParseNested(values))
+		if string(b) != expected {
+			t.Errorf("%s: expected %s, got %s", query, expected, b)
+		}
+	}
+}
+
+type filter struct {
+	Name    string `form:"name" gjango:"required"`
+	Address struct {
+		City string `form:"city" gjango:"min=2"`
+	} `form:"address"`
+	Items []struct {
+		ID    int     `form:"id"`
+		Price float64 `form:"price"`
+	} `form:"items"`
+	Tags []string `form:"tags"`
+}
+
+func TestBindValues(t *testing.T) {
+	values, _ := url.ParseQuery("name=jerry&address[city]=Paris&items[0][id]=1&items[1][id]=2&items[1][price]=9.5&tags[]=a")
+	f := &filter{}
+	if err := BindValues(values, f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "jerry" || f.Address.City != "Paris" || len(f.Items) != 2 || f.Items[1].Price != 9.5 || len(f.Tags) != 1 {
+		t.Errorf("unexpected binding: %+v", f)
+	}
+
+	values, _ = url.ParseQuery("name=jerry&items[0][id]=x")
+	err := BindValues(values, &filter{})
+	if err == nil || err.Error() != "field [items[0].id] must be of type int, got string" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	values, _ = url.ParseQuery("address[city]=P")
+	if err := BindValues(values, &filter{}); err == nil || err.Error() != "field [name] is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type getArticle struct {
+	ID      int      `path:"id" gjango:"required"`
+	Tags    []string `query:"tag"`
+	Version string   `header:"x-api-version" gjango:"enum=1|2"`
+	Title   string   `json:"title"`
+}
+
+func TestBindSources(t *testing.T) {
+	sources := Sources{
+		Path:   map[string]string{"id": "42"},
+		Query:  url.Values{"tag": {"go"}},
+		Header: http.Header{"X-Api-Version": {"2"}},
+	}
+	a := &getArticle{}
+	if err := BindSources(a, sources); err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 42 || len(a.Tags) != 1 || a.Tags[0] != "go" || a.Version != "2" {
+		t.Errorf("unexpected binding: %+v", a)
+	}
+	if err := DecodeJSON(json.NewDecoder(strings.NewReader(`{"title": "hello", "id": 7}`)), a, false); err != nil || a.ID != 42 || a.Title != "hello" {
+		t.Errorf("the body should not bind the path: %+v, %v", a, err)
+	}
+
+	sources.Query = url.Values{"tag": {"go", "web"}}
+	sources.Header.Set("X-Api-Version", "3")
+	if err := BindSources(&getArticle{}, sources); err == nil || !strings.Contains(err.Error(), "X-Api-Version") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := BindSources(&getArticle{}, Sources{}); err == nil || err.Error() != "field [id] is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
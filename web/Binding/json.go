@@ -0,0 +1,482 @@
+package Binding
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"reflect"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// DecodeJSON decodes the next JSON value of decoder into obj, which must be a non-nil pointer,
+// and validates it against the gjango tags of obj in the same pass.
+//
+// The value is read off decoder as raw bytes, which encoding/json only scans for the end of
+// the value and its syntax. They are then decoded into obj and validated in a single walk
+// (see jsonDecoder), guided by the metadata cached per type: every field is checked against
+// its rules as soon as it is decoded, and the presence of the required fields once their
+// object has been read. No intermediate map is built and numbers keep their precision. An
+// explicit null counts as missing for the required fields and the pointers, slices and maps.
+//
+// Type mismatches and unknown fields are reported as *I18n.Error carrying the JSON path of
+// the value, e.g. "items[2].price". The values of types implementing json.Unmarshaler or
+// encoding.TextUnmarshaler, of interfaces and of maps whose keys are not strings are handed
+// to encoding/json.
+func DecodeJSON(decoder *json.Decoder, obj any, disallowUnknownFields bool) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return I18n.NewError(I18n.BIND_NOT_POINTER, nil)
+	}
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	d := &jsonDecoder{data: raw, disallowUnknownFields: disallowUnknownFields}
+	return d.decode(value.Elem())
+}
+
+// jsonDecoder decodes a JSON value into a Go value and validates it against the gjango tags
+// of its type on the way. The value has been read off a json.Decoder, so it is known to be
+// valid JSON, and only its shape has to be checked against the one of the Go value. The
+// errors name the fields relative to the value being decoded, and are placed within their
+// parents on the way up (see within), so that no path is built unless there is an error.
+type jsonDecoder struct {
+	data                  []byte
+	pos                   int
+	disallowUnknownFields bool
+}
+
+// walkableTypes caches walkable, called for every value of a document.
+var walkableTypes sync.Map
+
+// walkable reports whether values of type t are decoded by jsonDecoder, rather than handed
+// to encoding/json.
+func walkable(t reflect.Type) bool {
+	if w, ok := walkableTypes.Load(t); ok {
+		return w.(bool)
+	}
+	w := isWalkable(t)
+	walkableTypes.Store(t, w)
+	return w
+}
+
+func isWalkable(t reflect.Type) bool {
+	if t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) ||
+		t.Implements(textUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return walkable(t.Elem())
+	case reflect.Struct, reflect.Array, reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		// []byte is a base64 string in JSON
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Map:
+		// encoding/json decodes the keys implementing encoding.TextUnmarshaler with it
+		return t.Key().Kind() == reflect.String && !reflect.PointerTo(t.Key()).Implements(textUnmarshalerType)
+	}
+	return false
+}
+
+// decode decodes the next value of the document into v.
+func (d *jsonDecoder) decode(v reflect.Value) error {
+	d.space()
+	if !walkable(v.Type()) {
+		return d.unmarshal(v)
+	}
+	if d.data[d.pos] == 'n' {
+		// null leaves the other values untouched, like encoding/json does
+		d.pos += len("null")
+		if nillable(v.Type()) {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Struct:
+		return d.decodeStruct(v)
+	case reflect.Slice:
+		return d.decodeSlice(v)
+	case reflect.Array:
+		return d.decodeArray(v)
+	case reflect.Map:
+		return d.decodeMap(v)
+	case reflect.String:
+		return d.decodeString(v)
+	case reflect.Bool:
+		return d.decodeBool(v)
+	}
+	return d.decodeNumber(v)
+}
+
+// unmarshal hands the next value of the document to encoding/json.
+func (d *jsonDecoder) unmarshal(v reflect.Value) error {
+	start := d.pos
+	d.skip()
+	err := json.Unmarshal(d.data[start:d.pos], v.Addr().Interface())
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return mismatch(typeError.Field, typeError.Type, typeError.Value, err)
+	}
+	return err
+}
+
+func (d *jsonDecoder) decodeStruct(v reflect.Value) error {
+	if d.data[d.pos] != '{' {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	info := cachedStructInfo(v.Type())
+	var present []bool
+	if info.presence {
+		present = make([]bool, len(info.fields))
+	}
+	d.pos++
+	for d.next('}') {
+		key := d.key()
+		f, ok := info.byName[string(key)]
+		if !ok {
+			f = info.lookup(string(key))
+		}
+		if f == nil {
+			if d.disallowUnknownFields {
+				return I18n.NewError(I18n.BIND_UNKNOWN_FIELD, map[string]any{"field": string(key)})
+			}
+			d.skip()
+			continue
+		}
+		fieldValue := fieldByIndex(v, f.index)
+		d.space()
+		if d.data[d.pos] == 'n' && (f.required || nillable(f.typ)) {
+			// an explicit null counts as missing
+			_ = d.decode(fieldValue)
+			continue
+		}
+		decode := d.decode
+		if f.quoted {
+			decode = d.decodeQuoted
+		}
+		if err := decode(fieldValue); err != nil {
+			return within(err, f.name)
+		}
+		if present != nil {
+			present[f.position] = true
+		}
+		if err := checkRules(f.rules, fieldValue, f.name); err != nil {
+			return err
+		}
+	}
+	for _, f := range info.fields {
+		if present == nil || present[f.position] {
+			continue
+		}
+		if f.required {
+			return I18n.NewError(I18n.VALIDATE_REQUIRED, map[string]any{"field": f.name})
+		}
+		if f.untagged {
+			// fields without a json tag have always been expected in validated payloads
+			return I18n.NewError(I18n.VALIDATE_MISSING, map[string]any{"field": f.name})
+		}
+	}
+	return nil
+}
+
+func (d *jsonDecoder) decodeSlice(v reflect.Value) error {
+	if d.data[d.pos] != '[' {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	v.SetLen(0)
+	d.pos++
+	for i := 0; d.next(']'); i++ {
+		if i >= v.Cap() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		} else {
+			v.SetLen(i + 1)
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+		if err := d.decode(v.Index(i)); err != nil {
+			return within(err, "["+strconv.Itoa(i)+"]")
+		}
+	}
+	return nil
+}
+
+func (d *jsonDecoder) decodeArray(v reflect.Value) error {
+	if d.data[d.pos] != '[' {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	d.pos++
+	i := 0
+	for ; d.next(']'); i++ {
+		if i >= v.Len() {
+			// like encoding/json, extra elements are discarded
+			d.skip()
+			continue
+		}
+		if err := d.decode(v.Index(i)); err != nil {
+			return within(err, "["+strconv.Itoa(i)+"]")
+		}
+	}
+	for ; i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	return nil
+}
+
+func (d *jsonDecoder) decodeMap(v reflect.Value) error {
+	if d.data[d.pos] != '{' {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	d.pos++
+	for d.next('}') {
+		key := string(d.key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := d.decode(elem); err != nil {
+			return within(err, key)
+		}
+		v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+	}
+	return nil
+}
+
+// decodeQuoted decodes the next value of the document, a JSON value encoded within a JSON
+// string (the ",string" option of encoding/json), into v.
+func (d *jsonDecoder) decodeQuoted(v reflect.Value) error {
+	if d.data[d.pos] != '"' {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	quoted := d.string()
+	if !json.Valid(quoted) {
+		return mismatch("", v.Type(), "string "+string(quoted), nil)
+	}
+	inner := &jsonDecoder{data: quoted}
+	return inner.decode(v)
+}
+
+func (d *jsonDecoder) decodeString(v reflect.Value) error {
+	if d.data[d.pos] != '"' {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	v.SetString(string(d.string()))
+	return nil
+}
+
+func (d *jsonDecoder) decodeBool(v reflect.Value) error {
+	switch d.data[d.pos] {
+	case 't':
+		d.pos += len("true")
+		v.SetBool(true)
+	case 'f':
+		d.pos += len("false")
+		v.SetBool(false)
+	default:
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	return nil
+}
+
+// decodeNumber decodes the next value of the document into v, an integer or a float.
+func (d *jsonDecoder) decodeNumber(v reflect.Value) error {
+	c := d.data[d.pos]
+	if c != '-' && (c < '0' || c > '9') {
+		return mismatch("", v.Type(), d.kind(), nil)
+	}
+	start := d.pos
+	d.skip()
+	number := string(d.data[start:d.pos])
+	var overflow bool
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(number, 10, 64)
+		if overflow = err != nil || v.OverflowInt(n); !overflow {
+			v.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(number, 10, 64)
+		if overflow = err != nil || v.OverflowUint(n); !overflow {
+			v.SetUint(n)
+		}
+	default:
+		n, err := strconv.ParseFloat(number, v.Type().Bits())
+		if overflow = err != nil || v.OverflowFloat(n); !overflow {
+			v.SetFloat(n)
+		}
+	}
+	if overflow {
+		// in the words of json.UnmarshalTypeError
+		return mismatch("", v.Type(), "number "+number, nil)
+	}
+	return nil
+}
+
+// kind describes the next value of the document, in the words of json.UnmarshalTypeError.
+func (d *jsonDecoder) kind() string {
+	switch d.data[d.pos] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// next moves to the next member of an object or element of an array, and reports false once
+// it has moved past the end of the object or the array, close.
+func (d *jsonDecoder) next(close byte) bool {
+	d.space()
+	if d.data[d.pos] == ',' {
+		d.pos++
+		d.space()
+	}
+	if d.data[d.pos] == close {
+		d.pos++
+		return false
+	}
+	return true
+}
+
+// key reads the key of the next member of an object, and moves past its colon.
+func (d *jsonDecoder) key() []byte {
+	key := d.string()
+	d.space()
+	d.pos++
+	return key
+}
+
+// string reads the string starting at the current position, and returns its unescaped bytes.
+// The bytes of a string without escapes nor invalid UTF-8 are returned as they are, without
+// copying them.
+func (d *jsonDecoder) string() []byte {
+	start := d.pos
+	escaped, ascii := false, true
+	for d.pos++; d.data[d.pos] != '"'; d.pos++ {
+		switch c := d.data[d.pos]; {
+		case c == '\\':
+			escaped = true
+			d.pos++
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	d.pos++
+	if !escaped && (ascii || utf8.Valid(d.data[start+1:d.pos-1])) {
+		return d.data[start+1 : d.pos-1]
+	}
+	// escapes, and invalid UTF-8 replaced with U+FFFD, the way encoding/json does it
+	var s string
+	_ = json.Unmarshal(d.data[start:d.pos], &s)
+	return []byte(s)
+}
+
+// skip moves past the next value.
+func (d *jsonDecoder) skip() {
+	d.space()
+	depth := 0
+	for {
+		switch d.data[d.pos] {
+		case '"':
+			d.string()
+			if depth == 0 {
+				return
+			}
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				d.pos++
+				return
+			}
+		default:
+			if depth == 0 {
+				// a number, true, false or null
+				for d.pos < len(d.data) && !bytes.ContainsRune([]byte(",}] \t\r\n"), rune(d.data[d.pos])) {
+					d.pos++
+				}
+				return
+			}
+		}
+		d.pos++
+	}
+}
+
+func (d *jsonDecoder) space() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\r', '\n':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// within places the field of err, named relative to a value, within the parent of the value,
+// segment being the name of the value in its parent: "name" within "[0]" is "[0].name", and
+// "[0].name" within "items" is "items[0].name". The errors about the value itself, named "$",
+// take the name of the value.
+func within(err error, segment string) error {
+	var e *I18n.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	switch field := e.Field(); {
+	case field == "" || field == "$":
+		e.Params["field"] = segment
+	case field[0] == '[':
+		e.Params["field"] = segment + field
+	default:
+		e.Params["field"] = segment + "." + field
+	}
+	return err
+}
+
+func nillable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func mismatch(path string, expected reflect.Type, actual string, err error) error {
+	if path == "" {
+		path = "$"
+	}
+	return &I18n.Error{
+		Key:    I18n.BIND_TYPE_MISMATCH,
+		Params: map[string]any{"field": path, "expected": expected.String(), "actual": actual},
+		Err:    err,
+	}
+}
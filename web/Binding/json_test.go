@@ -0,0 +1,246 @@
+package Binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Name  string  `json:"name" gjango:"required,min=2"`
+	Price float64 `json:"price" gjango:"min=0"`
+}
+
+type order struct {
+	ID    int64    `json:"id" gjango:"required"`
+	Email string   `json:"email" gjango:"required,pattern=^[^@]+@[^@]+$"`
+	Tags  []string `json:"tags" gjango:"max=3"`
+	Items []item   `json:"items" gjango:"required"`
+}
+
+func decodeString(obj any, body string, disallowUnknownFields bool) error {
+	return DecodeJSON(json.NewDecoder(strings.NewReader(body)), obj, disallowUnknownFields)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	o := &order{}
+	body := `{"id": 9007199254740993, "email": "a@b.c", "tags": ["x"], "items": [{"name": "pen", "price": 1.5}]}`
+	if err := decodeString(o, body, true); err != nil {
+		t.Fatal(err)
+	}
+	// 2^53 + 1 cannot be represented by a float64
+	if o.ID != 9007199254740993 {
+		t.Errorf("id lost precision: %d", o.ID)
+	}
+	if len(o.Items) != 1 || o.Items[0].Name != "pen" || o.Items[0].Price != 1.5 {
+		t.Errorf("unexpected items: %+v", o.Items)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	cases := []struct {
+		body  string
+		key   string
+		field string
+	}{
+		{`{"email": "a@b.c", "items": []}`, I18n.VALIDATE_REQUIRED, "id"},
+		{`{"id": null, "email": "a@b.c", "items": []}`, I18n.VALIDATE_REQUIRED, "id"},
+		{`{"id": 1, "email": "a@b.c", "items": [{"price": 1}]}`, I18n.VALIDATE_REQUIRED, "items[0].name"},
+		{`{"id": 1, "email": "a@b.c", "items": [{"name": "pen"}, {"name": "x"}]}`, I18n.VALIDATE_MIN, "items[1].name"},
+		{`{"id": 1, "email": "nope", "items": []}`, I18n.VALIDATE_PATTERN, "email"},
+		{`{"id": 1, "email": "a@b.c", "tags": ["a", "b", "c", "d"], "items": []}`, I18n.VALIDATE_MAX, "tags"},
+		{`{"id": "1", "email": "a@b.c", "items": []}`, I18n.BIND_TYPE_MISMATCH, "id"},
+		{`{"id": 1, "email": "a@b.c", "items": [{"name": "pen", "price": "free"}]}`, I18n.BIND_TYPE_MISMATCH, "items[0].price"},
+		{`{"id": 1, "email": "a@b.c", "items": {}}`, I18n.BIND_TYPE_MISMATCH, "items"},
+		{`{"id": 1, "email": "a@b.c", "items": [], "extra": true}`, I18n.BIND_UNKNOWN_FIELD, "extra"},
+	}
+	for _, c := range cases {
+		err := decodeString(&order{}, c.body, true)
+		var e *I18n.Error
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expected a catalog error, got %v", c.body, err)
+			continue
+		}
+		if e.Key != c.key || e.Field() != c.field {
+			t.Errorf("%s: expected %s on %s, got %s on %s (%v)", c.body, c.key, c.field, e.Key, e.Field(), err)
+		}
+	}
+}
+
+func TestDecodeJSONSlice(t *testing.T) {
+	items := make([]item, 0)
+	if err := decodeString(&items, `[{"name": "pen"}, {"name": "ink", "price": 2}]`, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].Price != 2 {
+		t.Errorf("unexpected items: %+v", items)
+	}
+	err := decodeString(&items, `[{"name": "pen"}, {"price": 2}]`, false)
+	if err == nil || err.Error() != "field [[1].name] is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type address struct {
+	City string `json:"city" gjango:"required"`
+}
+
+type shipment struct {
+	Address address         `json:"address" gjango:"required"`
+	Stock   map[string]item `json:"stock"`
+}
+
+// shipmentRequest has a field bound from the path, which the body must not fill.
+type shipmentRequest struct {
+	ID      int     `path:"id"`
+	Address address `json:"address" gjango:"required"`
+}
+
+func TestDecodeJSONRequiredStruct(t *testing.T) {
+	cases := []struct {
+		body  string
+		key   string
+		field string
+	}{
+		{`{}`, I18n.VALIDATE_REQUIRED, "address"},
+		{`{"address": null}`, I18n.VALIDATE_REQUIRED, "address"},
+		{`{"address": {}}`, I18n.VALIDATE_REQUIRED, "address.city"},
+		{`{"address": {"city": "P"}, "stock": {"p\u00e9n": {"name": "x"}}}`, I18n.VALIDATE_MIN, "stock.pén.name"},
+	}
+	for _, obj := range []func() any{func() any { return &shipment{} }, func() any { return &shipmentRequest{} }} {
+		for _, c := range cases {
+			if _, ok := obj().(*shipmentRequest); ok && strings.Contains(c.body, "stock") {
+				continue
+			}
+			err := decodeString(obj(), c.body, false)
+			var e *I18n.Error
+			if !errors.As(err, &e) || e.Key != c.key || e.Field() != c.field {
+				t.Errorf("%T %s: expected %s on %s, got %v", obj(), c.body, c.key, c.field, err)
+			}
+		}
+	}
+	s := &shipment{}
+	if err := decodeString(s, `{"address": {"city": "P"}, "stock": {"pen": {"name": "pen"}}}`, false); err != nil || s.Stock["pen"].Name != "pen" {
+		t.Errorf("unexpected shipment: %+v, %v", s, err)
+	}
+	r := &shipmentRequest{}
+	if err := decodeString(r, `{"ID": 5, "address": {"city": "P"}}`, false); err != nil || r.ID != 0 {
+		t.Errorf("unexpected request: %+v, %v", r, err)
+	}
+}
+
+// legacyDecode is the previous ParseJSON validation path: decode into a map, check
+// the required fields, then marshal the map and unmarshal it into obj.
+func legacyDecode(obj any, decoder *json.Decoder) error {
+	mapValue := make(map[string]interface{})
+	if err := decoder.Decode(&mapValue); err != nil {
+		return err
+	}
+	of := reflect.ValueOf(obj).Elem()
+	for i := 0; i < of.NumField(); i++ {
+		field := of.Type().Field(i)
+		tag := field.Tag.Get("json")
+		if mapValue[tag] == nil && HasRule(ParseRules(field.Tag.Get("gjango")), "required") {
+			return fmt.Errorf("field [%s] is required", tag)
+		}
+	}
+	b, _ := json.Marshal(mapValue)
+	_ = json.Unmarshal(b, obj)
+	return nil
+}
+
+func benchmarkPayload() []byte {
+	o := order{ID: 1, Email: "a@b.c", Tags: []string{"a", "b"}}
+	for i := 0; i < 1000; i++ {
+		o.Items = append(o.Items, item{Name: fmt.Sprintf("item-%d", i), Price: float64(i) / 3})
+	}
+	b, _ := json.Marshal(o)
+	return b
+}
+
+func BenchmarkDecodeJSON(b *testing.B) {
+	payload := benchmarkPayload()
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := DecodeJSON(json.NewDecoder(bytes.NewReader(payload)), &order{}, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLegacyDecode(b *testing.B) {
+	payload := benchmarkPayload()
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := legacyDecode(&order{}, json.NewDecoder(bytes.NewReader(payload))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type profile struct {
+	Name     string            `json:"name"`
+	Age      uint8             `json:"age,string"`
+	Active   bool              `json:"active"`
+	Score    float32           `json:"score"`
+	Born     time.Time         `json:"born"`
+	Extra    any               `json:"extra"`
+	Counts   map[int]int       `json:"counts"`
+	Labels   map[string]string `json:"labels"`
+	Nickname *string           `json:"nickname"`
+	Codes    [2]int            `json:"codes"`
+	Avatar   []byte            `json:"avatar"`
+}
+
+func TestDecodeJSONValues(t *testing.T) {
+	p := &profile{Nickname: new(string), Codes: [2]int{7, 7}}
+	body := `{"name": "Jérôme \"jo\"", "age": "42", "active": true, "score": 1.5e1,
+		"born": "2020-01-02T03:04:05Z", "extra": {"a": [1, "b"]}, "counts": {"1": 2},
+		"labels": {"ké": "v"}, "nickname": null, "codes": [1], "avatar": "aGk=", "ignored": [{"x": "}"}]}`
+	if err := decodeString(p, body, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := &profile{
+		Name: `Jérôme "jo"`, Age: 42, Active: true, Score: 15,
+		Born:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:  map[string]any{"a": []any{float64(1), "b"}},
+		Counts: map[int]int{1: 2}, Labels: map[string]string{"ké": "v"},
+		Codes: [2]int{1, 0}, Avatar: []byte("hi"),
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("expected %+v, got %+v", expected, p)
+	}
+
+	cases := []struct {
+		body   string
+		field  string
+		actual string
+	}{
+		{`{"age": "300"}`, "age", "number 300"},
+		{`{"age": 42}`, "age", "number"},
+		{`{"score": "1"}`, "score", "string"},
+		{`{"active": 1}`, "active", "number"},
+		{`{"codes": [1, 2.5]}`, "codes[1]", "number 2.5"},
+		{`{"labels": {"a": {"b": 1}}}`, "labels.a", "object"},
+		{`{"counts": {"1": "2"}}`, "counts.1", "string"},
+		{`[]`, "$", "array"},
+	}
+	for _, c := range cases {
+		err := decodeString(&profile{}, c.body, false)
+		var e *I18n.Error
+		if !errors.As(err, &e) || e.Key != I18n.BIND_TYPE_MISMATCH || e.Field() != c.field || e.Params["actual"] != c.actual {
+			t.Errorf("%s: expected a mismatch of %s with %s, got %v", c.body, c.field, c.actual, err)
+		}
+	}
+	if err := decodeString(&profile{}, `{"name": "jo"`, false); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
@@ -0,0 +1,154 @@
+package Binding
+
+import (
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SOURCE_TAGS are the tags binding a struct field from a part of the request other than the
+// body: the path parameters, the query string and the headers. Fields with one of them are
+// left out of the body bindings (DecodeJSON and BindValues).
+var SOURCE_TAGS = []string{"path", "query", "header"}
+
+// Sources are the parts of a request, other than the body, that fields are bound from.
+type Sources struct {
+	Path   map[string]string
+	Query  url.Values
+	Header http.Header
+}
+
+// sourceField is a field bound from a part of the request other than the body.
+type sourceField struct {
+	source   string // source is "path", "query" or "header".
+	key      string // key is the name of the parameter, query key or header.
+	index    []int
+	rules    []Rule
+	required bool
+}
+
+// sourceCache maps a reflect.Type to its []*sourceField.
+var sourceCache sync.Map
+
+// SourceTag returns the source ("path", "query" or "header") and the key of a field bound
+// from a part of the request other than the body, or empty strings.
+func SourceTag(field reflect.StructField) (string, string) {
+	for _, source := range SOURCE_TAGS {
+		if key, ok := field.Tag.Lookup(source); ok {
+			key, _, _ = strings.Cut(key, ",")
+			if key == "" {
+				key = field.Name
+			}
+			return source, key
+		}
+	}
+	return "", ""
+}
+
+// cachedSourceFields returns the fields of struct type t bound from the path, the query and
+// the headers, including those of embedded structs, computing them on first use.
+func cachedSourceFields(t reflect.Type) []*sourceField {
+	if fields, ok := sourceCache.Load(t); ok {
+		return fields.([]*sourceField)
+	}
+	fields := collectSourceFields(t, nil)
+	actual, _ := sourceCache.LoadOrStore(t, fields)
+	return actual.([]*sourceField)
+}
+
+func collectSourceFields(t reflect.Type, index []int) []*sourceField {
+	fields := make([]*sourceField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		source, key := SourceTag(field)
+		if source == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if field.Anonymous && ft.Kind() == reflect.Struct {
+				fields = append(fields, collectSourceFields(ft, fieldIndex)...)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if source == "header" {
+			key = http.CanonicalHeaderKey(key)
+		}
+		f := &sourceField{source: source, key: key, index: fieldIndex}
+		for _, rule := range ParseRules(field.Tag.Get("gjango")) {
+			if rule.Name == "required" {
+				f.required = true
+				continue
+			}
+			f.rules = append(f.rules, rule)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// BindSources binds the fields of obj, which must be a non-nil pointer to a struct, tagged
+// path, query or header from those parts of the request, and checks their gjango rules.
+// A required field must be present and not empty. Repeated query keys and headers bind
+// into slices.
+//
+// Example:
+//
+//	type GetArticle struct {
+//		ID      int      `path:"id"`
+//		Tags    []string `query:"tag"`
+//		Version string   `header:"X-Api-Version" gjango:"enum=1|2"`
+//	}
+//	// GET /articles/42?tag=go&tag=web with X-Api-Version: 2
+func BindSources(obj any, sources Sources) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return I18n.NewError(I18n.BIND_NOT_POINTER, nil)
+	}
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, f := range cachedSourceFields(value.Type()) {
+		var values []string
+		switch f.source {
+		case "path":
+			if v, ok := sources.Path[f.key]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = sources.Query[f.key]
+		case "header":
+			values = sources.Header[f.key]
+		}
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if f.required {
+				return I18n.NewError(I18n.VALIDATE_REQUIRED, map[string]any{"field": f.key})
+			}
+			continue
+		}
+		var tree any = values[0]
+		if len(values) > 1 {
+			list := make([]any, len(values))
+			for i, v := range values {
+				list[i] = v
+			}
+			tree = list
+		}
+		fieldValue := fieldByIndex(value, f.index)
+		if err := bindTree(fieldValue, tree, f.key); err != nil {
+			return err
+		}
+		if err := checkRules(f.rules, fieldValue, f.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// Package Binding validates the structs that request data is bound into,
+// following the rules declared in their gjango tags.
+package Binding
+
+import (
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Rule is a single rule of a gjango tag, e.g. "min=3" is Rule{Name: "min", Param: "3"}.
+type Rule struct {
+	Name  string
+	Param string
+
+	// pattern is the compiled regular expression of a pattern rule, set by ParseRules,
+	// or nil if Param is not a valid regular expression.
+	pattern *regexp.Regexp
+}
+
+// ParseRules splits a gjango tag into its rules.
+// Rules are separated by commas; since a regular expression may itself contain commas,
+// everything after "pattern=" is taken as the pattern, so it must be the last rule.
+// The pattern is compiled once here; an invalid one is reported by CheckTags and by CheckRule.
+//
+// Example:
+//
+//	ParseRules("required,min=1,max=10,enum=a|b,pattern=^[a-z]+$")
+func ParseRules(tag string) []Rule {
+	rules := make([]Rule, 0)
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "pattern=") {
+			part, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			part, tag = tag[:i], tag[i+1:]
+		} else {
+			part, tag = tag, ""
+		}
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(part, "=")
+		rule := Rule{Name: name, Param: param}
+		if name == "pattern" {
+			rule.pattern, _ = compilePattern(param)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// HasRule reports whether a rule with the given name is part of rules.
+func HasRule(rules []Rule, name string) bool {
+	for _, rule := range rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the exported fields of the struct (or slice of structs) pointed to by obj
+// against the value rules of their gjango tags: min, max, len, enum and pattern.
+// Nested structs are validated as well. The "required" rule is about presence in the
+// request, so it is enforced by the decoders rather than here.
+//
+// The returned error is an *I18n.Error describing the first violation.
+func Validate(obj any) error {
+	return validateValue(reflect.ValueOf(obj), "")
+}
+
+func validateValue(value reflect.Value, path string) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return validateStruct(value, path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateStruct(value reflect.Value, path string) error {
+	for _, f := range cachedStructInfo(value.Type()).fields {
+		fieldValue, ok := fieldByIndexNoAlloc(value, f.index)
+		if !ok {
+			continue
+		}
+		name := joinPath(path, f.name)
+		if err := checkRules(f.rules, fieldValue, name); err != nil {
+			return err
+		}
+		if err := validateValue(fieldValue, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRules checks every rule against value and returns the first violation.
+func checkRules(rules []Rule, value reflect.Value, field string) error {
+	for _, rule := range rules {
+		if err := CheckRule(rule, value, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinPath appends the key of a field to the path of its parent, e.g. "user" + "address" = "user.address".
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// FieldName returns the name a struct field is known by in requests: its json tag name
+// if there is one, otherwise its Go name.
+func FieldName(field reflect.StructField) string {
+	if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	return field.Name
+}
+
+// CheckRule checks a single value rule against value and returns an *I18n.Error naming
+// field if it is violated. Unknown rules and "required" are ignored.
+// For numbers, min and max compare the value; for strings, slices and maps they compare the length.
+// A pattern rule whose pattern is not a valid regular expression fails with a plain error,
+// answered as an internal error rather than as a violation; CheckTags reports it beforehand.
+func CheckRule(rule Rule, value reflect.Value, field string) error {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	var ok bool
+	var key string
+	switch rule.Name {
+	case "min":
+		key = I18n.VALIDATE_MIN
+		ok = compare(value, rule.Param, func(a, b float64) bool { return a >= b })
+	case "max":
+		key = I18n.VALIDATE_MAX
+		ok = compare(value, rule.Param, func(a, b float64) bool { return a <= b })
+	case "len":
+		key = I18n.VALIDATE_LEN
+		n, err := strconv.Atoi(rule.Param)
+		ok = err != nil || !hasLength(value) || value.Len() == n
+	case "enum":
+		key = I18n.VALIDATE_ENUM
+		ok = inEnum(value, strings.Split(rule.Param, "|"))
+		return ruleError(ok, key, field, strings.ReplaceAll(rule.Param, "|", ", "))
+	case "pattern":
+		key = I18n.VALIDATE_PATTERN
+		if value.Kind() != reflect.String {
+			return nil
+		}
+		re, err := rule.regexp()
+		if err != nil {
+			return err
+		}
+		ok = re.MatchString(value.String())
+	default:
+		return nil
+	}
+	return ruleError(ok, key, field, rule.Param)
+}
+
+func ruleError(ok bool, key string, field string, param string) error {
+	if ok {
+		return nil
+	}
+	return I18n.NewError(key, map[string]any{"field": field, "param": param})
+}
+
+func hasLength(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// compare applies cmp to the numeric value (or the length) of value and the rule parameter.
+// Values the rule does not apply to always pass.
+func compare(value reflect.Value, param string, cmp func(a, b float64) bool) bool {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp(float64(value.Int()), limit)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp(float64(value.Uint()), limit)
+	case reflect.Float32, reflect.Float64:
+		return cmp(value.Float(), limit)
+	case reflect.String:
+		return cmp(float64(len([]rune(value.String()))), limit)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return cmp(float64(value.Len()), limit)
+	}
+	return true
+}
+
+func inEnum(value reflect.Value, options []string) bool {
+	switch value.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface, reflect.Invalid:
+		return true
+	}
+	s := fmt.Sprint(value.Interface())
+	for _, option := range options {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+// regexp returns the compiled pattern of a pattern rule, compiling it if the rule was not
+// made by ParseRules.
+func (r Rule) regexp() (*regexp.Regexp, error) {
+	if r.pattern != nil {
+		return r.pattern, nil
+	}
+	return compilePattern(r.Param)
+}
+
+// compiledPattern is a pattern rule compiled by compilePattern.
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// patterns caches the compiled regular expressions of pattern rules, and their errors.
+var patterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := patterns.Load(pattern); ok {
+		return compiled.(*compiledPattern).re, compiled.(*compiledPattern).err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		err = fmt.Errorf("invalid pattern rule %q: %w", pattern, err)
+	}
+	patterns.Store(pattern, &compiledPattern{re: re, err: err})
+	return re, err
+}
+
+// CheckTags reports the first invalid rule of the gjango tags of struct type t, of the
+// structs it embeds and of the structs its fields hold (through pointers, slices, arrays
+// and maps), e.g. a pattern that is not a valid regular expression. Types that are not
+// structs have no tags to check. It is meant to be called when registering the handlers
+// that bind t, so that a bad tag fails at start-up rather than on every request.
+//
+// Example:
+//
+//	if err := Binding.CheckTags(reflect.TypeOf(CreateUser{})); err != nil {
+//		panic("[ERROR] " + err.Error())
+//	}
+func CheckTags(t reflect.Type) error {
+	return checkTags(t, make(map[reflect.Type]bool))
+}
+
+func checkTags(t reflect.Type, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		for _, rule := range ParseRules(field.Tag.Get("gjango")) {
+			if rule.Name != "pattern" {
+				continue
+			}
+			if _, err := rule.regexp(); err != nil {
+				return fmt.Errorf("the field [%s] of %s has an %w", field.Name, t, err)
+			}
+		}
+		if err := checkTags(field.Type, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package Binding
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type coupon struct {
+	Code string `json:"code" gjango:"min=3,pattern=^[A-Z]+(,[A-Z]+)?$"`
+}
+
+type badCoupon struct {
+	Code string `json:"code" gjango:"pattern=^[A-Z+$"`
+}
+
+type basket struct {
+	Coupons []*badCoupon `json:"coupons"`
+}
+
+func TestParseRules(t *testing.T) {
+	rules := ParseRules("required, min=3,pattern=^[a-z]+(,[a-z]+)?$")
+	if len(rules) != 3 || rules[1].Name != "min" || rules[2].Param != "^[a-z]+(,[a-z]+)?$" {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	if rules[2].pattern == nil || !HasRule(rules, "required") || HasRule(rules, "max") {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	if err := Validate(&coupon{Code: "ABC,DEF"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	var i18nErr *I18n.Error
+	if err := Validate(&coupon{Code: "abc"}); !errors.As(err, &i18nErr) || i18nErr.Key != I18n.VALIDATE_PATTERN {
+		t.Errorf("expected a pattern violation, got %v", err)
+	}
+	// a bad pattern fails the request with an error instead of a panic
+	err := Validate(&badCoupon{Code: "ABC"})
+	if err == nil || errors.As(err, &i18nErr) || !strings.Contains(err.Error(), `invalid pattern rule "^[A-Z+$"`) {
+		t.Errorf("expected an invalid pattern, got %v", err)
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	if err := CheckTags(reflect.TypeOf(&coupon{})); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, typ := range []reflect.Type{reflect.TypeOf(badCoupon{}), reflect.TypeOf(basket{}), reflect.TypeOf(map[string][]basket{})} {
+		if err := CheckTags(typ); err == nil || !strings.HasPrefix(err.Error(), "the field [Code] of Binding.badCoupon has an invalid pattern rule") {
+			t.Errorf("%s: unexpected error %v", typ, err)
+		}
+	}
+	if err := CheckTags(reflect.TypeOf(0)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
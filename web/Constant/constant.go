@@ -20,7 +20,26 @@ const JSON_HEADER_CONTENT_TYPE = "application/json; charset=utf-8"
 // STRING_HEADER_CONTENT_TYPE defines the Content-Type header for plain text responses.
 const STRING_HEADER_CONTENT_TYPE = "text/plain; charset=utf-8"
 
+// SSE_HEADER_CONTENT_TYPE defines the Content-Type header for server-sent event streams.
+const SSE_HEADER_CONTENT_TYPE = "text/event-stream; charset=utf-8"
+
+// NDJSON_HEADER_CONTENT_TYPE defines the Content-Type header for newline-delimited JSON streams.
+const NDJSON_HEADER_CONTENT_TYPE = "application/x-ndjson"
+
+// PROBLEM_JSON_HEADER_CONTENT_TYPE defines the Content-Type header for problem details (RFC 9457) in JSON.
+const PROBLEM_JSON_HEADER_CONTENT_TYPE = "application/problem+json"
+
+// PROBLEM_XML_HEADER_CONTENT_TYPE defines the Content-Type header for problem details (RFC 9457) in XML.
+const PROBLEM_XML_HEADER_CONTENT_TYPE = "application/problem+xml"
+
 // XML_HEADER defines the Content-Type header for XML responses.
 const XML_HEADER = "application/xml; charset=utf-8"
 
 const DEFAULT_MAX_MEMORY = 32 << 20 // 32 MB
+
+// DEFAULT_BODY_MEMORY_THRESHOLD is the size above which a cached request body is spilled to a temp file.
+const DEFAULT_BODY_MEMORY_THRESHOLD = 1 << 20 // 1 MB
+
+// DEFAULT_VALIDATED_BODY_MAX_SIZE is the largest request body cached by default to be validated
+// against an OpenAPI document, a JSON Schema or as JSON-RPC requests.
+const DEFAULT_VALIDATED_BODY_MAX_SIZE = 1 << 20 // 1 MB
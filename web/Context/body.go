@@ -0,0 +1,206 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"io"
+	"net/http"
+	"os"
+)
+
+// ErrBodyTooLarge is returned by CacheBody when the request body exceeds BodyCacheConfig.MaxSize.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodyCacheConfig configures how CacheBody buffers the request body.
+type BodyCacheConfig struct {
+	// MaxSize is the largest body accepted, in bytes. Zero means no limit.
+	MaxSize int64
+	// MemoryThreshold is the size above which the body is spilled to a temp file
+	// instead of being kept in memory. Zero means Constant.DEFAULT_BODY_MEMORY_THRESHOLD.
+	MemoryThreshold int64
+	// TempDir is the directory of the spill files. Empty means os.TempDir().
+	TempDir string
+}
+
+// bodyCache holds a buffered request body, either in memory or in a temp file.
+type bodyCache struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+// reader returns a new reader positioned at the beginning of the cached body.
+func (b *bodyCache) reader() io.ReadCloser {
+	if b.file != nil {
+		return io.NopCloser(io.NewSectionReader(b.file, 0, b.size))
+	}
+	return io.NopCloser(bytes.NewReader(b.data))
+}
+
+// cachedBody is the c.R.Body of a cached body. It goes back to the beginning of the cache once
+// it has been read to the end or closed, so that the middlewares reading c.R.Body directly one
+// after another, rather than with Context.BodyReader, all read the whole body.
+type cachedBody struct {
+	cache *bodyCache
+	r     io.ReadCloser
+}
+
+func (b *cachedBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		b.r = b.cache.reader()
+	}
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		b.r = nil
+	}
+	return n, err
+}
+
+// Close rewinds the body, the cache being released by Context.Finish.
+func (b *cachedBody) Close() error {
+	b.r = nil
+	return nil
+}
+
+// close removes the temp file of the cache, if any.
+func (b *bodyCache) close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	err := b.file.Close()
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// CacheBody reads the whole request body into a cache, so that it can be read and bound
+// several times, e.g. by a logging middleware, a signature-verification middleware and the handler.
+// Small bodies are kept in memory; bodies above config.MemoryThreshold are spilled to a temp file,
+// which is removed when the request is finished. Bodies above config.MaxSize are rejected with
+// ErrBodyTooLarge.
+//
+// Once the body is cached, c.R.Body is replaced by a reader over the cache, which goes back to
+// the beginning of the body once it has been read to the end or closed, and every time the body
+// is bound, so that the middlewares reading c.R.Body directly and the http.Handlers called
+// downstream see the full body as well. A reader stopping in the middle of c.R.Body leaves it
+// there: BodyReader returns a reader of its own. Calling CacheBody again is a no-op.
+func (c *Context) CacheBody(config BodyCacheConfig) error {
+	if c.bodyCache != nil {
+		return nil
+	}
+	if c.R.Body == nil || c.R.Body == http.NoBody {
+		c.bodyCache = &bodyCache{}
+		return nil
+	}
+	threshold := config.MemoryThreshold
+	if threshold <= 0 {
+		threshold = Constant.DEFAULT_BODY_MEMORY_THRESHOLD
+	}
+	body := io.Reader(c.R.Body)
+	if config.MaxSize > 0 {
+		// read one more byte than allowed to tell a body of exactly MaxSize from a larger one
+		body = io.LimitReader(body, config.MaxSize+1)
+	}
+	defer c.R.Body.Close()
+
+	cache := &bodyCache{}
+	buffer := &bytes.Buffer{}
+	n, err := io.CopyN(buffer, body, threshold+1)
+	if err != nil && err != io.EOF {
+		return bodyError(err)
+	}
+	if n <= threshold {
+		cache.data = buffer.Bytes()
+		cache.size = n
+	} else {
+		file, err := os.CreateTemp(config.TempDir, "gjango-body-*")
+		if err != nil {
+			return err
+		}
+		cache.file = file
+		if _, err = buffer.WriteTo(file); err == nil {
+			var rest int64
+			rest, err = io.Copy(file, body)
+			cache.size = n + rest
+		}
+		if err != nil {
+			_ = cache.close()
+			return bodyError(err)
+		}
+	}
+	if config.MaxSize > 0 && cache.size > config.MaxSize {
+		_ = cache.close()
+		return ErrBodyTooLarge
+	}
+	c.bodyCache = cache
+	c.rewindBody()
+	return nil
+}
+
+// bodyError returns ErrBodyTooLarge for the bodies limited before CacheBody, e.g. with
+// http.MaxBytesReader, and err otherwise.
+func bodyError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return ErrBodyTooLarge
+	}
+	return err
+}
+
+// IsBodyCached reports whether the request body has been cached by CacheBody.
+func (c *Context) IsBodyCached() bool {
+	return c.bodyCache != nil
+}
+
+// rewindBody points c.R.Body back to the beginning of the cached body, if there is one.
+func (c *Context) rewindBody() {
+	if c.bodyCache == nil {
+		return
+	}
+	c.R.Body = &cachedBody{cache: c.bodyCache}
+	c.R.ContentLength = c.bodyCache.size
+	cache := c.bodyCache
+	c.R.GetBody = func() (io.ReadCloser, error) {
+		return cache.reader(), nil
+	}
+}
+
+// BodyReader returns a reader over the request body. If the body has been cached, every call
+// returns a new reader starting at the beginning of the body; otherwise it is c.R.Body itself,
+// which can only be read once.
+func (c *Context) BodyReader() io.ReadCloser {
+	if c.bodyCache != nil {
+		return c.bodyCache.reader()
+	}
+	return c.R.Body
+}
+
+// Body returns the raw request body. If the body has not been cached,
+// reading it consumes c.R.Body. The returned slice is the caller's: changing it
+// does not change the cached body.
+func (c *Context) Body() ([]byte, error) {
+	if c.bodyCache != nil && c.bodyCache.file == nil {
+		return bytes.Clone(c.bodyCache.data), nil
+	}
+	body := c.BodyReader()
+	if body == nil {
+		return nil, nil
+	}
+	return io.ReadAll(body)
+}
+
+// Finish releases the resources held for the request, such as the temp file of
+// a cached body or an event stream. The engine calls it once the request has been handled.
+func (c *Context) Finish() {
+	if c.bodyCache != nil {
+		_ = c.bodyCache.close()
+		c.bodyCache = nil
+	}
+	if c.sse != nil {
+		c.sse.close()
+		c.sse = nil
+	}
+}
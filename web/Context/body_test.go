@@ -0,0 +1,83 @@
+package context
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// bodyContext returns a Context for a POST request of body.
+func bodyContext(body string) *Context {
+	ctx := &Context{}
+	ctx.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return ctx
+}
+
+func TestCacheBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BodyCacheConfig
+		spills bool
+	}{
+		{"memory", BodyCacheConfig{MaxSize: 64}, false},
+		{"spill to disk", BodyCacheConfig{MaxSize: 64, MemoryThreshold: 4}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			test.config.TempDir = dir
+			ctx := bodyContext("hello world")
+			if err := ctx.CacheBody(test.config); err != nil {
+				t.Fatal(err)
+			}
+			if files, _ := os.ReadDir(dir); (len(files) == 1) != test.spills {
+				t.Errorf("expected a spill file: %v, got %v", test.spills, files)
+			}
+			// the middlewares reading c.R.Body one after another all read the whole body
+			for i := 0; i < 2; i++ {
+				if data, _ := io.ReadAll(ctx.R.Body); string(data) != "hello world" {
+					t.Errorf("read %d: got %q", i, data)
+				}
+			}
+			data, err := ctx.Body()
+			if err != nil || string(data) != "hello world" {
+				t.Fatalf("got %q %v", data, err)
+			}
+			data[0] = 'J'
+			if again, _ := ctx.Body(); string(again) != "hello world" {
+				t.Errorf("the cached body was changed through Body: %q", again)
+			}
+			if data, _ := io.ReadAll(ctx.BodyReader()); string(data) != "hello world" {
+				t.Errorf("BodyReader: got %q", data)
+			}
+			ctx.Finish()
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("the spill file was not removed: %v", files)
+			}
+		})
+	}
+}
+
+func TestCacheBodyTooLarge(t *testing.T) {
+	for _, threshold := range []int64{0, 4} {
+		dir := t.TempDir()
+		ctx := bodyContext("hello world")
+		err := ctx.CacheBody(BodyCacheConfig{MaxSize: 5, MemoryThreshold: threshold, TempDir: dir})
+		if !errors.Is(err, ErrBodyTooLarge) || ctx.IsBodyCached() {
+			t.Errorf("threshold %d: expected ErrBodyTooLarge, got %v", threshold, err)
+		}
+		if files, _ := os.ReadDir(dir); len(files) != 0 {
+			t.Errorf("threshold %d: the spill file was not removed: %v", threshold, files)
+		}
+	}
+	// a body limited before being cached
+	ctx := bodyContext("hello world")
+	ctx.R.Body = http.MaxBytesReader(ctx.W, ctx.R.Body, 5)
+	if err := ctx.CacheBody(BodyCacheConfig{}); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
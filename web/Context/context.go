@@ -3,8 +3,10 @@ package context
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
-	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/Binding"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/LongPoll"
+	"github.com/Jerry20000730/Gjango/web/Progress"
 	"github.com/Jerry20000730/Gjango/web/Render"
 	"io"
 	"log"
@@ -12,7 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -21,6 +23,114 @@ type Context struct {
 	R          *http.Request
 	queryCache url.Values
 	formCache  url.Values
+	params     map[string]string
+	route      string
+
+	// Catalog is the message catalog used to localize errors, set by the engine.
+	Catalog *I18n.Catalog
+	locale  string
+
+	bodyCache   *bodyCache
+	paramErrors []error
+
+	// UploadLimits restricts multipart uploads, set by the engine and the web.UploadLimit middleware.
+	UploadLimits      UploadLimits
+	uploadBodyLimited bool
+	uploadErr         error
+
+	// ProgressTracker receives the progress of uploads identified by an X-Progress-ID header
+	// or query parameter, set by the engine.
+	ProgressTracker Progress.Tracker
+	progress        *Progress.Reader
+
+	// Notifier wakes the requests waiting on a key with WaitFor and LongPoll, set by the engine.
+	Notifier *LongPoll.Notifier
+
+	// ErrorHandler answers the requests failed with HandleError, set by the engine.
+	ErrorHandler func(c *Context, err error)
+	// Errors are the errors of the request recorded with Error and HandleError,
+	// for the middlewares running after the handler.
+	Errors []error
+
+	// Logger is the logger of the engine, set by the engine.
+	Logger *log.Logger
+	// Debug reports whether the engine runs in debug mode, set by the engine.
+	Debug bool
+
+	sse *SSEWriter
+}
+
+// Reset prepares a (pooled) Context for a new request, dropping whatever
+// state was left behind by the previous request.
+func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
+	c.W = w
+	c.R = r
+	c.queryCache = nil
+	c.formCache = nil
+	c.params = nil
+	c.route = ""
+	c.locale = ""
+	c.bodyCache = nil
+	c.paramErrors = nil
+	c.UploadLimits = UploadLimits{}
+	c.uploadBodyLimited = false
+	c.uploadErr = nil
+	c.ProgressTracker = nil
+	c.progress = nil
+	c.Notifier = nil
+	c.ErrorHandler = nil
+	c.Errors = nil
+	c.Logger = nil
+	c.Debug = false
+	c.sse = nil
+}
+
+// catalog returns the catalog of the Context, or the default one if the engine did not set any.
+func (c *Context) catalog() *I18n.Catalog {
+	if c.Catalog == nil {
+		return I18n.Default
+	}
+	return c.Catalog
+}
+
+// logger returns the logger of the engine, or the standard logger outside of an engine.
+func (c *Context) logger() *log.Logger {
+	if c.Logger == nil {
+		return log.Default()
+	}
+	return c.Logger
+}
+
+// SetLocale overrides the locale of the current request, e.g. from a session
+// or a route-specific middleware. An empty lang restores the Accept-Language negotiation.
+func (c *Context) SetLocale(lang string) {
+	c.locale = lang
+}
+
+// Locale returns the locale used to localize messages of the current request.
+// Unless it has been overridden by SetLocale, it is the catalog language that best
+// matches the Accept-Language header of the request.
+func (c *Context) Locale() string {
+	if c.locale == "" {
+		header := ""
+		if c.R != nil {
+			header = c.R.Header.Get("Accept-Language")
+		}
+		c.locale = c.catalog().Match(header)
+	}
+	return c.locale
+}
+
+// Translate renders the message key of the catalog in the locale of the current request,
+// filling its placeholders with params.
+func (c *Context) Translate(key string, params map[string]any) string {
+	return c.catalog().Translate(c.Locale(), key, params)
+}
+
+// LocalizeError returns a copy of err in which every catalog error (*I18n.Error) is localized
+// to the locale of the current request (see I18n.Localize).
+func (c *Context) LocalizeError(err error) error {
+	return I18n.Localize(err, c.catalog(), c.Locale())
 }
 
 // initQueryCache initializes the query cache for the Context if it hasn't been initialized yet.
@@ -35,6 +145,44 @@ func (c *Context) initQueryCache() {
 	}
 }
 
+// SetParams sets the path parameters of the matched route, e.g. {"id": "42"} for the
+// route "/user/:id" and the URL "/user/42". It is called by the engine when routing the request.
+func (c *Context) SetParams(params map[string]string) {
+	c.params = params
+}
+
+// SetRoute sets the pattern of the matched route, including its group, e.g. "/user/get/:id".
+// It is called by the engine when routing the request.
+func (c *Context) SetRoute(route string) {
+	c.route = route
+}
+
+// Route returns the pattern of the matched route, e.g. "/user/get/:id", or an empty string
+// if no route matched. Unlike the path of the URL, it does not depend on the parameters,
+// which makes it suitable for logs and metrics.
+func (c *Context) Route() string {
+	return c.route
+}
+
+// Param retrieves the value of a path parameter of the matched route.
+//
+// Parameters:
+//   - name: The name of the parameter, without the leading colon, e.g. "id" for "/user/:id".
+//
+// Returns:
+//   - The value of the parameter, or an empty string if the route has no such parameter.
+func (c *Context) Param(name string) string {
+	return c.params[name]
+}
+
+// Params returns all the path parameters of the matched route.
+//
+// Returns:
+//   - A map from parameter names to their values. It must not be modified.
+func (c *Context) Params() map[string]string {
+	return c.params
+}
+
 // GetQuery retrieves the first value associated with the specified query parameter key.
 // It ensures the query cache is initialized before attempting to retrieve the value.
 // If the key exists in the query parameters, its first value is returned. If the key
@@ -102,7 +250,8 @@ func (c *Context) GetQueryWithDefaultValues(key string, defaultValue string) str
 // QueryMap retrieves a map of string keys to string values for a given query parameter key.
 // This method is useful for parsing query parameters with nested keys, such as "user[id]=1&user[age]=29".
 // It leverages the GetQueryMap method to initialize the query cache (if not already done) and
-// to validate and extract the query parameters into a map.
+// to validate and extract the query parameters into a map. Deeper keys, such as
+// "user[address][city]=Paris", are decoded by QueryNestedMap.
 //
 // Parameters:
 //   - key: The base query parameter key to look for nested keys in.
@@ -129,6 +278,35 @@ func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
 	return c.getAndValidate(c.queryCache, key)
 }
 
+// QueryNestedMap retrieves the nested map of a given query parameter key, decoding keys of any depth,
+// such as "user[address][city]=Paris" or "user[items][0][name]=pen".
+// It leverages the GetQueryNestedMap method, like QueryMap does with GetQueryMap.
+//
+// Parameters:
+//   - key: The base query parameter key to look for nested keys in.
+//
+// Returns:
+//   - A map representing the nested query parameters.
+func (c *Context) QueryNestedMap(key string) map[string]any {
+	dicts, _ := c.GetQueryNestedMap(key)
+	return dicts
+}
+
+// GetQueryNestedMap initializes the query cache (if necessary) and retrieves the nested map
+// of a given query parameter key, along with a boolean indicating whether the key was found.
+// Values are strings, or nested maps and slices for deeper keys (see Binding.ParseNested).
+//
+// Parameters:
+//   - key: The base query parameter key to look for nested keys in.
+//
+// Returns:
+//   - A map representing the nested query parameters.
+//   - A boolean indicating whether the key was found in the query parameters.
+func (c *Context) GetQueryNestedMap(key string) (map[string]any, bool) {
+	c.initQueryCache()
+	return c.getNested(c.queryCache, key)
+}
+
 // getAndValidate parses a map of query parameters (map[string][]string) for nested keys based on a given key.
 // It constructs a map of the nested keys to their first corresponding value. This method supports parsing
 // query parameters formatted like "user[id]=1&user[age]=29", extracting the nested keys and their values.
@@ -159,17 +337,56 @@ func (c *Context) getAndValidate(m map[string][]string, key string) (map[string]
 	return dicts, exist
 }
 
+// getNested parses a map of query parameters (map[string][]string) for nested keys based on a given key.
+// The bracket notation is decoded by Binding.ParseNested, so any depth of nesting is supported.
+// If the key holds a list (e.g. "items[0][name]=pen"), the map is keyed by the indexes of the list.
+//
+// Parameters:
+//   - m: The map of query parameters to parse.
+//   - key: The base query parameter key to look for nested keys in.
+//
+// Returns:
+//   - A map representing the nested query parameters.
+//   - A boolean indicating whether any nested keys were found for the given base key.
+//
+// Example:
+//
+//	For a query string "?user[id]=1&user[address][city]=Paris", calling getNested with key "user"
+//	would return a map {"id": "1", "address": {"city": "Paris"}} and true.
+func (c *Context) getNested(m map[string][]string, key string) (map[string]any, bool) {
+	switch value := Binding.ParseNested(m)[key].(type) {
+	case map[string]any:
+		return value, true
+	case []any:
+		dicts := make(map[string]any, len(value))
+		for i, item := range value {
+			dicts[strconv.Itoa(i)] = item
+		}
+		return dicts, true
+	}
+	return make(map[string]any), false
+}
+
+// BindQuery binds the query parameters into obj, which must be a pointer, and validates them
+// against the gjango tags of obj. Nested structs, slices and maps are bound from bracket notation,
+// e.g. "?user[address][city]=Paris&items[0][name]=pen" (see Binding.BindValues).
+// Errors are localized to the locale of the request.
+func (c *Context) BindQuery(obj any) error {
+	c.initQueryCache()
+	return c.LocalizeError(Binding.BindValues(c.queryCache, obj))
+}
+
 // initPostFormCache initializes the form cache for the Context if it hasn't been initialized yet.
-// This method parses the request body as multipart/form-data if the request's Content-Type
-// indicates so and stores the parsed data in the formCache. If the request body is not
+// This method parses the request body as multipart/form-data (within the upload limits of the Context)
+// if the request's Content-Type indicates so and stores the parsed data in the formCache. If the request body is not
 // multipart/form-data or if the request is nil, it initializes formCache as an empty url.Values object.
 // This ensures that subsequent accesses to form parameters do not need to parse the request body again,
 // improving performance for multiple form parameter accesses.
 func (c *Context) initPostFormCache() {
 	if c.R != nil {
-		if err := c.R.ParseMultipartForm(Constant.DEFAULT_MAX_MEMORY); err != nil {
-			if errors.Is(err, http.ErrNotMultipart) {
-				log.Println(err)
+		if err := c.parseMultipartForm(); err != nil {
+			if !errors.Is(err, http.ErrNotMultipart) {
+				c.logger().Printf("[ERROR] %s %s: %v", c.R.Method, c.R.URL.Path, err)
 			}
 		}
 		c.formCache = c.R.PostForm
@@ -247,6 +464,28 @@ func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
 	return c.getAndValidate(c.formCache, key)
 }
 
+// GetPostFormNestedMap initializes the form cache (if necessary) and retrieves the nested map
+// of a given form parameter key, along with a boolean indicating whether the key was found.
+// Keys of any depth are decoded, like GetQueryNestedMap does for query parameters.
+//
+// Parameters:
+//   - key: The base form parameter key to look for nested keys in.
+//
+// Returns:
+//   - A map representing the nested form parameters.
+//   - A boolean indicating whether the key was found in the form parameters.
+func (c *Context) GetPostFormNestedMap(key string) (map[string]any, bool) {
+	c.initPostFormCache()
+	return c.getNested(c.formCache, key)
+}
+
+// BindForm binds the post form parameters (application/x-www-form-urlencoded or multipart/form-data)
+// into obj, which must be a pointer, the same way as BindQuery does for query parameters.
+func (c *Context) BindForm(obj any) error {
+	c.initPostFormCache()
+	return c.LocalizeError(Binding.BindValues(c.formCache, obj))
+}
+
 // FormFile retrieves a single file from the multipart form data based on the given name.
 // It returns the file header if the file is found, allowing further operations like opening or reading the file.
 // If the file is not found or an error occurs during retrieval, an error is returned.
@@ -258,15 +497,15 @@ func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
 //   - *multipart.FileHeader: The file header for the specified file, if found.
 //   - error: An error object if the file cannot be retrieved.
 func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
-	file, header, err := c.R.FormFile(name)
+	multipartForm, err := c.MultipartForm()
 	if err != nil {
 		return nil, err
 	}
-	err = file.Close()
-	if err != nil {
-		return nil, err
+	headers := multipartForm.File[name]
+	if len(headers) == 0 {
+		return nil, http.ErrMissingFile
 	}
-	return header, nil
+	return headers[0], nil
 }
 
 // FormFiles retrieves all files associated with the given name from the multipart form data.
@@ -288,20 +527,26 @@ func (c *Context) FormFiles(name string) []*multipart.FileHeader {
 
 // MultipartForm parses the request body as multipart/form-data and returns the parsed multipart form.
 // It ensures that the form data is parsed only once and caches the result for subsequent accesses.
+// The upload limits of the Context are enforced: the errors ErrUploadTooLarge, ErrFileTooLarge,
+// ErrTooManyFiles and ErrUnsupportedMediaType can be answered with AbortUpload.
 // If parsing fails, an error is returned.
 //
 // Returns:
 //   - *multipart.Form: The parsed multipart form containing all file and non-file form fields.
 //   - error: An error object if parsing fails.
 func (c *Context) MultipartForm() (*multipart.Form, error) {
-	err := c.R.ParseMultipartForm(Constant.DEFAULT_MAX_MEMORY)
-	return c.R.MultipartForm, err
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, c.LocalizeError(err)
+	}
+	return c.R.MultipartForm, nil
 }
 
 // SaveUploadedFile saves an uploaded file to a specified destination on the server's filesystem.
 // It opens the file associated with the provided file header, creates a new file at the destination path,
 // and copies the contents of the uploaded file to the new file.
 // If any step fails, an error is returned.
+// The destination is used as it is: never build it from the file name sent by the client,
+// use SaveUploadedFileIn instead.
 //
 // Parameters:
 //   - file: The file header of the uploaded file to be saved.
@@ -324,117 +569,39 @@ func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error
 	return err
 }
 
+// ParseJSON decodes the JSON body of the request into obj.
+// If disallowUnknownField is true, keys that do not match a field of obj are rejected.
+// If isValidate is true, the rules declared in the gjango tags of obj are checked as well.
+// Validation and binding errors are localized to the locale of the request.
 func (c *Context) ParseJSON(obj any, disallowUnknownField bool, isValidate bool) error {
+	return c.LocalizeError(c.parseJSON(obj, disallowUnknownField, isValidate))
+}
+
+func (c *Context) parseJSON(obj any, disallowUnknownField bool, isValidate bool) error {
+	// with a cached body, every bind reads from the beginning and leaves
+	// c.R.Body ready for whoever reads it next
+	c.rewindBody()
+	defer c.rewindBody()
 	body := c.R.Body
 	if body == nil {
-		return errors.New("[ERROR] body is nil, invalid request")
+		return I18n.NewError(I18n.BIND_NIL_BODY, nil)
 	}
 	decoder := json.NewDecoder(body)
+	if isValidate {
+		// decoding and validation happen in a single pass
+		return Binding.DecodeJSON(decoder, obj, disallowUnknownField)
+	}
 	// if there is unknown fields
 	// there will be errors
 	if disallowUnknownField {
 		decoder.DisallowUnknownFields()
 	}
-	if isValidate {
-		err := validateJsonParam(obj, decoder)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := decoder.Decode(obj)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func validateJsonParam(obj any, decoder *json.Decoder) error {
-	// parse to map, and then compare the key with the map key
-	valueOf := reflect.ValueOf(obj)
-	if valueOf.Kind() != reflect.Pointer {
-		return errors.New("[ERROR] passing parameter is not a pointer")
-	}
-	elem := valueOf.Elem().Interface()
-	of := reflect.ValueOf(elem)
-
-	switch of.Kind() {
-	case reflect.Struct:
-		err := checkStructParams(obj, decoder, of)
-		if err != nil {
-			return err
-		}
-	case reflect.Slice, reflect.Array:
-		elem := of.Type().Elem()
-		elemType := elem.Kind()
-		if elemType == reflect.Struct {
-			return checkSliceParams(obj, elem, decoder)
-		}
-	default:
-		err := decoder.Decode(obj)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func checkSliceParams(obj any, elem reflect.Type, decoder *json.Decoder) error {
-	mapData := make([]map[string]interface{}, 0)
-	err := decoder.Decode(&mapData)
-	if err != nil {
-		return err
-	}
-	if len(mapData) <= 0 {
-		return nil
-	}
-	for i := 0; i < elem.NumField(); i++ {
-		field := elem.Field(i)
-		gjangoTag := field.Tag.Get("gjango")
-		tag := field.Tag.Get("json")
-		value := mapData[0][tag]
-		if value == nil && gjangoTag == "required" {
-			return errors.New(fmt.Sprintf("field [%s] is required", tag))
-		}
-	}
-	if obj != nil {
-		marshal, _ := json.Marshal(mapData)
-		_ = json.Unmarshal(marshal, obj)
-	}
-	return nil
-}
-
-func checkStructParams(obj any, decoder *json.Decoder, of reflect.Value) error {
-	mapValue := make(map[string]interface{})
-	err := decoder.Decode(&mapValue)
-	if err != nil {
-		return err
-	}
-	for i := 0; i < of.NumField(); i++ {
-		field := of.Type().Field(i)
-		name := field.Name
-		tag := field.Tag.Get("json")
-		// no json tag name
-		if tag == "" {
-			value := mapValue[name]
-			if value == nil {
-				return errors.New(fmt.Sprintf("[ERROR] field [%s] does not exist", tag))
-			}
-		}
-		gjangoTag := field.Tag.Get("gjango")
-		value := mapValue[tag]
-		if value == nil && gjangoTag == "required" {
-			return errors.New(fmt.Sprintf("[ERROR] field [%s] does not exist, because [%s] is specified in gjango tag that it is required", tag, tag))
-		}
-	}
-	b, _ := json.Marshal(mapValue)
-	_ = json.Unmarshal(b, obj)
-	return nil
+	return decoder.Decode(obj)
 }
 
 // Render a general render function for rendering different types of data
 // by passing a specific render as the second parameter.
+// A code of 0 or less leaves the status code to the render itself (e.g. Render.Redirect).
 // This method delegates the actual rendering process to the passed Render interface implementation,
 // allowing for flexible rendering of various content types such as strings, JSON, XML, etc.
 // The HTTP status code is set before rendering the content.
@@ -446,11 +613,12 @@ func checkStructParams(obj any, decoder *json.Decoder, of reflect.Value) error {
 // Returns:
 //   - An error if the rendering process fails, otherwise nil.
 func (c *Context) Render(code int, r Render.Render) error {
-	err := r.Render(c.W)
-	if code != http.StatusOK {
+	// headers must be set before the status code is written
+	r.WriteContentType(c.W)
+	if code > 0 && code != http.StatusOK {
 		c.W.WriteHeader(code)
 	}
-	return err
+	return r.Render(c.W)
 }
 
 // String is a render function for rendering string content on the website.
@@ -546,7 +714,7 @@ func (c *Context) HTMLTemplate(preloader *Render.HTMLPreloader, status int, name
 // Returns:
 //   - An error if the redirect process fails, otherwise nil.
 func (c *Context) Redirect(status int, location string) error {
-	return c.Render(status, Render.Redirect{
+	return c.Render(-1, Render.Redirect{
 		Code:     status,
 		Request:  c.R,
 		Location: location,
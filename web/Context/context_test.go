@@ -0,0 +1,92 @@
+package context
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryMaps(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/?user[id]=1&user[address][city]=Paris&user[tags][]=a", strings.NewReader("user[name]=jo&user[items][0][name]=pen"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := &Context{}
+	ctx.Reset(httptest.NewRecorder(), r)
+
+	flat := map[string]string{"id": "1", "address": "Paris", "tags": "a"}
+	if m := ctx.QueryMap("user"); !reflect.DeepEqual(m, flat) {
+		t.Errorf("expected %v, got %v", flat, m)
+	}
+	nested := map[string]any{"id": "1", "address": map[string]any{"city": "Paris"}, "tags": []any{"a"}}
+	if m := ctx.QueryNestedMap("user"); !reflect.DeepEqual(m, nested) {
+		t.Errorf("expected %v, got %v", nested, m)
+	}
+	if m, ok := ctx.GetPostFormMap("user"); !ok || m["name"] != "jo" {
+		t.Errorf("unexpected form map %v", m)
+	}
+	nested = map[string]any{"name": "jo", "items": []any{map[string]any{"name": "pen"}}}
+	if m, ok := ctx.GetPostFormNestedMap("user"); !ok || !reflect.DeepEqual(m, nested) {
+		t.Errorf("expected %v, got %v", nested, m)
+	}
+	if _, ok := ctx.GetQueryNestedMap("missing"); ok {
+		t.Error("expected a missing key not to be found")
+	}
+}
+
+type renderedUser struct {
+	Name string
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		render      func(ctx *Context) error
+		status      int
+		contentType string
+		body        string
+	}{
+		{"string", func(ctx *Context) error { return ctx.String(http.StatusCreated, "hello %s", "jo") }, http.StatusCreated, "text/plain; charset=utf-8", "hello jo"},
+		{"json", func(ctx *Context) error { return ctx.JSON(http.StatusBadRequest, map[string]string{"name": "jo"}) }, http.StatusBadRequest, "application/json; charset=utf-8", `{"name":"jo"}`},
+		{"xml", func(ctx *Context) error { return ctx.XML(http.StatusAccepted, renderedUser{Name: "jo"}) }, http.StatusAccepted, "application/xml; charset=utf-8", "<Name>jo</Name>"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		ctx := &Context{}
+		ctx.Reset(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		if err := test.render(ctx); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		// the headers of the recorded response are the ones written along with the status code
+		response := w.Result()
+		if response.StatusCode != test.status || !strings.Contains(w.Body.String(), test.body) {
+			t.Errorf("%s: unexpected answer %d %s", test.name, response.StatusCode, w.Body)
+		}
+		if response.Header.Get("Content-Type") != test.contentType {
+			t.Errorf("%s: unexpected content type %q", test.name, response.Header.Get("Content-Type"))
+		}
+	}
+	w := httptest.NewRecorder()
+	ctx := &Context{}
+	ctx.Reset(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if err := ctx.Redirect(http.StatusSeeOther, "/login"); err != nil || w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("unexpected redirect %d %v, %v", w.Code, w.Header(), err)
+	}
+}
+
+func TestPostFormLogger(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("--broken"))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary=missing")
+	logs := &bytes.Buffer{}
+	ctx := &Context{}
+	ctx.Reset(httptest.NewRecorder(), r)
+	ctx.Logger = log.New(logs, "", 0)
+	if _, ok := ctx.GetPostForm("name"); ok {
+		t.Error("expected no form values")
+	}
+	if !strings.HasPrefix(logs.String(), "[ERROR] POST /upload: ") {
+		t.Errorf("unexpected logs %q", logs)
+	}
+}
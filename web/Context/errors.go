@@ -0,0 +1,35 @@
+package context
+
+import (
+	"net/http"
+)
+
+// Error records a non-fatal error of the request in Errors, for the middlewares running after
+// the handler, such as loggers. The response is left to the handler. Nil errors are ignored.
+//
+// Example:
+//
+//	if err := cache.Set(key, value); err != nil {
+//		ctx.Error(err) // the response does not depend on the cache
+//	}
+func (c *Context) Error(err error) {
+	if err != nil {
+		c.Errors = append(c.Errors, err)
+	}
+}
+
+// HandleError answers the request with err, through the ErrorHandler set by the engine, and
+// records err in Errors. It is what the engine does with the errors returned by handlers (see
+// web.Fallible); handlers can call it directly to fail the request and return. Without an
+// ErrorHandler, the answer is a bare 500 Internal Server Error.
+func (c *Context) HandleError(err error) {
+	if err == nil {
+		return
+	}
+	c.Error(err)
+	if c.ErrorHandler != nil {
+		c.ErrorHandler(c, err)
+		return
+	}
+	http.Error(c.W, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
@@ -0,0 +1,72 @@
+package context
+
+import (
+	"github.com/Jerry20000730/Gjango/web/LongPoll"
+	"net/http"
+	"time"
+)
+
+// notifier returns the notifier of the Context, or the default one if the engine did not set any.
+func (c *Context) notifier() *LongPoll.Notifier {
+	if c.Notifier == nil {
+		return LongPoll.Default
+	}
+	return c.Notifier
+}
+
+// WaitFor waits for a notification of key sent by Notify, from another request, until timeout
+// expires or the client disconnects. No goroutine is left behind in any case.
+//
+// Parameters:
+//   - key: The key to wait on, e.g. "inbox:42".
+//   - timeout: The longest wait; zero or less waits until the client disconnects.
+//
+// Returns:
+//   - any: The data of the notification.
+//   - bool: true if a notification was received, false if the timeout expired.
+//   - error: The error of the request context if the client disconnected first.
+func (c *Context) WaitFor(key string, timeout time.Duration) (any, bool, error) {
+	return c.notifier().Wait(c.R.Context(), key, timeout)
+}
+
+// LongPoll answers a long-polling request: it waits for a notification of key like WaitFor,
+// then answers 200 with the data of the notification as JSON, or 204 No Content if the timeout
+// expired, so that the client polls again. Nothing is written if the client disconnected.
+//
+// Parameters:
+//   - key: The key to wait on.
+//   - timeout: The longest wait, which should stay below the timeouts of the proxies in between.
+//
+// Returns:
+//   - error: The error of the request context if the client disconnected, or the rendering error.
+//
+// Example:
+//
+//	g.Get("/inbox/:user/poll", func(ctx *context.Context) {
+//		ctx.LongPoll("inbox:"+ctx.Param("user"), 30*time.Second)
+//	})
+//	g.Post("/inbox/:user", func(ctx *context.Context) {
+//		...
+//		ctx.Notify("inbox:"+ctx.Param("user"), message)
+//	})
+func (c *Context) LongPoll(key string, timeout time.Duration) error {
+	data, ok, err := c.WaitFor(key, timeout)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		c.W.Header().Set("Cache-Control", "no-store")
+		c.W.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	c.W.Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, data)
+}
+
+// Notify wakes every request waiting on key, with data.
+//
+// Returns:
+//   - int: The number of requests woken.
+func (c *Context) Notify(key string, data any) int {
+	return c.notifier().Notify(key, data)
+}
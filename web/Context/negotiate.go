@@ -0,0 +1,181 @@
+package context
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Offer is a representation a handler can answer with: the media type it is negotiated as,
+// and the render writing it.
+type Offer struct {
+	// MediaType is matched against the Accept header, e.g. "application/json".
+	MediaType string
+	// Render writes the representation.
+	Render Render.Render
+}
+
+// OfferJSON offers data as JSON (application/json).
+func OfferJSON(data any) Offer {
+	return Offer{MediaType: "application/json", Render: Render.JSONRender{Data: data}}
+}
+
+// OfferXML offers data as XML (application/xml).
+func OfferXML(data any) Offer {
+	return Offer{MediaType: "application/xml", Render: Render.XMLRender{Data: data}}
+}
+
+// OfferHTML offers raw HTML content (text/html).
+func OfferHTML(html string) Offer {
+	return Offer{MediaType: "text/html", Render: Render.HTMLRender{IsTemplate: false, Data: html}}
+}
+
+// OfferHTMLTemplate offers the HTML template name of preloader, executed with data (text/html).
+func OfferHTMLTemplate(preloader *Render.HTMLPreloader, name string, data any) Offer {
+	return Offer{MediaType: "text/html", Render: Render.HTMLRender{
+		IsTemplate: true,
+		Name:       name,
+		Data:       data,
+		Template:   preloader.Template,
+	}}
+}
+
+// OfferString offers a formatted string as plain text (text/plain).
+func OfferString(format string, values ...any) Offer {
+	return Offer{MediaType: "text/plain", Render: Render.StringRender{Format: format, Data: values}}
+}
+
+// Negotiate answers with the offer the client prefers according to its Accept header, so that
+// the same endpoint serves browsers and API clients. The response varies on Accept. If the
+// client accepts none of the offers, it answers 406 Not Acceptable, listing the media types offered.
+//
+// Parameters:
+//   - status: HTTP status code to be set for the response.
+//   - offers: The representations available, in order of preference of the server: the first
+//     one wins ties, and is chosen when the request has no Accept header.
+//
+// Returns:
+//   - An error if the rendering process fails, otherwise nil.
+//
+// Example:
+//
+//	ctx.Negotiate(http.StatusOK,
+//		context.OfferJSON(user),
+//		context.OfferXML(user),
+//		context.OfferHTMLTemplate(&engine.HTMLPreloader, "user.html", user),
+//	)
+func (c *Context) Negotiate(status int, offers ...Offer) error {
+	mediaTypes := make([]string, len(offers))
+	for i, offer := range offers {
+		mediaTypes[i] = offer.MediaType
+	}
+	format := c.NegotiateFormat(mediaTypes...)
+	for _, offer := range offers {
+		if offer.MediaType == format {
+			return c.Render(status, offer.Render)
+		}
+	}
+	return c.String(http.StatusNotAcceptable, "not acceptable, available: %s", strings.Join(mediaTypes, ", "))
+}
+
+// NegotiateFormat chooses the media type the client prefers among offered, according to the
+// Accept header of the request: its q-values, its wildcards (type/* and */*), and the most
+// specific range matching each media type. It adds Accept to the Vary header of the response,
+// since the answer depends on it.
+//
+// Parameters:
+//   - offered: The media types available, in order of preference of the server: the first one
+//     wins ties, and is chosen when the request has no Accept header.
+//
+// Returns:
+//   - string: The chosen media type, as offered, or "" if the client accepts none of them.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if !varies(c.W.Header(), "Accept") {
+		c.W.Header().Add("Vary", "Accept")
+	}
+	if len(offered) == 0 {
+		return ""
+	}
+	accept := strings.Join(c.R.Header.Values("Accept"), ",")
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		if q := acceptQuality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// mediaRange is an element of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses an Accept header, skipping the malformed elements.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, element := range strings.Split(header, ",") {
+		parts := strings.Split(element, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(parts[0])), "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range parts[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			r.q = q
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of a media type: the q-value of the most specific range
+// matching it, or 0 if none does.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	base, _, _ := strings.Cut(mediaType, ";")
+	typ, subtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(base)), "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// varies reports whether the Vary header already lists name.
+func varies(header http.Header, name string) bool {
+	for _, value := range header.Values("Vary") {
+		for _, element := range strings.Split(value, ",") {
+			element = strings.TrimSpace(element)
+			if element == "*" || strings.EqualFold(element, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{"application/json", "application/xml", "text/html"}
+	cases := map[string]string{
+		"":                                       "application/json",
+		"text/html":                              "text/html",
+		"application/xml;q=0.9, text/html;q=0.8": "application/xml",
+		"*/*":                                    "application/json",
+		"text/*, application/*;q=0.5":            "text/html",
+		// the most specific range wins: JSON is excluded despite */*
+		"application/json;q=0, */*;q=0.1": "application/xml",
+		// a browser
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": "text/html",
+		"image/png":     "",
+		"text/html;q=0": "",
+		"garbage":       "",
+	}
+	for accept, expected := range cases {
+		ctx := &Context{}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		ctx.Reset(httptest.NewRecorder(), req)
+		if format := ctx.NegotiateFormat(offered...); format != expected {
+			t.Errorf("Accept %q: expected %q, got %q", accept, expected, format)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+	negotiate := func(accept string) *httptest.ResponseRecorder {
+		ctx := &Context{}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		ctx.Reset(w, req)
+		_ = ctx.Negotiate(http.StatusCreated, OfferJSON(user{"jerry"}), OfferXML(user{"jerry"}), OfferString("user %s", "jerry"))
+		return w
+	}
+
+	w := negotiate("application/xml")
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != "application/xml; charset=utf-8" || w.Header().Get("Vary") != "Accept" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if w = negotiate("text/plain, application/json;q=0.5"); w.Body.String() != "user jerry" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if w = negotiate("text/html"); w.Code != http.StatusNotAcceptable || w.Header().Get("Vary") != "Accept" {
+		t.Fatalf("expected 406, got %d", w.Code)
+	}
+}
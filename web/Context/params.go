@@ -0,0 +1,328 @@
+package context
+
+import (
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The typed accessors below come in two forms for both query and post form parameters:
+//
+//   - GetQueryInt(key) (int, error) fails when the parameter is missing or cannot be parsed;
+//   - GetQueryIntWithDefault(key, defaultValue) int returns defaultValue in both cases.
+//
+// Every parse failure (and every missing parameter in the first form) is also collected,
+// so that a handler can answer 400 with all the bad parameters at once:
+//
+//	page := ctx.GetQueryIntWithDefault("page", 1)
+//	since, _ := ctx.GetQueryTime("since", time.RFC3339)
+//	if ctx.HasParamErrors() {
+//		ctx.JSON(http.StatusBadRequest, ctx.ParamErrors())
+//		return
+//	}
+
+// ParamErrors returns the errors collected by the typed query and form accessors during the request,
+// localized to the locale of the request.
+func (c *Context) ParamErrors() []error {
+	return c.paramErrors
+}
+
+// HasParamErrors reports whether a typed query or form accessor failed during the request.
+func (c *Context) HasParamErrors() bool {
+	return len(c.paramErrors) > 0
+}
+
+// parseParam parses the first value of a parameter with parse. A missing parameter or a value
+// that parse rejects produces an *I18n.Error, which is recorded in the errors of the Context.
+func parseParam[T any](c *Context, key string, values []string, ok bool, expected string, parse func(string) (T, error)) (T, error) {
+	var zero T
+	if !ok || len(values) == 0 {
+		err := c.LocalizeError(I18n.NewError(I18n.PARAM_MISSING, map[string]any{"field": key}))
+		c.paramErrors = append(c.paramErrors, err)
+		return zero, err
+	}
+	value, err := parse(values[0])
+	if err != nil {
+		err = c.LocalizeError(&I18n.Error{
+			Key:    I18n.PARAM_INVALID,
+			Params: map[string]any{"field": key, "expected": expected, "value": values[0]},
+			Err:    err,
+		})
+		c.paramErrors = append(c.paramErrors, err)
+		return zero, err
+	}
+	return value, nil
+}
+
+// parseParamWithDefault is like parseParam, but returns defaultValue when the parameter is
+// missing (without recording an error) or invalid.
+func parseParamWithDefault[T any](c *Context, key string, values []string, ok bool, expected string, parse func(string) (T, error), defaultValue T) T {
+	if !ok || len(values) == 0 {
+		return defaultValue
+	}
+	value, err := parseParam(c, key, values, ok, expected, parse)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func parseInt(s string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(s))
+}
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+}
+
+func parseUint(s string) (uint, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 0)
+	return uint(n), err
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+func parseBool(s string) (bool, error) {
+	return strconv.ParseBool(strings.TrimSpace(s))
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	return time.ParseDuration(strings.TrimSpace(s))
+}
+
+func timeParser(layout string) func(string) (time.Time, error) {
+	return func(s string) (time.Time, error) {
+		return time.Parse(layout, strings.TrimSpace(s))
+	}
+}
+
+// splitList splits every value on commas, e.g. ["a,b", "c"] gives ["a", "b", "c"].
+// Blank items are dropped.
+func splitList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func (c *Context) queryValues(key string) ([]string, bool) {
+	c.initQueryCache()
+	values, ok := c.queryCache[key]
+	return values, ok
+}
+
+func (c *Context) postFormValues(key string) ([]string, bool) {
+	c.initPostFormCache()
+	values, ok := c.formCache[key]
+	return values, ok
+}
+
+// GetQueryInt returns the query parameter key parsed as an int.
+func (c *Context) GetQueryInt(key string) (int, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "int", parseInt)
+}
+
+// GetQueryIntWithDefault returns the query parameter key parsed as an int, or defaultValue.
+func (c *Context) GetQueryIntWithDefault(key string, defaultValue int) int {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "int", parseInt, defaultValue)
+}
+
+// GetQueryInt64 returns the query parameter key parsed as an int64.
+func (c *Context) GetQueryInt64(key string) (int64, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "int64", parseInt64)
+}
+
+// GetQueryInt64WithDefault returns the query parameter key parsed as an int64, or defaultValue.
+func (c *Context) GetQueryInt64WithDefault(key string, defaultValue int64) int64 {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "int64", parseInt64, defaultValue)
+}
+
+// GetQueryUint returns the query parameter key parsed as a uint.
+func (c *Context) GetQueryUint(key string) (uint, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "uint", parseUint)
+}
+
+// GetQueryUintWithDefault returns the query parameter key parsed as a uint, or defaultValue.
+func (c *Context) GetQueryUintWithDefault(key string, defaultValue uint) uint {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "uint", parseUint, defaultValue)
+}
+
+// GetQueryFloat returns the query parameter key parsed as a float64.
+func (c *Context) GetQueryFloat(key string) (float64, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "float", parseFloat)
+}
+
+// GetQueryFloatWithDefault returns the query parameter key parsed as a float64, or defaultValue.
+func (c *Context) GetQueryFloatWithDefault(key string, defaultValue float64) float64 {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "float", parseFloat, defaultValue)
+}
+
+// GetQueryBool returns the query parameter key parsed as a bool (1, t, true, 0, f, false...).
+func (c *Context) GetQueryBool(key string) (bool, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "bool", parseBool)
+}
+
+// GetQueryBoolWithDefault returns the query parameter key parsed as a bool, or defaultValue.
+func (c *Context) GetQueryBoolWithDefault(key string, defaultValue bool) bool {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "bool", parseBool, defaultValue)
+}
+
+// GetQueryDuration returns the query parameter key parsed as a time.Duration, e.g. "1m30s".
+func (c *Context) GetQueryDuration(key string) (time.Duration, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "duration", parseDuration)
+}
+
+// GetQueryDurationWithDefault returns the query parameter key parsed as a time.Duration, or defaultValue.
+func (c *Context) GetQueryDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "duration", parseDuration, defaultValue)
+}
+
+// GetQueryTime returns the query parameter key parsed as a time.Time with layout, e.g. time.RFC3339.
+func (c *Context) GetQueryTime(key string, layout string) (time.Time, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, layout, timeParser(layout))
+}
+
+// GetQueryTimeWithDefault returns the query parameter key parsed as a time.Time with layout, or defaultValue.
+func (c *Context) GetQueryTimeWithDefault(key string, layout string, defaultValue time.Time) time.Time {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, layout, timeParser(layout), defaultValue)
+}
+
+// GetQueryList returns the comma-separated items of the query parameter key.
+// Repeated parameters are merged, so "?id=1,2&id=3" gives ["1", "2", "3"].
+func (c *Context) GetQueryList(key string) ([]string, error) {
+	values, ok := c.queryValues(key)
+	return parseParam(c, key, values, ok, "list", func(string) ([]string, error) {
+		return splitList(values), nil
+	})
+}
+
+// GetQueryListWithDefault returns the comma-separated items of the query parameter key, or defaultValue.
+func (c *Context) GetQueryListWithDefault(key string, defaultValue []string) []string {
+	values, ok := c.queryValues(key)
+	return parseParamWithDefault(c, key, values, ok, "list", func(string) ([]string, error) {
+		return splitList(values), nil
+	}, defaultValue)
+}
+
+// GetPostFormInt returns the form parameter key parsed as an int.
+func (c *Context) GetPostFormInt(key string) (int, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "int", parseInt)
+}
+
+// GetPostFormIntWithDefault returns the form parameter key parsed as an int, or defaultValue.
+func (c *Context) GetPostFormIntWithDefault(key string, defaultValue int) int {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "int", parseInt, defaultValue)
+}
+
+// GetPostFormInt64 returns the form parameter key parsed as an int64.
+func (c *Context) GetPostFormInt64(key string) (int64, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "int64", parseInt64)
+}
+
+// GetPostFormInt64WithDefault returns the form parameter key parsed as an int64, or defaultValue.
+func (c *Context) GetPostFormInt64WithDefault(key string, defaultValue int64) int64 {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "int64", parseInt64, defaultValue)
+}
+
+// GetPostFormUint returns the form parameter key parsed as a uint.
+func (c *Context) GetPostFormUint(key string) (uint, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "uint", parseUint)
+}
+
+// GetPostFormUintWithDefault returns the form parameter key parsed as a uint, or defaultValue.
+func (c *Context) GetPostFormUintWithDefault(key string, defaultValue uint) uint {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "uint", parseUint, defaultValue)
+}
+
+// GetPostFormFloat returns the form parameter key parsed as a float64.
+func (c *Context) GetPostFormFloat(key string) (float64, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "float", parseFloat)
+}
+
+// GetPostFormFloatWithDefault returns the form parameter key parsed as a float64, or defaultValue.
+func (c *Context) GetPostFormFloatWithDefault(key string, defaultValue float64) float64 {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "float", parseFloat, defaultValue)
+}
+
+// GetPostFormBool returns the form parameter key parsed as a bool (1, t, true, 0, f, false...).
+func (c *Context) GetPostFormBool(key string) (bool, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "bool", parseBool)
+}
+
+// GetPostFormBoolWithDefault returns the form parameter key parsed as a bool, or defaultValue.
+func (c *Context) GetPostFormBoolWithDefault(key string, defaultValue bool) bool {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "bool", parseBool, defaultValue)
+}
+
+// GetPostFormDuration returns the form parameter key parsed as a time.Duration, e.g. "1m30s".
+func (c *Context) GetPostFormDuration(key string) (time.Duration, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "duration", parseDuration)
+}
+
+// GetPostFormDurationWithDefault returns the form parameter key parsed as a time.Duration, or defaultValue.
+func (c *Context) GetPostFormDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "duration", parseDuration, defaultValue)
+}
+
+// GetPostFormTime returns the form parameter key parsed as a time.Time with layout, e.g. time.RFC3339.
+func (c *Context) GetPostFormTime(key string, layout string) (time.Time, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, layout, timeParser(layout))
+}
+
+// GetPostFormTimeWithDefault returns the form parameter key parsed as a time.Time with layout, or defaultValue.
+func (c *Context) GetPostFormTimeWithDefault(key string, layout string, defaultValue time.Time) time.Time {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, layout, timeParser(layout), defaultValue)
+}
+
+// GetPostFormList returns the comma-separated items of the form parameter key.
+// Repeated parameters are merged, so "id=1,2&id=3" gives ["1", "2", "3"].
+func (c *Context) GetPostFormList(key string) ([]string, error) {
+	values, ok := c.postFormValues(key)
+	return parseParam(c, key, values, ok, "list", func(string) ([]string, error) {
+		return splitList(values), nil
+	})
+}
+
+// GetPostFormListWithDefault returns the comma-separated items of the form parameter key, or defaultValue.
+func (c *Context) GetPostFormListWithDefault(key string, defaultValue []string) []string {
+	values, ok := c.postFormValues(key)
+	return parseParamWithDefault(c, key, values, ok, "list", func(string) ([]string, error) {
+		return splitList(values), nil
+	}, defaultValue)
+}
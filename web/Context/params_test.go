@@ -0,0 +1,126 @@
+package context
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func paramContext(query string, form string) *Context {
+	r := httptest.NewRequest(http.MethodPost, "/?"+query, strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := &Context{}
+	ctx.Reset(httptest.NewRecorder(), r)
+	return ctx
+}
+
+func TestParams(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		query string
+		get   func(ctx *Context) (any, error)
+		want  any
+		key   string // key is the message key of the expected error, if any
+	}{
+		{"page=2", func(ctx *Context) (any, error) { return ctx.GetQueryInt("page") }, 2, ""},
+		{"page=+2", func(ctx *Context) (any, error) { return ctx.GetQueryInt("page") }, 2, ""},
+		{"page=%202%20", func(ctx *Context) (any, error) { return ctx.GetQueryInt("page") }, 2, ""},
+		{"page=two", func(ctx *Context) (any, error) { return ctx.GetQueryInt("page") }, 0, I18n.PARAM_INVALID},
+		{"", func(ctx *Context) (any, error) { return ctx.GetQueryInt("page") }, 0, I18n.PARAM_MISSING},
+		{"id=9007199254740993", func(ctx *Context) (any, error) { return ctx.GetQueryInt64("id") }, int64(9007199254740993), ""},
+		{"n=-1", func(ctx *Context) (any, error) { return ctx.GetQueryUint("n") }, uint(0), I18n.PARAM_INVALID},
+		{"price=1.5", func(ctx *Context) (any, error) { return ctx.GetQueryFloat("price") }, 1.5, ""},
+		{"draft=1", func(ctx *Context) (any, error) { return ctx.GetQueryBool("draft") }, true, ""},
+		{"draft=yes", func(ctx *Context) (any, error) { return ctx.GetQueryBool("draft") }, false, I18n.PARAM_INVALID},
+		{"ttl=1m30s", func(ctx *Context) (any, error) { return ctx.GetQueryDuration("ttl") }, 90 * time.Second, ""},
+		{"ttl=90", func(ctx *Context) (any, error) { return ctx.GetQueryDuration("ttl") }, time.Duration(0), I18n.PARAM_INVALID},
+		{"since=2024-05-01T12:00:00Z", func(ctx *Context) (any, error) { return ctx.GetQueryTime("since", time.RFC3339) }, since, ""},
+		{"since=yesterday", func(ctx *Context) (any, error) { return ctx.GetQueryTime("since", time.RFC3339) }, time.Time{}, I18n.PARAM_INVALID},
+		{"tag=a,b&tag=c,,", func(ctx *Context) (any, error) { return ctx.GetQueryList("tag") }, []string{"a", "b", "c"}, ""},
+		{"", func(ctx *Context) (any, error) { return ctx.GetQueryList("tag") }, []string(nil), I18n.PARAM_MISSING},
+		// only the first value of a parameter is parsed
+		{"page=1&page=x", func(ctx *Context) (any, error) { return ctx.GetQueryInt("page") }, 1, ""},
+	}
+	for _, c := range cases {
+		ctx := paramContext(c.query, "")
+		got, err := c.get(ctx)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: expected %v, got %v", c.query, c.want, got)
+		}
+		var e *I18n.Error
+		switch {
+		case c.key == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", c.query, err)
+		case c.key != "" && (!errors.As(err, &e) || e.Key != c.key):
+			t.Errorf("%q: expected %s, got %v", c.query, c.key, err)
+		case c.key != "" && len(ctx.ParamErrors()) != 1:
+			t.Errorf("%q: expected the error to be collected, got %v", c.query, ctx.ParamErrors())
+		}
+	}
+}
+
+func TestParamsWithDefault(t *testing.T) {
+	cases := []struct {
+		query  string
+		form   string
+		get    func(ctx *Context) any
+		want   any
+		errors int // errors is the number of errors collected
+	}{
+		{"", "", func(ctx *Context) any { return ctx.GetQueryIntWithDefault("page", 1) }, 1, 0},
+		{"page=3", "", func(ctx *Context) any { return ctx.GetQueryIntWithDefault("page", 1) }, 3, 0},
+		{"page=x", "", func(ctx *Context) any { return ctx.GetQueryIntWithDefault("page", 1) }, 1, 1},
+		{"", "", func(ctx *Context) any { return ctx.GetQueryListWithDefault("tag", []string{"go"}) }, []string{"go"}, 0},
+		{"", "limit=20", func(ctx *Context) any { return ctx.GetPostFormUintWithDefault("limit", 10) }, uint(20), 0},
+		{"", "limit=-1", func(ctx *Context) any { return ctx.GetPostFormUintWithDefault("limit", 10) }, uint(10), 1},
+		{"", "", func(ctx *Context) any { return ctx.GetPostFormBoolWithDefault("draft", true) }, true, 0},
+		{"", "ttl=soon", func(ctx *Context) any { return ctx.GetPostFormDurationWithDefault("ttl", time.Second) }, time.Second, 1},
+		// the query does not fill the form parameters
+		{"limit=20", "", func(ctx *Context) any { return ctx.GetPostFormIntWithDefault("limit", 10) }, 10, 0},
+	}
+	for _, c := range cases {
+		ctx := paramContext(c.query, c.form)
+		if got := c.get(ctx); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q %q: expected %v, got %v", c.query, c.form, c.want, got)
+		}
+		if len(ctx.ParamErrors()) != c.errors || ctx.HasParamErrors() != (c.errors > 0) {
+			t.Errorf("%q %q: expected %d errors, got %v", c.query, c.form, c.errors, ctx.ParamErrors())
+		}
+	}
+}
+
+func TestParamErrors(t *testing.T) {
+	ctx := paramContext("page=x&since=never", "limit=1")
+	ctx.Catalog = I18n.NewCatalog()
+	ctx.Catalog.Register("fr", map[string]string{I18n.PARAM_MISSING: "le paramètre [{field}] est obligatoire"})
+	ctx.SetLocale("fr")
+
+	ctx.GetQueryIntWithDefault("page", 1)
+	_, _ = ctx.GetQueryTime("since", time.DateOnly)
+	_, _ = ctx.GetQueryFloat("price")
+	_, _ = ctx.GetPostFormInt("limit")
+	errs := ctx.ParamErrors()
+	want := []string{
+		"parameter [page] is not a valid int: x",
+		"parameter [since] is not a valid 2006-01-02: never",
+		"le paramètre [price] est obligatoire",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("expected %q, got %q", want[i], err)
+		}
+	}
+	// the parse error is kept as the cause
+	var e *I18n.Error
+	if !errors.As(errs[0], &e) || e.Err == nil || e.Field() != "page" {
+		t.Errorf("unexpected error %#v", errs[0])
+	}
+}
@@ -0,0 +1,36 @@
+package context
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"net/http"
+)
+
+// Problem answers the request with a problem details object (RFC 9457), as XML
+// (application/problem+xml) if the client prefers XML, and as JSON (application/problem+json)
+// otherwise. The status code of the response is the Status of the problem, 500 if it is not set.
+//
+// Parameters:
+//   - problem: The problem to answer, e.g. Render.NewProblem(http.StatusNotFound, "no such user").
+//
+// Returns:
+//   - An error if the rendering process fails, otherwise nil.
+//
+// Example:
+//
+//	ctx.Problem(Render.NewProblem(http.StatusForbidden, "your balance is 30, but that costs 50").
+//		With("balance", 30).
+//		With("accounts", []string{"/account/12345", "/account/67890"}))
+func (c *Context) Problem(problem Render.Problem) error {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+		if problem.Title == "" {
+			problem.Title = http.StatusText(problem.Status)
+		}
+	}
+	switch c.NegotiateFormat(Constant.PROBLEM_JSON_HEADER_CONTENT_TYPE, Constant.PROBLEM_XML_HEADER_CONTENT_TYPE, "application/json", "application/xml", "text/xml") {
+	case Constant.PROBLEM_XML_HEADER_CONTENT_TYPE, "application/xml", "text/xml":
+		return c.Render(problem.Status, Render.ProblemXMLRender{Problem: problem})
+	}
+	return c.Render(problem.Status, Render.ProblemRender{Problem: problem})
+}
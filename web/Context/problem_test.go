@@ -0,0 +1,58 @@
+package context
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	problem := Render.Problem{
+		Type:     "https://example.com/probs/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   http.StatusForbidden,
+		Detail:   "Your current balance is 30, but that costs 50.",
+		Instance: "/account/12345/msgs/abc",
+	}.With("balance", 30).With("accounts", []string{"/account/12345", "/account/67890"}).With("status", "ignored")
+
+	answer := func(accept string) *httptest.ResponseRecorder {
+		ctx := &Context{}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		ctx.Reset(w, req)
+		_ = ctx.Problem(problem)
+		return w
+	}
+
+	w := answer("application/json")
+	if w.Code != http.StatusForbidden || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	expected := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,` +
+		`"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc",` +
+		`"accounts":["/account/12345","/account/67890"],"balance":30}`
+	if w.Body.String() != expected {
+		t.Fatalf("unexpected JSON\n%s\n%s", w.Body.String(), expected)
+	}
+	var decoded Render.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil || decoded.Status != 403 ||
+		!reflect.DeepEqual(decoded.Extensions["accounts"], []any{"/account/12345", "/account/67890"}) {
+		t.Fatalf("unexpected decoded problem %+v %v", decoded, err)
+	}
+
+	w = answer("application/xml")
+	if w.Header().Get("Content-Type") != "application/problem+xml" {
+		t.Fatalf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+	expected = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<problem xmlns="urn:ietf:rfc:7807">` +
+		`<type>https://example.com/probs/out-of-credit</type><title>You do not have enough credit.</title><status>403</status>` +
+		`<detail>Your current balance is 30, but that costs 50.</detail><instance>/account/12345/msgs/abc</instance>` +
+		`<accounts><i>/account/12345</i><i>/account/67890</i></accounts><balance>30</balance></problem>`
+	if w.Body.String() != expected {
+		t.Fatalf("unexpected XML\n%s\n%s", w.Body.String(), expected)
+	}
+}
@@ -0,0 +1,215 @@
+package context
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/Utils"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath is returned when an uploaded file would be written outside of SaveOptions.BaseDir.
+var ErrUnsafePath = errors.New("destination is outside of the base directory")
+
+// SaveOptions configures SaveUploadedFileIn.
+type SaveOptions struct {
+	// BaseDir is the directory the file is confined to. It is created if it does not exist.
+	BaseDir string
+	// Name is the destination path, relative to BaseDir. If empty, the sanitized name sent by
+	// the client is used (see Utils.SanitizeFilename).
+	Name string
+	// UniqueName replaces the name of the file by a random one, keeping its extension.
+	UniqueName bool
+	// Overwrite allows replacing an existing file.
+	Overwrite bool
+	// DirPerm are the permissions of the created directories. Zero means 0755.
+	DirPerm os.FileMode
+	// FilePerm are the permissions of the saved file. Zero means 0644.
+	FilePerm os.FileMode
+	// AllowedTypes are the accepted content types, e.g. "image/png" or "image/*", checked against
+	// the type detected from the first bytes of the file (http.DetectContentType) rather than the
+	// type declared by the client. Empty means any type.
+	AllowedTypes []string
+}
+
+// SaveUploadedFileIn saves an uploaded file under options.BaseDir and returns the path of the saved file.
+// Unlike SaveUploadedFile, it is meant for file names coming from clients:
+//
+//   - the client file name is sanitized, or replaced by a unique one;
+//   - the destination cannot escape options.BaseDir, neither with its name (e.g. "../") nor
+//     through a symlink inside options.BaseDir;
+//   - missing directories are created with options.DirPerm;
+//   - the real type of the file is detected from its content and checked against options.AllowedTypes;
+//   - the file is written to a temp file first and published under its name in one step, so that
+//     a partial file is never visible; without options.Overwrite, it is hard linked, which fails
+//     if a file of the same name appears in the meantime, rather than renamed.
+//
+// The symlinks are resolved before the file is written: a party able to replace the directories
+// of options.BaseDir with symlinks while the file is being saved can still make it escape.
+//
+// Parameters:
+//   - file: The file header of the uploaded file to be saved.
+//   - options: Where and how the file is saved.
+//
+// Returns:
+//   - The path of the saved file.
+//   - error: ErrUnsafePath, ErrUnsupportedMediaType (wrapped in a localized error) or any I/O error.
+func (c *Context) SaveUploadedFileIn(file *multipart.FileHeader, options SaveOptions) (string, error) {
+	if options.BaseDir == "" {
+		return "", errors.New("a base directory is required to save uploaded files")
+	}
+	dst, err := destination(file.Filename, options)
+	if err != nil {
+		return "", err
+	}
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// sniff the real type of the file from its first bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	head = head[:n]
+	detected := http.DetectContentType(head)
+	if !(UploadLimits{AllowedTypes: options.AllowedTypes}).AllowsType(detected) {
+		return "", c.LocalizeError(uploadError(I18n.UPLOAD_UNSUPPORTED_TYPE, ErrUnsupportedMediaType,
+			map[string]any{"field": file.Filename, "file": file.Filename, "type": detected}))
+	}
+
+	dirPerm := options.DirPerm
+	if dirPerm == 0 {
+		dirPerm = 0755
+	}
+	base, err := filepath.Abs(options.BaseDir)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(dst)
+	if err := os.MkdirAll(base, dirPerm); err != nil {
+		return "", err
+	}
+	// no directory is created through a symlink leading out of the base directory
+	if err := checkConfined(base, dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return "", err
+	}
+	if err := checkConfined(base, dir); err != nil {
+		return "", err
+	}
+	if !options.Overwrite {
+		// a shortcut: the link of writeAtomically is what guarantees that no file is replaced
+		if _, err := os.Lstat(dst); err == nil {
+			return "", fmt.Errorf("%s: %w", dst, os.ErrExist)
+		}
+	}
+	if err := writeAtomically(dst, io.MultiReader(bytes.NewReader(head), src), options.FilePerm, options.Overwrite); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// destination computes the path an uploaded file is saved to, making sure it stays inside BaseDir.
+func destination(clientName string, options SaveOptions) (string, error) {
+	name := options.Name
+	if name == "" {
+		name = Utils.SanitizeFilename(clientName)
+	}
+	if options.UniqueName {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		ext := strings.ToLower(filepath.Ext(Utils.SanitizeFilename(clientName)))
+		name = filepath.Join(filepath.Dir(name), hex.EncodeToString(random)+ext)
+	}
+	base, err := filepath.Abs(options.BaseDir)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(base, filepath.FromSlash(name))
+	if dst == base || !within(base, dst) {
+		return "", ErrUnsafePath
+	}
+	return dst, nil
+}
+
+// within reports whether path is base or is inside base, lexically.
+func within(base string, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkConfined makes sure that dir stays inside base once the symlinks of both are resolved,
+// since destination only checks the names. The nearest existing ancestor of dir is resolved,
+// so that it can be checked before the missing directories are created.
+func checkConfined(base string, dir string) error {
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return err
+	}
+	for existing := dir; ; existing = filepath.Dir(existing) {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !within(realBase, resolved) {
+				return ErrUnsafePath
+			}
+			return nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || filepath.Dir(existing) == existing {
+			return err
+		}
+	}
+}
+
+// writeAtomically writes the content of src to a temp file next to dst, then renames it to dst,
+// or hard links it to dst without overwrite, failing with os.ErrExist if dst exists.
+func writeAtomically(dst string, src io.Reader, perm os.FileMode, overwrite bool) error {
+	if perm == 0 {
+		perm = 0644
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	// removing the temp file fails harmlessly once it has been renamed, and removes the name
+	// left once it has been linked
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, src); err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if overwrite {
+		return os.Rename(tmp.Name(), dst)
+	}
+	if err := os.Link(tmp.Name(), dst); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s: %w", dst, os.ErrExist)
+		}
+		return err
+	}
+	return nil
+}
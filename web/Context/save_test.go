@@ -0,0 +1,103 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// uploadedFile returns the file header of a file named name holding content, as sent by a client.
+func uploadedFile(t *testing.T, name string, content string) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", name)
+	_, _ = part.Write([]byte(content))
+	_ = writer.Close()
+	r := httptest.NewRequest("POST", "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	// the name is read as it is sent, e.g. with its directories
+	header := r.MultipartForm.File["file"][0]
+	header.Filename = name
+	return header
+}
+
+func TestSaveUploadedFileInConfinesNames(t *testing.T) {
+	base := t.TempDir()
+	ctx := &Context{}
+	for _, name := range []string{"../../escape.txt", "/etc/escape.txt", `..\..\escape.txt`} {
+		dst, err := ctx.SaveUploadedFileIn(uploadedFile(t, name, "hello"), SaveOptions{BaseDir: base})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if dst != filepath.Join(base, "escape.txt") {
+			t.Errorf("%s: expected the file in the base directory, got %s", name, dst)
+		}
+		_ = os.Remove(dst)
+	}
+	for _, name := range []string{"../escape.txt", "/etc/escape.txt", "sub/../../escape.txt", "."} {
+		_, err := ctx.SaveUploadedFileIn(uploadedFile(t, "a.txt", "hello"), SaveOptions{BaseDir: base, Name: name})
+		if name == "/etc/escape.txt" {
+			// an absolute name is joined to the base directory
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%s: expected ErrUnsafePath, got %v", name, err)
+		}
+	}
+}
+
+func TestSaveUploadedFileInConfinesSymlinks(t *testing.T) {
+	base, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+	ctx := &Context{}
+	for _, name := range []string{"link/a.txt", "link/sub/a.txt"} {
+		_, err := ctx.SaveUploadedFileIn(uploadedFile(t, "a.txt", "hello"), SaveOptions{BaseDir: base, Name: name})
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%s: expected ErrUnsafePath, got %v", name, err)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("files were written outside of the base directory: %v", entries)
+	}
+}
+
+func TestSaveUploadedFileInOverwrite(t *testing.T) {
+	base := t.TempDir()
+	ctx := &Context{}
+	dst, err := ctx.SaveUploadedFileIn(uploadedFile(t, "a.txt", "first"), SaveOptions{BaseDir: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ctx.SaveUploadedFileIn(uploadedFile(t, "a.txt", "second"), SaveOptions{BaseDir: base})
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected os.ErrExist, got %v", err)
+	}
+	// the link fails even when the file appears after the shortcut check
+	if err := writeAtomically(dst, bytes.NewReader([]byte("second")), 0, false); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected os.ErrExist, got %v", err)
+	}
+	if content, _ := os.ReadFile(dst); string(content) != "first" {
+		t.Errorf("the file was overwritten: %q", content)
+	}
+	if _, err := ctx.SaveUploadedFileIn(uploadedFile(t, "a.txt", "third"), SaveOptions{BaseDir: base, Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(dst); string(content) != "third" {
+		t.Errorf("expected the file to be overwritten, got %q", content)
+	}
+	if entries, _ := os.ReadDir(base); len(entries) != 1 {
+		t.Errorf("temp files were left behind: %v", entries)
+	}
+}
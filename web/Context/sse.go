@@ -0,0 +1,187 @@
+package context
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStreamClosed is returned when writing to an event stream whose handler has returned.
+var ErrStreamClosed = errors.New("event stream closed")
+
+// SSEWriter writes a stream of server-sent events (text/event-stream). Every event is
+// flushed to the client as soon as it is written. It is safe for concurrent use, and is
+// closed when the handler returns.
+type SSEWriter struct {
+	w           http.ResponseWriter
+	r           *http.Request
+	controller  *http.ResponseController
+	mu          sync.Mutex
+	closed      chan struct{}
+	nextID      string
+	lastEventID string
+}
+
+// SSE starts a server-sent event stream: it writes the headers of the stream and returns
+// a writer for its events. The handler keeps the stream open as long as it does not return,
+// and should return once the client has gone (see SSEWriter.Done).
+//
+// Returns:
+//   - *SSEWriter: The writer of the events.
+//   - error: http.ErrNotSupported if the response cannot be flushed.
+//
+// Example:
+//
+//	stream, err := ctx.SSE()
+//	if err != nil {
+//		return
+//	}
+//	go stream.KeepAlive(15 * time.Second)
+//	for {
+//		select {
+//		case <-stream.Done():
+//			return
+//		case message := <-messages:
+//			stream.ID(message.ID)
+//			stream.Event("message", message)
+//		}
+//	}
+func (c *Context) SSE() (*SSEWriter, error) {
+	controller := http.NewResponseController(c.W)
+	header := c.W.Header()
+	Render.SSEvent{}.WriteContentType(c.W)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disables the buffering of proxies such as nginx
+	header.Set("X-Accel-Buffering", "no")
+	c.W.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return nil, err
+	}
+	c.sse = &SSEWriter{
+		w:           c.W,
+		r:           c.R,
+		controller:  controller,
+		closed:      make(chan struct{}),
+		lastEventID: c.R.Header.Get("Last-Event-ID"),
+	}
+	return c.sse, nil
+}
+
+// LastEventID returns the id of the last event received by the client before it reconnected,
+// sent in the Last-Event-ID header, so that the stream can resume after it. It is empty for new clients.
+func (s *SSEWriter) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel closed when the client disconnects.
+func (s *SSEWriter) Done() <-chan struct{} {
+	return s.r.Context().Done()
+}
+
+// ID sets the id of the next event sent by Event or Data.
+func (s *SSEWriter) ID(id string) {
+	s.mu.Lock()
+	s.nextID = id
+	s.mu.Unlock()
+}
+
+// Event sends an event named name. Strings and byte slices are sent as they are,
+// anything else is serialized as JSON, the same way as Context.JSON does.
+func (s *SSEWriter) Event(name string, data any) error {
+	return s.Send(Render.SSEvent{Event: name, Data: data})
+}
+
+// Data sends an unnamed event, dispatched by browsers as a "message" event.
+func (s *SSEWriter) Data(data any) error {
+	return s.Send(Render.SSEvent{Data: data})
+}
+
+// Retry tells the client how long to wait before reconnecting when the stream is interrupted.
+func (s *SSEWriter) Retry(delay time.Duration) error {
+	return s.write(Render.SSEvent{Retry: delay}, false)
+}
+
+// Comment sends a comment, ignored by clients. Comments keep idle connections from being closed by proxies.
+func (s *SSEWriter) Comment(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r", ""), "\n")
+	if _, err := s.w.Write([]byte(": " + strings.Join(lines, "\n: ") + "\n\n")); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// KeepAlive sends a comment every interval until the client disconnects, the handler
+// returns or a write fails. It blocks, and is meant to be started in its own goroutine.
+func (s *SSEWriter) KeepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.Done():
+			return
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			if s.Comment("keep-alive") != nil {
+				return
+			}
+		}
+	}
+}
+
+// Send sends an event, using the id set by ID if the event has none.
+func (s *SSEWriter) Send(event Render.SSEvent) error {
+	return s.write(event, true)
+}
+
+// write sends an event and flushes it, failing with the error of the request context once the client has gone.
+// withID attaches the id set by ID to the event.
+func (s *SSEWriter) write(event Render.SSEvent, withID bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if withID {
+		if event.ID == "" {
+			event.ID = s.nextID
+		}
+		s.nextID = ""
+	}
+	if err := event.Render(s.w); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// writable returns the error of the request context once the client has gone,
+// or ErrStreamClosed once the handler has returned. It must be called with s.mu held.
+func (s *SSEWriter) writable() error {
+	select {
+	case <-s.closed:
+		return ErrStreamClosed
+	default:
+	}
+	return s.r.Context().Err()
+}
+
+// close stops the stream, so that goroutines still holding the writer cannot write
+// to the response once the handler has returned.
+func (s *SSEWriter) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+}
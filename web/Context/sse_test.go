@@ -0,0 +1,69 @@
+package context
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	disconnected := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := &Context{}
+		ctx.Reset(w, r)
+		defer ctx.Finish()
+		stream, err := ctx.SSE()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = stream.Retry(3 * time.Second)
+		stream.ID("7")
+		_ = stream.Event("greeting", map[string]string{"resume": stream.LastEventID()})
+		_ = stream.Data("line 1\nline 2")
+		_ = stream.Comment("ping")
+		<-stream.Done()
+		disconnected <- stream.Data("too late")
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", "6")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Header.Get("Content-Type") != "text/event-stream; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", res.Header.Get("Content-Type"))
+	}
+
+	// the events are flushed while the handler is still running
+	expected := []string{
+		"retry: 3000", "",
+		"event: greeting", "id: 7", `data: {"resume":"6"}`, "",
+		"data: line 1", "data: line 2", "",
+		": ping", "",
+	}
+	scanner := bufio.NewScanner(res.Body)
+	for _, line := range expected {
+		if !scanner.Scan() {
+			t.Fatalf("stream ended early: %v", scanner.Err())
+		}
+		if scanner.Text() != line {
+			t.Fatalf("expected %q, got %q", line, scanner.Text())
+		}
+	}
+	res.Body.Close()
+
+	select {
+	case err := <-disconnected:
+		if err == nil || !strings.Contains(err.Error(), "canceled") {
+			t.Fatalf("expected a canceled context, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnect not detected")
+	}
+}
@@ -0,0 +1,84 @@
+package context
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Storage"
+	"io"
+	"mime/multipart"
+)
+
+// SaveUploadedFileTo copies an uploaded file to a storage backend under key.
+// The key must not be built from the client file name without sanitizing it
+// (see Utils.SanitizeFilename).
+//
+// Parameters:
+//   - store: The storage the file is copied to.
+//   - key: The key the file is stored under.
+//   - file: The file header of the uploaded file to be saved.
+//
+// Returns:
+//   - Storage.Object: The description of the stored object.
+//   - error: Storage.ErrInvalidKey or any error of the storage.
+func (c *Context) SaveUploadedFileTo(store Storage.Storage, key string, file *multipart.FileHeader) (Storage.Object, error) {
+	src, err := file.Open()
+	if err != nil {
+		return Storage.Object{}, err
+	}
+	defer src.Close()
+	return store.Put(c.R.Context(), key, src, file.Size, file.Header.Get("Content-Type"))
+}
+
+// StreamUploadsTo streams the files of a multipart/form-data request directly into a storage
+// backend, as they are received: the files are not parsed into memory or temp files first
+// (see MultipartForm), and their size is unknown to the backend. What the backend buffers
+// depends on it: Storage.Local writes the file next to its key and renames it once complete,
+// and Storage.S3 holds one part of S3Config.PartSize in memory at a time (see Storage.S3.Put).
+// The key of every file part is
+// given by keyFunc; returning an empty key skips the part. Plain form values are skipped.
+// The upload limits of the Context are enforced while streaming (see MultipartReader), and the
+// objects stored before an error are deleted.
+//
+// Parameters:
+//   - store: The storage the files are streamed to.
+//   - keyFunc: Returns the key of a file part.
+//
+// Returns:
+//   - []Storage.Object: The stored objects, in the order of the request.
+//   - error: An upload error (see AbortUpload) or any error of the storage.
+//
+// Example:
+//
+//	objects, err := ctx.StreamUploadsTo(store, func(part *context.UploadPart) string {
+//		return "avatars/" + Utils.SanitizeFilename(part.FileName())
+//	})
+//	if err != nil {
+//		ctx.AbortUpload(err)
+//	}
+func (c *Context) StreamUploadsTo(store Storage.Storage, keyFunc func(part *UploadPart) string) ([]Storage.Object, error) {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return nil, c.LocalizeError(err)
+	}
+	objects := make([]Storage.Object, 0)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err == nil && part.IsFile() {
+			if key := keyFunc(part); key != "" {
+				var object Storage.Object
+				object, err = store.Put(c.R.Context(), key, part, -1, part.Header.Get("Content-Type"))
+				if err == nil {
+					objects = append(objects, object)
+				}
+			}
+		}
+		if err != nil {
+			for _, object := range objects {
+				_ = store.Delete(c.R.Context(), object.Key)
+			}
+			return nil, c.LocalizeError(err)
+		}
+	}
+}
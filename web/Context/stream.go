@@ -0,0 +1,47 @@
+package context
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Stream writes the response incrementally: step is called repeatedly with the response
+// writer, and what it wrote is flushed to the client after every call, until step returns
+// false or the client disconnects. Headers, such as the Content-Type, must be set before.
+//
+// Parameters:
+//   - step: Writes the next part of the response, and returns whether there is more to write.
+//
+// Returns:
+//   - bool: true if the client disconnected before the end of the stream.
+//
+// Example:
+//
+//	ctx.W.Header().Set("Content-Type", "text/csv")
+//	ctx.Stream(func(w io.Writer) bool {
+//		row, ok := <-rows
+//		if !ok {
+//			return false
+//		}
+//		fmt.Fprintf(w, "%s,%d\n", row.Name, row.Count)
+//		return true
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	controller := http.NewResponseController(c.W)
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+		}
+		more := step(c.W)
+		if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package context
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	ctx := &Context{}
+	w := httptest.NewRecorder()
+	ctx.Reset(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	i := 0
+	gone := ctx.Stream(func(w io.Writer) bool {
+		i++
+		fmt.Fprintf(w, "%d,", i)
+		return i < 3
+	})
+	if gone || w.Body.String() != "1,2,3," || !w.Flushed {
+		t.Fatalf("got %q, gone %v, flushed %v", w.Body.String(), gone, w.Flushed)
+	}
+
+	type row struct {
+		ID int `json:"id"`
+	}
+	w = httptest.NewRecorder()
+	ctx.Reset(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	_ = ctx.Render(http.StatusOK, Render.JSONArrayRender[row]{Items: Render.Slice([]row{{1}, {2}})})
+	if w.Body.String() != `[{"id":1},{"id":2}]` {
+		t.Fatalf("unexpected array %q", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	ctx.Reset(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	_ = ctx.Render(http.StatusOK, Render.JSONArrayRender[row]{Items: Render.Slice([]row{})})
+	if w.Body.String() != `[]` {
+		t.Fatalf("unexpected empty array %q", w.Body.String())
+	}
+}
+
+func TestNDJSONDisconnect(t *testing.T) {
+	stopped := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := &Context{}
+		ctx.Reset(w, r)
+		// an endless stream, fed by a producer that gives up with the request
+		rows := make(chan int)
+		go func() {
+			defer close(rows)
+			for i := 0; ; i++ {
+				select {
+				case rows <- i:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}()
+		stopped <- ctx.Render(http.StatusOK, Render.NDJSONRender[int]{Items: Render.Chan(rows), Done: r.Context().Done()})
+	}))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected Content-Type %q", res.Header.Get("Content-Type"))
+	}
+	scanner := bufio.NewScanner(res.Body)
+	for _, line := range []string{"0", "1", "2"} {
+		if !scanner.Scan() || scanner.Text() != line {
+			t.Fatalf("expected %q, got %q", line, scanner.Text())
+		}
+	}
+	res.Body.Close()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("expected a clean stop, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnect not detected")
+	}
+}
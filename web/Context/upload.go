@@ -0,0 +1,348 @@
+package context
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/Progress"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrUploadTooLarge is returned when the request body exceeds UploadLimits.MaxTotalSize.
+	ErrUploadTooLarge = errors.New("upload too large")
+	// ErrFileTooLarge is returned when an uploaded file exceeds UploadLimits.MaxFileSize.
+	ErrFileTooLarge = errors.New("uploaded file too large")
+	// ErrTooManyFiles is returned when more files than UploadLimits.MaxFiles are uploaded.
+	ErrTooManyFiles = errors.New("too many uploaded files")
+	// ErrUnsupportedMediaType is returned when an uploaded file is not one of UploadLimits.AllowedTypes.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// UploadLimits restricts what multipart/form-data uploads may contain.
+// The zero value means no limit, with Constant.DEFAULT_MAX_MEMORY of memory used by MultipartForm.
+// Limits are set for the whole engine by Engine.UploadLimits, and per route with the
+// web.UploadLimit middleware.
+type UploadLimits struct {
+	// MaxMemory is the memory MultipartForm uses for file parts before storing them in temp files.
+	MaxMemory int64
+	// MaxTotalSize is the largest request body accepted, in bytes.
+	MaxTotalSize int64
+	// MaxFileSize is the largest file accepted, in bytes.
+	MaxFileSize int64
+	// MaxFiles is the largest number of files accepted in a request.
+	MaxFiles int
+	// AllowedTypes are the MIME types accepted for files, e.g. "image/png" or "image/*".
+	// They are checked against the Content-Type of the parts, which is declared by the client
+	// and not checked against the content of the files: SaveOptions.AllowedTypes checks the
+	// type detected from the content when the file is saved.
+	AllowedTypes []string
+}
+
+// maxMemory returns the memory MultipartForm may use.
+func (l UploadLimits) maxMemory() int64 {
+	if l.MaxMemory > 0 {
+		return l.MaxMemory
+	}
+	return Constant.DEFAULT_MAX_MEMORY
+}
+
+// AllowsType reports whether files of the given Content-Type may be uploaded.
+func (l UploadLimits) AllowsType(contentType string) bool {
+	if len(l.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	for _, allowed := range l.AllowedTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*/*" || allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadErrorStatus returns the HTTP status code matching an upload error:
+// 413 Request Entity Too Large for exceeded limits, 415 Unsupported Media Type for rejected
+// file types and 400 Bad Request for anything else.
+func UploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUploadTooLarge), errors.Is(err, ErrFileTooLarge), errors.Is(err, ErrTooManyFiles):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+// AbortUpload answers the request with the status matching err (see UploadErrorStatus)
+// and its localized message.
+func (c *Context) AbortUpload(err error) error {
+	if c.progress != nil {
+		c.progress.Fail(err)
+	}
+	return c.String(UploadErrorStatus(err), c.LocalizeError(err).Error())
+}
+
+func uploadError(key string, sentinel error, params map[string]any) error {
+	return &I18n.Error{Key: key, Params: params, Err: sentinel}
+}
+
+// limitBody rejects requests whose declared length exceeds MaxTotalSize and makes sure the
+// body cannot be read beyond it.
+func (c *Context) limitBody() error {
+	limit := c.UploadLimits.MaxTotalSize
+	if limit <= 0 || c.uploadBodyLimited {
+		return nil
+	}
+	if c.R.ContentLength > limit {
+		return uploadError(I18n.UPLOAD_TOO_LARGE, ErrUploadTooLarge, map[string]any{"limit": limit})
+	}
+	c.R.Body = http.MaxBytesReader(c.W, c.R.Body, limit)
+	c.uploadBodyLimited = true
+	return nil
+}
+
+// trackProgress reports the bytes read from the body to the ProgressTracker, when the client
+// identified its upload with an X-Progress-ID header or query parameter.
+func (c *Context) trackProgress() {
+	if c.ProgressTracker == nil || c.progress != nil {
+		return
+	}
+	id := c.R.Header.Get(Progress.HEADER)
+	if id == "" {
+		id = c.R.URL.Query().Get(Progress.HEADER)
+	}
+	if id == "" {
+		return
+	}
+	c.progress = Progress.NewReader(c.R.Body, c.ProgressTracker, id, c.R.ContentLength)
+	c.R.Body = c.progress
+}
+
+// parseMultipartForm parses the multipart form of the request once, applying the upload limits.
+func (c *Context) parseMultipartForm() error {
+	if c.R.MultipartForm != nil || c.uploadErr != nil {
+		return c.uploadErr
+	}
+	c.rewindBody()
+	defer c.rewindBody()
+	if err := c.limitBody(); err != nil {
+		c.uploadErr = err
+		return err
+	}
+	c.trackProgress()
+	// the query, and the body if it is not multipart, like http.Request.ParseMultipartForm
+	err := c.R.ParseForm()
+	if err == nil {
+		err = c.readMultipartForm()
+	}
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) || errors.Is(err, multipart.ErrMessageTooLarge) {
+		err = uploadError(I18n.UPLOAD_TOO_LARGE, ErrUploadTooLarge, map[string]any{"limit": c.UploadLimits.MaxTotalSize})
+	}
+	if c.progress != nil {
+		if err != nil {
+			c.progress.Fail(err)
+		} else {
+			c.progress.Finish()
+		}
+	}
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		if c.R.MultipartForm != nil {
+			_ = c.R.MultipartForm.RemoveAll()
+		}
+		c.uploadErr = err
+	}
+	return err
+}
+
+// readMultipartForm reads the multipart form of the request into c.R.MultipartForm, checking the
+// upload limits part by part as they arrive (see MultipartReader), rather than once the whole
+// body has been stored: a file over the limits stops the parsing before the rest of the body is
+// read. The accepted parts are encoded again into a pipe read by multipart.Reader.ReadForm,
+// which keeps the files of the form in memory up to UploadLimits.MaxMemory, in temp files beyond.
+func (c *Context) readMultipartForm() error {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		// MultipartReader marks the form as read by a reader even when the body is not multipart
+		c.R.MultipartForm = nil
+		return err
+	}
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	var partsErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		partsErr = copyParts(reader, writer)
+		if partsErr == nil {
+			partsErr = writer.Close()
+		}
+		// nil closes the pipe with io.EOF
+		_ = pw.CloseWithError(partsErr)
+	}()
+	form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(c.UploadLimits.maxMemory())
+	// unblocks copyParts if ReadForm stopped before the end of the parts
+	_ = pr.Close()
+	<-done
+	if partsErr != nil && !errors.Is(partsErr, io.ErrClosedPipe) {
+		err = partsErr
+	}
+	if err != nil {
+		if form != nil {
+			_ = form.RemoveAll()
+		}
+		c.R.MultipartForm = nil
+		return err
+	}
+	if c.R.PostForm == nil {
+		c.R.PostForm = make(url.Values)
+	}
+	for key, values := range form.Value {
+		c.R.Form[key] = append(c.R.Form[key], values...)
+		c.R.PostForm[key] = append(c.R.PostForm[key], values...)
+	}
+	c.R.MultipartForm = form
+	return nil
+}
+
+// copyParts copies the parts of reader to writer, until the last part or the first error,
+// e.g. a part over the upload limits.
+func copyParts(reader *MultipartReader, writer *multipart.Writer) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, part); err != nil {
+			return err
+		}
+	}
+}
+
+// MultipartReader streams the parts of a multipart/form-data request as they arrive,
+// applying the upload limits of the Context.
+type MultipartReader struct {
+	reader   *multipart.Reader
+	limits   UploadLimits
+	files    int
+	progress *Progress.Reader
+}
+
+// UploadPart is a part of a streamed multipart request. Reading a file part beyond
+// UploadLimits.MaxFileSize fails with ErrFileTooLarge.
+type UploadPart struct {
+	*multipart.Part
+	limit int64
+	read  int64
+}
+
+// IsFile reports whether the part is a file rather than a plain form value.
+func (p *UploadPart) IsFile() bool {
+	return p.FileName() != ""
+}
+
+// Read reads the content of the part.
+func (p *UploadPart) Read(b []byte) (int, error) {
+	if p.limit > 0 {
+		if p.read > p.limit {
+			return 0, p.tooLarge()
+		}
+		if int64(len(b)) > p.limit-p.read+1 {
+			// never read more than one byte past the limit
+			b = b[:p.limit-p.read+1]
+		}
+	}
+	n, err := p.Part.Read(b)
+	p.read += int64(n)
+	if p.limit > 0 && p.read > p.limit {
+		return n, p.tooLarge()
+	}
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return n, uploadError(I18n.UPLOAD_TOO_LARGE, ErrUploadTooLarge, map[string]any{"limit": maxBytesError.Limit})
+	}
+	return n, err
+}
+
+func (p *UploadPart) tooLarge() error {
+	return uploadError(I18n.UPLOAD_FILE_TOO_LARGE, ErrFileTooLarge,
+		map[string]any{"field": p.FormName(), "file": p.FileName(), "limit": p.limit})
+}
+
+// MultipartReader returns a reader that streams the parts of a multipart/form-data request,
+// so that large uploads can be processed (e.g. copied to storage) without being buffered
+// in memory or in temp files. The upload limits of the Context are enforced as parts are read.
+// It cannot be used together with MultipartForm, FormFile or the post form accessors.
+//
+// Example:
+//
+//	reader, err := ctx.MultipartReader()
+//	for err == nil {
+//		var part *context.UploadPart
+//		if part, err = reader.NextPart(); err == nil && part.IsFile() {
+//			_, err = io.Copy(dst, part)
+//		}
+//	}
+//	if err != io.EOF {
+//		ctx.AbortUpload(err)
+//	}
+func (c *Context) MultipartReader() (*MultipartReader, error) {
+	if err := c.limitBody(); err != nil {
+		return nil, err
+	}
+	c.trackProgress()
+	reader, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartReader{reader: reader, limits: c.UploadLimits, progress: c.progress}, nil
+}
+
+// NextPart returns the next part of the request, or io.EOF when there are no more parts.
+func (r *MultipartReader) NextPart() (*UploadPart, error) {
+	part, err := r.reader.NextPart()
+	if err == io.EOF && r.progress != nil {
+		r.progress.Finish()
+	}
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, uploadError(I18n.UPLOAD_TOO_LARGE, ErrUploadTooLarge, map[string]any{"limit": maxBytesError.Limit})
+		}
+		return nil, err
+	}
+	p := &UploadPart{Part: part}
+	if !p.IsFile() {
+		return p, nil
+	}
+	r.files++
+	if r.limits.MaxFiles > 0 && r.files > r.limits.MaxFiles {
+		return nil, uploadError(I18n.UPLOAD_TOO_MANY_FILES, ErrTooManyFiles, map[string]any{"limit": r.limits.MaxFiles})
+	}
+	if contentType := part.Header.Get("Content-Type"); !r.limits.AllowsType(contentType) {
+		return nil, uploadError(I18n.UPLOAD_UNSUPPORTED_TYPE, ErrUnsupportedMediaType,
+			map[string]any{"field": part.FormName(), "file": part.FileName(), "type": contentType})
+	}
+	p.limit = r.limits.MaxFileSize
+	return p, nil
+}
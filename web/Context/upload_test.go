@@ -0,0 +1,126 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.read += n
+	return n, err
+}
+
+// uploadContext returns a Context for a multipart request made of the given parts, each a
+// form name, a file name (empty for plain values), a content type and a content, the body
+// being counted by the returned reader.
+func uploadContext(limits UploadLimits, parts ...[4]string) (*Context, *countingReader, int) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		if part[1] == "" {
+			header.Set("Content-Disposition", `form-data; name="`+part[0]+`"`)
+		} else {
+			header.Set("Content-Disposition", `form-data; name="`+part[0]+`"; filename="`+part[1]+`"`)
+			header.Set("Content-Type", part[2])
+		}
+		w, _ := writer.CreatePart(header)
+		_, _ = io.WriteString(w, part[3])
+	}
+	_ = writer.Close()
+	size := body.Len()
+	counter := &countingReader{r: &body}
+	r := httptest.NewRequest(http.MethodPost, "/upload?page=1", io.NopCloser(counter))
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx := &Context{}
+	ctx.Reset(httptest.NewRecorder(), r)
+	ctx.UploadLimits = limits
+	return ctx, counter, size
+}
+
+func TestMultipartForm(t *testing.T) {
+	// the files beyond MaxMemory are stored in temp files
+	ctx, _, _ := uploadContext(UploadLimits{MaxMemory: 8, MaxFiles: 2, MaxFileSize: 64, AllowedTypes: []string{"text/*"}},
+		[4]string{"title", "", "", "notes"},
+		[4]string{"file", "a.txt", "text/plain", "hello"},
+		[4]string{"file", "b.txt", "text/csv", strings.Repeat("b", 64)})
+	if title, _ := ctx.GetPostForm("title"); title != "notes" || ctx.R.FormValue("page") != "1" {
+		t.Errorf("unexpected form values %v", ctx.R.Form)
+	}
+	files := ctx.FormFiles("file")
+	if len(files) != 2 || files[0].Filename != "a.txt" || files[1].Size != 64 {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	for _, file := range files {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(f)
+		f.Close()
+		if int64(len(content)) != file.Size {
+			t.Errorf("%s: read %d bytes out of %d", file.Filename, len(content), file.Size)
+		}
+	}
+	_ = ctx.R.MultipartForm.RemoveAll()
+}
+
+func TestMultipartFormLimits(t *testing.T) {
+	// the parts after the rejected one are never read
+	rest := [4]string{"rest", "rest.bin", "text/plain", strings.Repeat("x", 1<<20)}
+	tests := []struct {
+		name   string
+		limits UploadLimits
+		parts  [][4]string
+		err    error
+	}{
+		{"file too large", UploadLimits{MaxFileSize: 4}, [][4]string{{"file", "a.txt", "text/plain", "hello world"}, rest}, ErrFileTooLarge},
+		{"too many files", UploadLimits{MaxFiles: 1}, [][4]string{{"file", "a.txt", "text/plain", "a"}, {"file", "b.txt", "text/plain", "b"}, rest}, ErrTooManyFiles},
+		{"unsupported type", UploadLimits{AllowedTypes: []string{"image/*"}}, [][4]string{{"file", "a.txt", "text/plain", "a"}, rest}, ErrUnsupportedMediaType},
+		{"upload too large", UploadLimits{MaxTotalSize: 1 << 10}, [][4]string{{"file", "a.txt", "text/plain", "a"}, rest}, ErrUploadTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, counter, size := uploadContext(test.limits, test.parts...)
+			// the declared length would reject the request before reading it
+			ctx.R.ContentLength = -1
+			_, err := ctx.MultipartForm()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if counter.read >= size/2 {
+				t.Errorf("read %d bytes out of %d before rejecting the upload", counter.read, size)
+			}
+			if _, err := ctx.FormFile("file"); !errors.Is(err, test.err) {
+				t.Errorf("expected the error to be kept, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMultipartFormNotMultipart(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=jo"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := &Context{}
+	ctx.Reset(httptest.NewRecorder(), r)
+	if _, err := ctx.MultipartForm(); !errors.Is(err, http.ErrNotMultipart) {
+		t.Errorf("expected http.ErrNotMultipart, got %v", err)
+	}
+	if name, _ := ctx.GetPostForm("name"); name != "jo" {
+		t.Errorf("expected the urlencoded form to be parsed, got %v", ctx.R.PostForm)
+	}
+}
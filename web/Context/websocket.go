@@ -0,0 +1,35 @@
+package context
+
+import (
+	"github.com/Jerry20000730/Gjango/web/WebSocket"
+)
+
+// Upgrade performs the WebSocket opening handshake (RFC 6455) of the request and takes over
+// its connection. Once upgraded, c.W must not be used anymore. If the request is not a valid
+// handshake, the HTTP error has already been answered.
+//
+// Parameters:
+//   - options: How the connection is negotiated (subprotocols, allowed origins, read limit);
+//     nil means the default options.
+//
+// Returns:
+//   - *WebSocket.Conn: The WebSocket connection. It must be closed by the handler.
+//   - error: A *WebSocket.HandshakeError, or the error of the hijacking.
+//
+// Example:
+//
+//	conn, err := ctx.Upgrade(&WebSocket.Options{ReadLimit: 64 << 10})
+//	if err != nil {
+//		return
+//	}
+//	defer conn.Close()
+//	for {
+//		messageType, data, err := conn.ReadMessage()
+//		if err != nil {
+//			return
+//		}
+//		conn.WriteMessage(messageType, data)
+//	}
+func (c *Context) Upgrade(options *WebSocket.Options) (*WebSocket.Conn, error) {
+	return WebSocket.Upgrade(c.W, c.R, options)
+}
@@ -1,10 +1,14 @@
 package File
 
 import (
+	"errors"
 	context "github.com/Jerry20000730/Gjango/web/Context" // Custom context package for handling web contexts.
+	"github.com/Jerry20000730/Gjango/web/Storage"         // Storage backends files can be served from.
 	"github.com/Jerry20000730/Gjango/web/Utils"           // Utility package for miscellaneous helper functions.
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
 // FileManager provides methods for file handling operations such as downloading,
@@ -42,6 +46,78 @@ func (f *FileManager) FileAttachment(ctx *context.Context, filePath, fileName st
 	http.ServeFile(ctx.W, ctx.R, filePath)
 }
 
+// FileFromStorage serves an object from a storage backend. When the backend returns a
+// seekable reader (Local and Memory), range and conditional requests are supported through
+// http.ServeContent; otherwise (S3) the object is streamed as is. A missing object answers
+// 404 Not Found, any other failure 500 Internal Server Error.
+//
+// Parameters:
+// - ctx: The web context containing the HTTP request and response writer.
+// - store: The storage the object is read from.
+// - key: The key of the object to be served.
+//
+// Returns:
+// - error: The error of the storage, or of writing the response.
+func (f *FileManager) FileFromStorage(ctx *context.Context, store Storage.Storage, key string) error {
+	reader, object, err := store.Get(ctx.R.Context(), key)
+	if err != nil {
+		if errors.Is(err, Storage.ErrNotExist) || errors.Is(err, Storage.ErrInvalidKey) {
+			http.NotFound(ctx.W, ctx.R)
+		} else {
+			http.Error(ctx.W, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return err
+	}
+	defer reader.Close()
+
+	header := ctx.W.Header()
+	if object.ContentType != "" {
+		header.Set("Content-Type", object.ContentType)
+	}
+	if object.ETag != "" {
+		header.Set("ETag", `"`+object.ETag+`"`)
+	}
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.W, ctx.R, object.Key, object.ModTime, seeker)
+		return nil
+	}
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/octet-stream")
+	}
+	if object.Size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	if !object.ModTime.IsZero() {
+		header.Set("Last-Modified", object.ModTime.UTC().Format(http.TimeFormat))
+	}
+	ctx.W.WriteHeader(http.StatusOK)
+	if ctx.R.Method == http.MethodHead {
+		return nil
+	}
+	_, err = io.Copy(ctx.W, reader)
+	return err
+}
+
+// StorageAttachment serves an object from a storage backend for download, suggesting
+// fileName to the client as FileAttachment does.
+//
+// Parameters:
+// - ctx: The web context containing the HTTP request and response writer.
+// - store: The storage the object is read from.
+// - key: The key of the object to be downloaded.
+// - fileName: The suggested filename for the download. Special characters are handled.
+//
+// Returns:
+// - error: The error of the storage, or of writing the response.
+func (f *FileManager) StorageAttachment(ctx *context.Context, store Storage.Storage, key, fileName string) error {
+	if Utils.IsASCII(fileName) {
+		ctx.W.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	} else {
+		ctx.W.Header().Set("Content-Disposition", `attachment; filename*=UTF-8''`+url.QueryEscape(fileName))
+	}
+	return f.FileFromStorage(ctx, store, key)
+}
+
 // FileFromFileSystem serves a file from a specified file system. It temporarily modifies
 // the request URL path to the path of the file to be served, ensuring the file server
 // serves the correct file.
@@ -0,0 +1,343 @@
+// Package Hub pushes messages to groups of long-lived connections: subscribers join topics,
+// and every message published on a topic is delivered to its subscribers. Messages travel
+// through a pluggable Broker, so that several nodes can share their topics.
+package Hub
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSlowConsumer is the error of a subscription disconnected by the Disconnect policy.
+	ErrSlowConsumer = errors.New("hub: subscriber too slow, disconnected")
+	// ErrClosed is returned when using a closed hub or subscription.
+	ErrClosed = errors.New("hub: closed")
+)
+
+// DEFAULT_BUFFER is the number of messages buffered for a subscriber without SubscribeOptions.Buffer.
+const DEFAULT_BUFFER = 16
+
+// Policy decides what happens to a message when the buffer of a subscriber is full.
+type Policy int
+
+const (
+	// DropNewest drops the message that does not fit.
+	DropNewest Policy = iota
+	// DropOldest drops the oldest buffered message to make room.
+	DropOldest
+	// Disconnect closes the subscription with ErrSlowConsumer.
+	Disconnect
+)
+
+// Message is a message published on a topic. With a multi-node broker, Data must be serializable.
+type Message struct {
+	Topic string    `json:"topic"`
+	Data  any       `json:"data"`
+	Time  time.Time `json:"time"`
+}
+
+// Broker carries the messages between the hubs of the nodes. It is told which topics have
+// subscribers on this node, and delivers the messages published on them, whichever node
+// published them.
+type Broker interface {
+	// Publish sends a message to every node subscribed to its topic, this one included.
+	Publish(ctx context.Context, message Message) error
+	// Subscribe starts the delivery of the messages of topic to deliver, until unsubscribe is
+	// called. The hub subscribes when the first subscriber of the topic joins on this node,
+	// and unsubscribes when the last one leaves. The broker must not hold its own locks while
+	// calling deliver.
+	Subscribe(topic string, deliver func(Message)) (unsubscribe func(), err error)
+}
+
+// SubscribeOptions configures a subscription.
+type SubscribeOptions struct {
+	// Buffer is the number of messages buffered for the subscriber. Zero means DEFAULT_BUFFER.
+	Buffer int
+	// Policy decides what happens to messages that do not fit in the buffer.
+	Policy Policy
+}
+
+// Hub delivers the messages of topics to their subscribers.
+type Hub struct {
+	broker Broker
+
+	mu      sync.RWMutex
+	topics  map[string]map[*Subscription]struct{}
+	cancels map[string]func()
+	closed  bool
+}
+
+// New creates a hub on a broker. Nil means an in-process broker of its own.
+func New(broker Broker) *Hub {
+	if broker == nil {
+		broker = NewMemoryBroker()
+	}
+	return &Hub{
+		broker:  broker,
+		topics:  make(map[string]map[*Subscription]struct{}),
+		cancels: make(map[string]func()),
+	}
+}
+
+// Publish sends data to the subscribers of topic, on every node.
+func (h *Hub) Publish(ctx context.Context, topic string, data any) error {
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+	return h.broker.Publish(ctx, Message{Topic: topic, Data: data, Time: time.Now()})
+}
+
+// Subscribe creates a subscription to topics. More topics can be joined and left later
+// with Subscription.Subscribe and Subscription.Unsubscribe.
+func (h *Hub) Subscribe(options SubscribeOptions, topics ...string) (*Subscription, error) {
+	if options.Buffer <= 0 {
+		options.Buffer = DEFAULT_BUFFER
+	}
+	s := &Subscription{
+		hub:      h,
+		policy:   options.Policy,
+		messages: make(chan Message, options.Buffer),
+		done:     make(chan struct{}),
+		topics:   make(map[string]struct{}),
+	}
+	if err := s.Subscribe(topics...); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Presence returns the number of subscribers of topic on this node.
+func (h *Hub) Presence(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Topics returns the topics having subscribers on this node, sorted.
+func (h *Hub) Topics() []string {
+	h.mu.RLock()
+	topics := make([]string, 0, len(h.topics))
+	for topic := range h.topics {
+		topics = append(topics, topic)
+	}
+	h.mu.RUnlock()
+	sort.Strings(topics)
+	return topics
+}
+
+// Close closes every subscription and stops the delivery of messages.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subscriptions := make(map[*Subscription]struct{})
+	for _, subscribers := range h.topics {
+		for s := range subscribers {
+			subscriptions[s] = struct{}{}
+		}
+	}
+	h.mu.Unlock()
+	for s := range subscriptions {
+		s.end(ErrClosed)
+	}
+	h.mu.Lock()
+	for topic, unsubscribe := range h.cancels {
+		unsubscribe()
+		delete(h.cancels, topic)
+		delete(h.topics, topic)
+	}
+	h.mu.Unlock()
+}
+
+// deliver hands a message to the subscribers of its topic on this node.
+func (h *Hub) deliver(message Message) {
+	h.mu.RLock()
+	var slow []*Subscription
+	for s := range h.topics[message.Topic] {
+		if !s.offer(message) {
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+	for _, s := range slow {
+		s.end(ErrSlowConsumer)
+	}
+}
+
+func (h *Hub) join(s *Subscription, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrClosed
+	}
+	subscribers, ok := h.topics[topic]
+	if !ok {
+		unsubscribe, err := h.broker.Subscribe(topic, h.deliver)
+		if err != nil {
+			return err
+		}
+		subscribers = make(map[*Subscription]struct{})
+		h.topics[topic] = subscribers
+		h.cancels[topic] = unsubscribe
+	}
+	subscribers[s] = struct{}{}
+	return nil
+}
+
+func (h *Hub) leave(s *Subscription, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subscribers, ok := h.topics[topic]
+	if !ok {
+		return
+	}
+	delete(subscribers, s)
+	if len(subscribers) == 0 {
+		delete(h.topics, topic)
+		if unsubscribe, ok := h.cancels[topic]; ok {
+			delete(h.cancels, topic)
+			unsubscribe()
+		}
+	}
+}
+
+// Subscription receives the messages of the topics it joined.
+type Subscription struct {
+	hub    *Hub
+	policy Policy
+
+	mu       sync.Mutex
+	messages chan Message
+	done     chan struct{}
+	topics   map[string]struct{}
+	err      error
+	dropped  uint64
+}
+
+// Messages returns the channel of the messages received. It is closed when the subscription ends.
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Done returns a channel closed when the subscription ends.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscription ended: ErrSlowConsumer, ErrClosed, or nil while it is active.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped returns the number of messages dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Subscribe joins more topics.
+func (s *Subscription) Subscribe(topics ...string) error {
+	for _, topic := range topics {
+		s.mu.Lock()
+		_, joined := s.topics[topic]
+		ended := s.err != nil
+		s.mu.Unlock()
+		if ended {
+			return s.Err()
+		}
+		if joined {
+			continue
+		}
+		if err := s.hub.join(s, topic); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		if s.err != nil {
+			// ended while joining
+			s.mu.Unlock()
+			s.hub.leave(s, topic)
+			return s.Err()
+		}
+		s.topics[topic] = struct{}{}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Unsubscribe leaves topics.
+func (s *Subscription) Unsubscribe(topics ...string) {
+	for _, topic := range topics {
+		s.mu.Lock()
+		delete(s.topics, topic)
+		s.mu.Unlock()
+		s.hub.leave(s, topic)
+	}
+}
+
+// Close leaves every topic and ends the subscription.
+func (s *Subscription) Close() {
+	s.end(ErrClosed)
+}
+
+// offer buffers a message according to the policy. It returns false if the subscriber
+// must be disconnected.
+func (s *Subscription) offer(message Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return true
+	}
+	select {
+	case s.messages <- message:
+		return true
+	default:
+	}
+	switch s.policy {
+	case DropOldest:
+		select {
+		case <-s.messages:
+		default:
+		}
+		s.dropped++
+		select {
+		case s.messages <- message:
+		default:
+		}
+		return true
+	case Disconnect:
+		return false
+	}
+	s.dropped++
+	return true
+}
+
+// end leaves every topic and closes the channels of the subscription, once.
+func (s *Subscription) end(err error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = err
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	s.topics = make(map[string]struct{})
+	close(s.messages)
+	close(s.done)
+	s.mu.Unlock()
+	for _, topic := range topics {
+		s.hub.leave(s, topic)
+	}
+}
@@ -0,0 +1,184 @@
+package Hub
+
+import (
+	"bufio"
+	ctx "context"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/WebSocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, s *Subscription) Message {
+	t.Helper()
+	select {
+	case message := <-s.Messages():
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return Message{}
+}
+
+func TestHub(t *testing.T) {
+	broker := NewMemoryBroker()
+	node1, node2 := New(broker), New(broker)
+	defer node1.Close()
+	defer node2.Close()
+
+	a, _ := node1.Subscribe(SubscribeOptions{}, "news", "sport")
+	b, _ := node2.Subscribe(SubscribeOptions{}, "news")
+	if node1.Presence("news") != 1 || node2.Presence("news") != 1 || node1.Presence("sport") != 1 {
+		t.Fatalf("unexpected presence %d %d", node1.Presence("news"), node2.Presence("news"))
+	}
+
+	// messages reach the subscribers of every node
+	_ = node2.Publish(ctx.Background(), "news", "hello")
+	if message := receive(t, a); message.Topic != "news" || message.Data != "hello" {
+		t.Fatalf("unexpected message %+v", message)
+	}
+	if message := receive(t, b); message.Data != "hello" {
+		t.Fatalf("unexpected message %+v", message)
+	}
+	_ = node1.Publish(ctx.Background(), "sport", 1)
+	if message := receive(t, a); message.Topic != "sport" {
+		t.Fatalf("unexpected message %+v", message)
+	}
+
+	// leaving a topic
+	a.Unsubscribe("sport")
+	if node1.Presence("sport") != 0 || strings.Join(node1.Topics(), ",") != "news" {
+		t.Fatalf("unexpected topics %v", node1.Topics())
+	}
+	_ = b.Subscribe("sport")
+	_ = node1.Publish(ctx.Background(), "sport", 2)
+	if message := receive(t, b); message.Data != 2 {
+		t.Fatalf("unexpected message %+v", message)
+	}
+	select {
+	case message := <-a.Messages():
+		t.Fatalf("unexpected message %+v", message)
+	default:
+	}
+
+	// closing
+	b.Close()
+	if _, ok := <-b.Messages(); ok || node2.Presence("news") != 0 {
+		t.Fatal("subscription not closed")
+	}
+	node1.Close()
+	if a.Err() != ErrClosed || node1.Publish(ctx.Background(), "news", 3) != ErrClosed {
+		t.Fatalf("hub not closed: %v", a.Err())
+	}
+	if _, err := node1.Subscribe(SubscribeOptions{}, "news"); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	hub := New(nil)
+	defer hub.Close()
+	newest, _ := hub.Subscribe(SubscribeOptions{Buffer: 2, Policy: DropNewest}, "t")
+	oldest, _ := hub.Subscribe(SubscribeOptions{Buffer: 2, Policy: DropOldest}, "t")
+	slow, _ := hub.Subscribe(SubscribeOptions{Buffer: 2, Policy: Disconnect}, "t")
+	for i := 1; i <= 4; i++ {
+		_ = hub.Publish(ctx.Background(), "t", i)
+	}
+
+	if receive(t, newest).Data != 1 || receive(t, newest).Data != 2 || newest.Dropped() != 2 {
+		t.Fatalf("DropNewest kept the wrong messages, dropped %d", newest.Dropped())
+	}
+	if receive(t, oldest).Data != 3 || receive(t, oldest).Data != 4 || oldest.Dropped() != 2 {
+		t.Fatalf("DropOldest kept the wrong messages, dropped %d", oldest.Dropped())
+	}
+	<-slow.Done()
+	if !errors.Is(slow.Err(), ErrSlowConsumer) || hub.Presence("t") != 2 {
+		t.Fatalf("expected a disconnected subscriber, got %v", slow.Err())
+	}
+}
+
+func TestServeSSE(t *testing.T) {
+	hub := New(nil)
+	defer hub.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &context.Context{}
+		c.Reset(w, r)
+		defer c.Finish()
+		stream, err := c.SSE()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		sub, _ := hub.Subscribe(SubscribeOptions{}, "chat")
+		_ = sub.ServeSSE(stream)
+	}))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hub.Presence("chat") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = hub.Publish(ctx.Background(), "chat", map[string]string{"text": "hi"})
+	scanner := bufio.NewScanner(res.Body)
+	for _, line := range []string{"event: chat", `data: {"text":"hi"}`} {
+		if !scanner.Scan() || scanner.Text() != line {
+			t.Fatalf("expected %q, got %q", line, scanner.Text())
+		}
+	}
+	res.Body.Close()
+
+	// the subscription ends with the stream
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.Presence("chat") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not closed after the client went away")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServeWebSocket(t *testing.T) {
+	hub := New(nil)
+	defer hub.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := WebSocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sub, _ := hub.Subscribe(SubscribeOptions{})
+		// the client joins rooms by sending their names
+		_ = sub.ServeWebSocket(conn, func(messageType int, data []byte) {
+			_ = sub.Subscribe(string(data))
+		})
+	}))
+	defer server.Close()
+
+	conn, _, err := WebSocket.Dial(ctx.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_ = conn.WriteMessage(WebSocket.TextMessage, []byte("lobby"))
+	for hub.Presence("lobby") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = hub.Publish(ctx.Background(), "lobby", "welcome")
+	if _, data, err := conn.ReadMessage(); err != nil || !strings.HasPrefix(string(data), `{"topic":"lobby","data":"welcome"`) {
+		t.Fatalf("got %s %v", data, err)
+	}
+
+	// closing the hub closes the connection
+	hub.Close()
+	if _, _, err := conn.ReadMessage(); !WebSocket.IsCloseError(err, WebSocket.CloseGoingAway) {
+		t.Fatalf("expected a going away close, got %v", err)
+	}
+	conn.Close()
+}
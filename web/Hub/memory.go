@@ -0,0 +1,57 @@
+package Hub
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker is an in-process Broker. Sharing one between several hubs connects them as
+// if they were nodes of a cluster.
+type MemoryBroker struct {
+	mu     sync.RWMutex
+	topics map[string]map[*func(Message)]struct{}
+}
+
+// NewMemoryBroker creates an in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{topics: make(map[string]map[*func(Message)]struct{})}
+}
+
+// Publish delivers message to the subscribers of its topic, synchronously.
+func (b *MemoryBroker) Publish(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.RLock()
+	delivers := make([]func(Message), 0, len(b.topics[message.Topic]))
+	for deliver := range b.topics[message.Topic] {
+		delivers = append(delivers, *deliver)
+	}
+	b.mu.RUnlock()
+	for _, deliver := range delivers {
+		deliver(message)
+	}
+	return nil
+}
+
+// Subscribe delivers the messages of topic to deliver until unsubscribe is called.
+func (b *MemoryBroker) Subscribe(topic string, deliver func(Message)) (func(), error) {
+	key := &deliver
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*func(Message)]struct{})
+	}
+	b.topics[topic][key] = struct{}{}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.topics[topic], key)
+			if len(b.topics[topic]) == 0 {
+				delete(b.topics, topic)
+			}
+		})
+	}, nil
+}
@@ -0,0 +1,127 @@
+package Hub
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"github.com/Jerry20000730/Gjango/web/WebSocket"
+	"time"
+)
+
+// ServeSSE sends the messages of the subscription to an event stream, as events named after
+// their topic, until the client goes away or the subscription ends. The subscription is
+// closed on return.
+//
+// Parameters:
+//   - stream: The event stream, as returned by Context.SSE.
+//
+// Returns:
+//   - error: nil when the client went away, the error of the subscription when it ended
+//     (e.g. ErrSlowConsumer), or the error of the stream.
+//
+// Example:
+//
+//	g.Get("/rooms/:room/events", func(ctx *context.Context) {
+//		stream, err := ctx.SSE()
+//		if err != nil {
+//			return
+//		}
+//		sub, err := hub.Subscribe(Hub.SubscribeOptions{Policy: Hub.DropOldest}, ctx.Param("room"))
+//		if err != nil {
+//			return
+//		}
+//		_ = sub.ServeSSE(stream)
+//	})
+func (s *Subscription) ServeSSE(stream *context.SSEWriter) error {
+	defer s.Close()
+	for {
+		select {
+		case <-stream.Done():
+			return nil
+		case message, ok := <-s.messages:
+			if !ok {
+				return s.endError()
+			}
+			if err := stream.Event(message.Topic, message.Data); err != nil {
+				if errors.Is(err, context.ErrStreamClosed) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// ServeWebSocket sends the messages of the subscription to a WebSocket connection, as JSON
+// text messages {"topic": ..., "data": ..., "time": ...}, until the connection or the
+// subscription ends. The messages of the client are read concurrently and handed to
+// onMessage, which may for instance join or leave topics; onMessage is not called anymore
+// once ServeWebSocket has returned. The subscription is closed on return, and when it ends
+// first, a close frame telling why is sent to the client.
+//
+// Parameters:
+//   - conn: The WebSocket connection.
+//   - onMessage: Receives the messages of the client; nil discards them.
+//
+// Returns:
+//   - error: nil when the client closed the connection, the error of the subscription when
+//     it ended (e.g. ErrSlowConsumer), or the error of the connection.
+func (s *Subscription) ServeWebSocket(conn *WebSocket.Conn, onMessage func(messageType int, data []byte)) error {
+	defer s.Close()
+	readErr := make(chan error, 1)
+	reading := true
+	defer func() {
+		if reading {
+			// unblock the reader, so that onMessage is not called after return
+			_ = conn.SetReadDeadline(time.Now())
+			<-readErr
+		}
+	}()
+	go func() {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			if onMessage != nil {
+				onMessage(messageType, data)
+			}
+		}
+	}()
+	for {
+		select {
+		case err := <-readErr:
+			reading = false
+			if WebSocket.IsCloseError(err, WebSocket.CloseNormalClosure, WebSocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		case message, ok := <-s.messages:
+			if !ok {
+				err := s.endError()
+				code, reason := WebSocket.CloseGoingAway, "hub closed"
+				if errors.Is(err, ErrSlowConsumer) {
+					code, reason = WebSocket.ClosePolicyViolation, "subscriber too slow"
+				}
+				_ = conn.WriteClose(code, reason)
+				return err
+			}
+			data, err := Render.EncodeJSON(message)
+			if err != nil {
+				return err
+			}
+			if err := conn.WriteMessage(WebSocket.TextMessage, data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// endError returns the error of an ended subscription, nil if it was closed by its owner.
+func (s *Subscription) endError() error {
+	if err := s.Err(); err != ErrClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package I18n provides the message catalog used to localize the errors the
+// framework reports to clients, such as validation and binding failures.
+package I18n
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DEFAULT_LANGUAGE is the language every catalog falls back to unless told otherwise.
+const DEFAULT_LANGUAGE = "en"
+
+// Default is the catalog used when a Context has not been given one by the engine.
+var Default = NewCatalog()
+
+// Catalog stores translated message templates per language.
+// A template may contain placeholders such as {field} or {param}, which are
+// replaced by the parameters passed to Translate.
+type Catalog struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates a catalog that already contains the framework's English messages
+// and falls back to English when a message is missing in the requested language.
+func NewCatalog() *Catalog {
+	c := &Catalog{
+		fallback: DEFAULT_LANGUAGE,
+		messages: make(map[string]map[string]string),
+	}
+	c.Register(DEFAULT_LANGUAGE, englishMessages)
+	return c
+}
+
+// Register adds (or overrides) the message templates of a language.
+// Registering the same language twice merges the two sets of messages.
+func (c *Catalog) Register(lang string, messages map[string]string) {
+	lang = normalize(lang)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.messages[lang]; !ok {
+		c.messages[lang] = make(map[string]string, len(messages))
+	}
+	for key, message := range messages {
+		c.messages[lang][key] = message
+	}
+}
+
+// SetFallback changes the language used when no better match can be found.
+func (c *Catalog) SetFallback(lang string) {
+	c.mu.Lock()
+	c.fallback = normalize(lang)
+	c.mu.Unlock()
+}
+
+// Fallback returns the language used when no better match can be found.
+func (c *Catalog) Fallback() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fallback
+}
+
+// Languages returns every language registered in the catalog.
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	languages := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		languages = append(languages, lang)
+	}
+	return languages
+}
+
+// Has reports whether the language has been registered in the catalog.
+func (c *Catalog) Has(lang string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.messages[normalize(lang)]
+	return ok
+}
+
+// Translate looks up the message for key in lang and fills its placeholders with params.
+// The lookup tries the exact language ("pt-br"), then its base language ("pt"), then the
+// fallback language. If the key is unknown everywhere, the key itself is returned.
+func (c *Catalog) Translate(lang string, key string, params map[string]any) string {
+	return format(c.lookup(lang, key), params)
+}
+
+func (c *Catalog) lookup(lang string, key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	lang = normalize(lang)
+	candidates := []string{lang}
+	if i := strings.IndexByte(lang, '-'); i > 0 {
+		candidates = append(candidates, lang[:i])
+	}
+	candidates = append(candidates, c.fallback)
+	for _, candidate := range candidates {
+		if message, ok := c.messages[candidate][key]; ok {
+			return message
+		}
+	}
+	return key
+}
+
+// format replaces every {name} placeholder in message with the matching parameter.
+// Placeholders without a parameter are left untouched.
+func format(message string, params map[string]any) string {
+	if len(params) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// normalize turns language tags like "en_US" or "EN-us" into the "en-us" form used as catalog keys.
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
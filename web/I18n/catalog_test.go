@@ -0,0 +1,41 @@
+package I18n
+
+import "testing"
+
+func newFrenchCatalog() *Catalog {
+	c := NewCatalog()
+	c.Register("fr", map[string]string{VALIDATE_REQUIRED: "le champ [{field}] est obligatoire"})
+	c.Register("fr-CH", map[string]string{VALIDATE_MIN: "[{field}] : au moins {param}"})
+	return c
+}
+
+func TestTranslate(t *testing.T) {
+	c := newFrenchCatalog()
+	params := map[string]any{"field": "name", "param": 2}
+	cases := []struct {
+		lang string
+		key  string
+		want string
+	}{
+		{"fr", VALIDATE_REQUIRED, "le champ [name] est obligatoire"},
+		// the regional language falls back to its base language, then to the fallback one
+		{"fr_ch", VALIDATE_MIN, "[name] : au moins 2"},
+		{"FR-ch", VALIDATE_REQUIRED, "le champ [name] est obligatoire"},
+		{"fr", VALIDATE_MIN, Default.Translate("en", VALIDATE_MIN, params)},
+		{"de", VALIDATE_REQUIRED, "field [name] is required"},
+		{"fr", "unknown.key", "unknown.key"},
+	}
+	for _, tc := range cases {
+		if got := c.Translate(tc.lang, tc.key, params); got != tc.want {
+			t.Errorf("%s %s: expected %q, got %q", tc.lang, tc.key, tc.want, got)
+		}
+	}
+
+	c.SetFallback("fr")
+	if got := c.Translate("de", VALIDATE_REQUIRED, params); got != "le champ [name] est obligatoire" {
+		t.Errorf("unexpected fallback message %q", got)
+	}
+	if got := c.Translate("fr", VALIDATE_REQUIRED, nil); got != "le champ [{field}] est obligatoire" {
+		t.Errorf("placeholders without parameters should be kept: %q", got)
+	}
+}
@@ -0,0 +1,185 @@
+package I18n
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Error is an error whose message comes from a Catalog.
+// Until it is localized, Error() renders the message with the Default catalog
+// in its fallback language.
+type Error struct {
+	Key    string         // Key identifies the message template in the catalog.
+	Params map[string]any // Params fill the placeholders of the template, e.g. "field".
+	Err    error          // Err is the optional underlying error.
+
+	message string
+}
+
+// NewError creates a localizable error for the given message key and parameters.
+func NewError(key string, params map[string]any) *Error {
+	return &Error{Key: key, Params: params}
+}
+
+// Error returns the localized message if the error has been localized,
+// otherwise the message of the Default catalog.
+func (e *Error) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return Default.Translate(Default.Fallback(), e.Key, e.Params)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Field returns the "field" parameter of the error, or an empty string if there is none.
+func (e *Error) Field() string {
+	field, _ := e.Params["field"].(string)
+	return field
+}
+
+// MarshalJSON renders the error as {"code": key, "field": field, "message": message},
+// so that errors can be sent to clients as they are.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code    string `json:"code"`
+		Field   string `json:"field,omitempty"`
+		Message string `json:"message"`
+	}{Code: e.Key, Field: e.Field(), Message: e.Error()})
+}
+
+// Localize renders the message of e in lang using catalog c.
+func (e *Error) Localize(c *Catalog, lang string) string {
+	return c.Translate(lang, e.Key, e.Params)
+}
+
+// Localize returns a copy of err in which every *Error, including those wrapped with
+// fmt.Errorf("%w") or joined with errors.Join, is localized, so that calling Error() on the
+// result yields messages in lang. err itself is left untouched, since it may be shared, e.g.
+// a sentinel or an error cached by a binder. The errors wrapping catalog errors are copied
+// too: their messages are rendered again with the localized messages of the wrapped errors,
+// and errors.Is and errors.As still match them. Errors that do not contain catalog errors
+// are returned unchanged.
+func Localize(err error, c *Catalog, lang string) error {
+	if err == nil {
+		return nil
+	}
+	if c == nil {
+		c = Default
+	}
+	localized, _ := localize(err, c, lang)
+	return localized
+}
+
+// localize returns the localized copy of err, and whether it differs from err.
+func localize(err error, c *Catalog, lang string) (error, bool) {
+	switch wrapper := err.(type) {
+	case *Error:
+		localized := *wrapper
+		localized.message = wrapper.Localize(c, lang)
+		if wrapper.Err != nil {
+			localized.Err, _ = localize(wrapper.Err, c, lang)
+		}
+		return &localized, true
+	case interface{ Unwrap() error }:
+		inner := wrapper.Unwrap()
+		if inner == nil {
+			return err, false
+		}
+		localizedInner, changed := localize(inner, c, lang)
+		if !changed {
+			return err, false
+		}
+		message := rewrite(err.Error(), []error{inner}, []error{localizedInner})
+		return &wrapError{copied{err, message}, localizedInner}, true
+	case interface{ Unwrap() []error }:
+		inner := wrapper.Unwrap()
+		localizedInner := make([]error, len(inner))
+		changed := false
+		for i, e := range inner {
+			var ok bool
+			localizedInner[i], ok = localize(e, c, lang)
+			changed = changed || ok
+		}
+		if !changed {
+			return err, false
+		}
+		message := rewrite(err.Error(), inner, localizedInner)
+		return &joinError{copied{err, message}, localizedInner}, true
+	}
+	return err, false
+}
+
+// rewrite replaces in order the messages of errs found in message with the messages of their
+// copies.
+func rewrite(message string, errs []error, copies []error) string {
+	var b strings.Builder
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		old := err.Error()
+		at := strings.Index(message, old)
+		if at < 0 {
+			continue
+		}
+		b.WriteString(message[:at])
+		b.WriteString(copies[i].Error())
+		message = message[at+len(old):]
+	}
+	b.WriteString(message)
+	return b.String()
+}
+
+// copied is the localized copy of an error wrapping catalog errors.
+type copied struct {
+	original error
+	message  string
+}
+
+func (c copied) Error() string {
+	return c.message
+}
+
+// Is reports whether the original error is target.
+func (c copied) Is(target error) bool {
+	return reflect.TypeOf(target).Comparable() && c.original == target
+}
+
+// As finds the original error itself in target, e.g. a *web.HTTPError; the errors it wraps
+// are found through the localized copies.
+func (c copied) As(target any) bool {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return false
+	}
+	if reflect.TypeOf(c.original).AssignableTo(value.Type().Elem()) {
+		value.Elem().Set(reflect.ValueOf(c.original))
+		return true
+	}
+	return false
+}
+
+// wrapError is the localized copy of an error wrapping a single error.
+type wrapError struct {
+	copied
+	wrapped error
+}
+
+func (w *wrapError) Unwrap() error {
+	return w.wrapped
+}
+
+// joinError is the localized copy of an error wrapping several errors, e.g. with errors.Join.
+type joinError struct {
+	copied
+	wrapped []error
+}
+
+func (j *joinError) Unwrap() []error {
+	return j.wrapped
+}
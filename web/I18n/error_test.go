@@ -0,0 +1,39 @@
+package I18n
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	c := newFrenchCatalog()
+	required := NewError(VALIDATE_REQUIRED, map[string]any{"field": "name"})
+	invalid := &Error{Key: VALIDATE_REQUIRED, Params: map[string]any{"field": "age"}, Err: io.ErrUnexpectedEOF}
+	err := fmt.Errorf("binding: %w", errors.Join(required, invalid, io.EOF))
+
+	localized := Localize(err, c, "fr")
+	want := "binding: le champ [name] est obligatoire\nle champ [age] est obligatoire\nEOF"
+	if localized.Error() != want {
+		t.Errorf("expected %q, got %q", want, localized.Error())
+	}
+	// the errors given are shared, and left untouched
+	if required.Error() != "field [name] is required" || err.Error() != "binding: field [name] is required\nfield [age] is required\nEOF" {
+		t.Errorf("the original errors have been modified: %q", err)
+	}
+
+	var e *Error
+	if !errors.As(localized, &e) || e == required || e.Error() != "le champ [name] est obligatoire" {
+		t.Errorf("expected the localized copy, got %v", e)
+	}
+	if !errors.Is(localized, io.EOF) || !errors.Is(localized, io.ErrUnexpectedEOF) || !errors.Is(localized, err) {
+		t.Error("the localized copy should still match the wrapped errors")
+	}
+	if got := Localize(io.EOF, c, "fr"); got != io.EOF {
+		t.Errorf("errors without catalog errors should be returned unchanged, got %v", got)
+	}
+	if Localize(nil, c, "fr") != nil {
+		t.Error("nil should stay nil")
+	}
+}
@@ -0,0 +1,82 @@
+package I18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// languageRange is one entry of an Accept-Language header, e.g. "fr-CH;q=0.9".
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// ParseAcceptLanguage parses an Accept-Language header into language tags ordered by preference.
+// Entries with q=0 are dropped, entries with an invalid q value are treated as q=1.
+//
+// Example:
+//
+//	ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5")
+//	// ["fr-ch", "fr", "en", "*"]
+func ParseAcceptLanguage(header string) []string {
+	ranges := make([]languageRange, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := normalize(fields[0])
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, quality: quality})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// Match picks the registered language that best satisfies an Accept-Language header.
+// A requested "fr-ch" matches a registered "fr-ch" first, then "fr", and a requested
+// "fr" matches a registered regional variant such as "fr-ch". If nothing matches,
+// the fallback language is returned.
+func (c *Catalog) Match(acceptLanguage string) string {
+	languages := c.Languages()
+	sort.Strings(languages)
+	for _, tag := range ParseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if c.Has(tag) {
+			return tag
+		}
+		base := tag
+		if i := strings.IndexByte(tag, '-'); i > 0 {
+			base = tag[:i]
+		}
+		if c.Has(base) {
+			return base
+		}
+		for _, lang := range languages {
+			if strings.HasPrefix(lang, base+"-") {
+				return lang
+			}
+		}
+	}
+	return c.Fallback()
+}
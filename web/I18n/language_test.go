@@ -0,0 +1,46 @@
+package I18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	cases := []struct {
+		header string
+		want   []string
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5", []string{"fr-ch", "fr", "en", "*"}},
+		{"en;q=0.5, de;q=0, fr", []string{"fr", "en"}},
+		{"en;q=oops, fr;q=0.9", []string{"en", "fr"}},
+		{"", []string{}},
+	}
+	for _, c := range cases {
+		if got := ParseAcceptLanguage(c.header); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: expected %v, got %v", c.header, c.want, got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c := newFrenchCatalog()
+	c.Register("pt-BR", map[string]string{})
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"fr-CH, en;q=0.5", "fr-ch"},
+		{"fr-BE", "fr"},
+		{"de, fr;q=0.8", "fr"},
+		// a base language matches a regional variant
+		{"pt", "pt-br"},
+		{"de", "en"},
+		{"*, fr;q=0.1", "en"},
+		{"", "en"},
+	}
+	for _, tc := range cases {
+		if got := c.Match(tc.header); got != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.header, tc.want, got)
+		}
+	}
+}
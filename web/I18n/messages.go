@@ -0,0 +1,64 @@
+package I18n
+
+// Message keys used by the framework. Applications can translate them by registering
+// a language with Catalog.Register, e.g.
+//
+//	catalog.Register("fr", map[string]string{
+//		I18n.VALIDATE_REQUIRED: "le champ [{field}] est obligatoire",
+//	})
+const (
+	BIND_NIL_BODY           = "bind.nil_body"
+	BIND_NOT_POINTER        = "bind.not_pointer"
+	BIND_UNKNOWN_FIELD      = "bind.unknown_field"
+	BIND_TYPE_MISMATCH      = "bind.type_mismatch"
+	PARAM_MISSING           = "param.missing"
+	PARAM_INVALID           = "param.invalid"
+	UPLOAD_TOO_LARGE        = "upload.too_large"
+	UPLOAD_FILE_TOO_LARGE   = "upload.file_too_large"
+	UPLOAD_TOO_MANY_FILES   = "upload.too_many_files"
+	UPLOAD_UNSUPPORTED_TYPE = "upload.unsupported_type"
+	VALIDATE_MISSING        = "validate.missing"
+	VALIDATE_REQUIRED       = "validate.required"
+	VALIDATE_MIN            = "validate.min"
+	VALIDATE_MAX            = "validate.max"
+	VALIDATE_LEN            = "validate.len"
+	VALIDATE_ENUM           = "validate.enum"
+	VALIDATE_PATTERN        = "validate.pattern"
+	VALIDATE_EXCLUSIVE_MIN  = "validate.exclusive_min"
+	VALIDATE_EXCLUSIVE_MAX  = "validate.exclusive_max"
+	VALIDATE_MULTIPLE_OF    = "validate.multiple_of"
+	VALIDATE_UNIQUE         = "validate.unique"
+	VALIDATE_MIN_CONTAINS   = "validate.min_contains"
+	VALIDATE_MAX_CONTAINS   = "validate.max_contains"
+	VALIDATE_FORMAT         = "validate.format"
+	VALIDATE_SCHEMA         = "validate.schema"
+)
+
+// englishMessages are the built-in English templates every catalog starts with.
+var englishMessages = map[string]string{
+	BIND_NIL_BODY:           "body is nil, invalid request",
+	BIND_NOT_POINTER:        "passing parameter is not a pointer",
+	BIND_UNKNOWN_FIELD:      "field [{field}] is not allowed",
+	BIND_TYPE_MISMATCH:      "field [{field}] must be of type {expected}, got {actual}",
+	PARAM_MISSING:           "parameter [{field}] is required",
+	PARAM_INVALID:           "parameter [{field}] is not a valid {expected}: {value}",
+	UPLOAD_TOO_LARGE:        "request body exceeds the limit of {limit} bytes",
+	UPLOAD_FILE_TOO_LARGE:   "file [{file}] exceeds the limit of {limit} bytes",
+	UPLOAD_TOO_MANY_FILES:   "too many files, at most {limit} are allowed",
+	UPLOAD_UNSUPPORTED_TYPE: "file [{file}] has an unsupported type {type}",
+	VALIDATE_MISSING:        "field [{field}] does not exist",
+	VALIDATE_REQUIRED:       "field [{field}] is required",
+	VALIDATE_MIN:            "field [{field}] must be at least {param}",
+	VALIDATE_MAX:            "field [{field}] must be at most {param}",
+	VALIDATE_LEN:            "field [{field}] must have a length of {param}",
+	VALIDATE_ENUM:           "field [{field}] must be one of [{param}]",
+	VALIDATE_PATTERN:        "field [{field}] must match the pattern {param}",
+	VALIDATE_EXCLUSIVE_MIN:  "field [{field}] must be greater than {param}",
+	VALIDATE_EXCLUSIVE_MAX:  "field [{field}] must be less than {param}",
+	VALIDATE_MULTIPLE_OF:    "field [{field}] must be a multiple of {param}",
+	VALIDATE_UNIQUE:         "field [{field}] must not contain duplicate items",
+	VALIDATE_MIN_CONTAINS:   "field [{field}] must contain at least {param} matching items",
+	VALIDATE_MAX_CONTAINS:   "field [{field}] must contain at most {param} matching items",
+	VALIDATE_FORMAT:         "field [{field}] must be a valid {param}",
+	VALIDATE_SCHEMA:         "field [{field}] does not match the expected schema",
+}
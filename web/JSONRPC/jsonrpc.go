@@ -0,0 +1,201 @@
+// Package JSONRPC implements the JSON-RPC 2.0 protocol (https://www.jsonrpc.org/specification):
+// the requests, the responses and the errors of the specification, and a Server calling the
+// methods registered by name or reflected from a service struct.
+package JSONRPC
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// VERSION is the version of the protocol, the value of the member "jsonrpc".
+const VERSION = "2.0"
+
+// The error codes defined by the specification.
+const (
+	// PARSE_ERROR is the code of the requests that are not valid JSON.
+	PARSE_ERROR = -32700
+	// INVALID_REQUEST is the code of the requests that are not valid request objects.
+	INVALID_REQUEST = -32600
+	// METHOD_NOT_FOUND is the code of the requests of a method that does not exist.
+	METHOD_NOT_FOUND = -32601
+	// INVALID_PARAMS is the code of the requests whose params cannot be bound or validated.
+	INVALID_PARAMS = -32602
+	// INTERNAL_ERROR is the code of the methods failing unexpectedly.
+	INTERNAL_ERROR = -32603
+	// SERVER_ERROR is the code of the other errors of the methods, the first of the range
+	// -32000 to -32099 reserved for the errors of the server.
+	SERVER_ERROR = -32000
+)
+
+// messages are the messages of the error codes defined by the specification.
+var messages = map[int]string{
+	PARSE_ERROR:      "Parse error",
+	INVALID_REQUEST:  "Invalid Request",
+	METHOD_NOT_FOUND: "Method not found",
+	INVALID_PARAMS:   "Invalid params",
+	INTERNAL_ERROR:   "Internal error",
+	SERVER_ERROR:     "Server error",
+}
+
+// Error is the error object of a response. The methods return an *Error to answer with their
+// own code, e.g. a code of the range -32000 to -32099, or any code of the application.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// Data is additional information about the error, sent to the client.
+	Data any `json:"data,omitempty"`
+	// Err is the underlying error, e.g. the validation errors of the params; it is not sent
+	// to the client.
+	Err error `json:"-"`
+}
+
+// NewError creates an Error. An empty message means the message of the code in the
+// specification, e.g. "Method not found" for METHOD_NOT_FOUND.
+func NewError(code int, message string, data any) *Error {
+	if message == "" {
+		message = messages[code]
+	}
+	return &Error{Code: code, Message: message, Data: data}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Request is a request object. A request without ID is a notification, which is not answered.
+type Request struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	// Params are the by-name (an object) or the by-position (an array) params of the method.
+	Params json.RawMessage `json:"params,omitempty"`
+	// ID is the string, the number or the null identifying the request, and nil for the
+	// notifications.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification reports whether r is a notification, a request without ID.
+func (r *Request) IsNotification() bool {
+	return r.ID == nil
+}
+
+// Response is the response object of a request: its Result, or its Error.
+type Response struct {
+	Result any
+	Error  *Error
+	// ID is the ID of the request, null if it could not be read.
+	ID json.RawMessage
+}
+
+// NewResponse creates the response of the request of id, with result if err is nil.
+func NewResponse(id json.RawMessage, result any, err *Error) *Response {
+	return &Response{Result: result, Error: err, ID: id}
+}
+
+// MarshalJSON renders the response with either its result, which is present even when it is
+// null, or its error.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *Error          `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{VERSION, r.Error, id})
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  any             `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{VERSION, r.Result, id})
+}
+
+// Decode splits the body of an HTTP request into its messages: the single request, or the
+// requests of a batch, which is reported. A body that is not valid JSON is a PARSE_ERROR,
+// and an empty batch an INVALID_REQUEST. The messages are parsed with ParseRequest.
+func Decode(data []byte) (requests []json.RawMessage, batch bool, err *Error) {
+	data = bytes.TrimSpace(data)
+	if !json.Valid(data) {
+		return nil, false, &Error{Code: PARSE_ERROR, Message: messages[PARSE_ERROR], Err: errors.New("invalid JSON")}
+	}
+	if len(data) == 0 || data[0] != '[' {
+		return []json.RawMessage{data}, false, nil
+	}
+	if err := json.Unmarshal(data, &requests); err != nil {
+		return nil, true, &Error{Code: PARSE_ERROR, Message: messages[PARSE_ERROR], Err: err}
+	}
+	if len(requests) == 0 {
+		return nil, true, NewError(INVALID_REQUEST, "", nil)
+	}
+	return requests, true, nil
+}
+
+// ParseRequest parses a message into a Request. A message that is not a valid request
+// object is an INVALID_REQUEST: it must be an object, with the member "jsonrpc" set to
+// "2.0", a method name, params that are an array or an object, and an ID that is a string,
+// a number or null. The invalid requests are answered even without ID, with the ID of the
+// returned request if any, null otherwise.
+func ParseRequest(message json.RawMessage) (*Request, *Error) {
+	invalid := func(reason string) *Error {
+		return &Error{Code: INVALID_REQUEST, Message: messages[INVALID_REQUEST], Err: errors.New(reason)}
+	}
+	if kind(message) != '{' {
+		return nil, invalid("the request is not an object")
+	}
+	r := &Request{}
+	if err := json.Unmarshal(message, r); err != nil {
+		return nil, invalid(err.Error())
+	}
+	if r.ID != nil {
+		switch kind(r.ID) {
+		case '"', '0', 'n':
+		default:
+			// the ID cannot be trusted, so the response has a null ID
+			id := r.ID
+			r.ID = json.RawMessage("null")
+			return r, invalid("invalid id " + string(id))
+		}
+	}
+	if r.JSONRPC != VERSION {
+		return r, invalid(`the member "jsonrpc" must be "2.0"`)
+	}
+	if r.Method == "" {
+		return r, invalid("the method is missing")
+	}
+	if r.Params != nil {
+		switch kind(r.Params) {
+		case '{', '[':
+		default:
+			return r, invalid("the params must be an array or an object")
+		}
+	}
+	return r, nil
+}
+
+// kind returns the kind of a JSON value: '{', '[', '"', 'n' (null), 't' (a boolean)
+// or '0' (a number).
+func kind(value json.RawMessage) byte {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return 0
+	}
+	switch c := value[0]; c {
+	case '{', '[', '"', 'n':
+		return c
+	case 't', 'f':
+		return 't'
+	}
+	return '0'
+}
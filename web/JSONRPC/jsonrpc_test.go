@@ -0,0 +1,129 @@
+package JSONRPC
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"strings"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		message      string
+		valid        bool
+		notification bool
+	}{
+		{`{"jsonrpc": "2.0", "method": "add", "params": [1, 2], "id": 1}`, true, false},
+		{`{"jsonrpc": "2.0", "method": "add", "id": null}`, true, false},
+		{`{"jsonrpc": "2.0", "method": "update", "params": {"a": 1}}`, true, true},
+		{`{"jsonrpc": "1.0", "method": "add", "id": 1}`, false, false},
+		{`{"jsonrpc": "2.0", "method": 1, "id": 1}`, false, false},
+		{`{"jsonrpc": "2.0", "method": "add", "params": "a", "id": 1}`, false, false},
+		{`{"jsonrpc": "2.0", "method": "add", "id": {}}`, false, false},
+		{`1`, false, false},
+	}
+	for _, test := range tests {
+		r, err := ParseRequest(json.RawMessage(test.message))
+		if (err == nil) != test.valid {
+			t.Errorf("unexpected error for %s: %v", test.message, err)
+			continue
+		}
+		if err != nil && err.Code != INVALID_REQUEST {
+			t.Errorf("unexpected code %d for %s", err.Code, test.message)
+		}
+		if test.valid && r.IsNotification() != test.notification {
+			t.Errorf("unexpected notification %v for %s", r.IsNotification(), test.message)
+		}
+	}
+	if _, _, err := Decode([]byte(`[{"jsonrpc": "2.0", "method"`)); err == nil || err.Code != PARSE_ERROR {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, _, err := Decode([]byte(` [] `)); err == nil || err.Code != INVALID_REQUEST {
+		t.Errorf("unexpected error %v", err)
+	}
+	if messages, batch, err := Decode([]byte(`[1, {}]`)); err != nil || !batch || len(messages) != 2 {
+		t.Errorf("unexpected batch %v %v %v", messages, batch, err)
+	}
+}
+
+func TestResponse(t *testing.T) {
+	b, _ := json.Marshal(NewResponse(json.RawMessage(`"a"`), nil, nil))
+	if string(b) != `{"jsonrpc":"2.0","result":null,"id":"a"}` {
+		t.Errorf("unexpected response %s", b)
+	}
+	b, _ = json.Marshal(NewResponse(nil, nil, NewError(METHOD_NOT_FOUND, "", nil)))
+	if string(b) != `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":null}` {
+		t.Errorf("unexpected response %s", b)
+	}
+}
+
+type operands struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type calculator struct{}
+
+func (calculator) Add(ctx *context.Context, params operands) (int, error) {
+	return params.A + params.B, nil
+}
+
+func (calculator) Sum(ctx *context.Context, numbers []int) (int, error) {
+	total := 0
+	for _, n := range numbers {
+		total += n
+	}
+	return total, nil
+}
+
+func (calculator) Reset(ctx *context.Context) error {
+	return nil
+}
+
+func (calculator) String() string {
+	return "calculator"
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	s.RegisterService("calc", calculator{})
+	s.Register("ping", func(ctx *context.Context) (string, error) { return "pong", nil })
+	if methods := s.Methods(); len(methods) != 4 || methods[0] != "calc.add" || methods[3] != "ping" {
+		t.Fatalf("unexpected methods %v", methods)
+	}
+	calls := map[string]any{
+		`{"jsonrpc": "2.0", "method": "calc.add", "params": {"a": 1, "b": 2}, "id": 1}`: 3,
+		`{"jsonrpc": "2.0", "method": "calc.sum", "params": [1, 2, 3], "id": 1}`:        6,
+		`{"jsonrpc": "2.0", "method": "calc.reset", "id": 1}`:                           nil,
+		`{"jsonrpc": "2.0", "method": "ping", "id": 1}`:                                 "pong",
+	}
+	for message, expected := range calls {
+		r, _ := ParseRequest(json.RawMessage(message))
+		result, err := s.Call(nil, r)
+		if err != nil || result != expected {
+			t.Errorf("unexpected result %v, %v for %s", result, err, message)
+		}
+	}
+	r, _ := ParseRequest(json.RawMessage(`{"jsonrpc": "2.0", "method": "calc.div", "id": 1}`))
+	if _, err := s.Call(nil, r); err == nil || err.(*Error).Code != METHOD_NOT_FOUND {
+		t.Errorf("unexpected error %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a repeated method")
+		}
+	}()
+	s.Register("ping", func(ctx *context.Context) error { return nil })
+}
+
+func TestRegisterInvalidParams(t *testing.T) {
+	type coupon struct {
+		Code string `json:"code" gjango:"pattern=^[A-Z+$"`
+	}
+	defer func() {
+		if value := recover(); value == nil || !strings.HasPrefix(value.(string), "[ERROR] Invalid params of the method [redeem] of JSON-RPC: the field [Code]") {
+			t.Errorf("unexpected panic %v", value)
+		}
+	}()
+	NewServer().Register("redeem", func(ctx *context.Context, params coupon) error { return nil })
+}
@@ -0,0 +1,204 @@
+package JSONRPC
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Binding"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Server calls the methods of the requests. The methods are functions of one of the forms
+//
+//	func(ctx *context.Context, params P) (R, error)
+//	func(ctx *context.Context, params P) error
+//	func(ctx *context.Context) (R, error)
+//	func(ctx *context.Context) error
+//
+// where the params of the request are bound into P the way context.ParseJSON binds a body,
+// checking the gjango rules of P, and R is the result of the response. Registering a method
+// whose P has invalid gjango tags (see Binding.CheckTags) panics. The methods are
+// registered before the server is used, with Register or RegisterService.
+type Server struct {
+	// Body configures the caching of the request bodies when the server answers HTTP
+	// requests (see the JSONRPC method of the router groups of web). A MaxSize of 0 limits
+	// the bodies to Constant.DEFAULT_VALIDATED_BODY_MAX_SIZE, and a negative MaxSize lifts
+	// the limit.
+	Body    context.BodyCacheConfig
+	methods map[string]*method
+}
+
+// method is a registered method.
+type method struct {
+	fn reflect.Value
+	// params is the type of the params, nil for the methods without params
+	params reflect.Type
+	// result reports whether the method returns a result besides its error
+	result bool
+}
+
+// NewServer creates a Server without methods.
+func NewServer() *Server {
+	return &Server{methods: make(map[string]*method)}
+}
+
+// Register registers fn, a function of one of the forms of Server, as the method name.
+// It panics if fn is not such a function, or if name is already registered.
+//
+// Example:
+//
+//	server.Register("sum", func(ctx *context.Context, numbers []int) (int, error) {
+//		total := 0
+//		for _, n := range numbers {
+//			total += n
+//		}
+//		return total, nil
+//	})
+func (s *Server) Register(name string, fn any) {
+	m, ok := newMethod(reflect.ValueOf(fn))
+	if !ok {
+		panic("[ERROR] The method [" + name + "] of JSON-RPC is not a func(*context.Context[, P]) ([R, ]error)")
+	}
+	s.register(name, m)
+}
+
+// RegisterService registers the exported methods of service that have one of the forms of
+// Server, named after the method with its first letter lowered, e.g. "getUser" for GetUser,
+// prefixed with prefix and a dot if prefix is not empty, e.g. "users.getUser". The other
+// methods are ignored. It panics if service has no such method.
+//
+// Example:
+//
+//	type Users struct{ store *Store }
+//
+//	func (u *Users) Get(ctx *context.Context, params GetUser) (*User, error) { ... }
+//
+//	server.RegisterService("users", &Users{store: store}) // "users.get"
+func (s *Server) RegisterService(prefix string, service any) {
+	value := reflect.ValueOf(service)
+	registered := 0
+	for i := 0; i < value.NumMethod(); i++ {
+		m, ok := newMethod(value.Method(i))
+		if !ok {
+			continue
+		}
+		name := lowerFirst(value.Type().Method(i).Name)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		s.register(name, m)
+		registered++
+	}
+	if registered == 0 {
+		panic("[ERROR] The service " + value.Type().String() + " has no method of JSON-RPC")
+	}
+}
+
+func (s *Server) register(name string, m *method) {
+	if name == "" || strings.HasPrefix(name, "rpc.") {
+		panic("[ERROR] Invalid name [" + name + "] of a method of JSON-RPC, the names starting with rpc. are reserved")
+	}
+	if _, ok := s.methods[name]; ok {
+		panic("[ERROR] Repeated registration of the method [" + name + "] of JSON-RPC")
+	}
+	if m.params != nil {
+		if err := Binding.CheckTags(m.params); err != nil {
+			panic("[ERROR] Invalid params of the method [" + name + "] of JSON-RPC: " + err.Error())
+		}
+	}
+	s.methods[name] = m
+}
+
+// Methods returns the names of the registered methods, sorted.
+func (s *Server) Methods() []string {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Call calls the method of request with its params. The method is not found with a
+// METHOD_NOT_FOUND *Error, and params that cannot be bound into the params of the method
+// fail with an INVALID_PARAMS *Error, whose Err holds the binding and validation errors.
+// The error returned by the method is returned as it is.
+func (s *Server) Call(ctx *context.Context, request *Request) (any, error) {
+	m, ok := s.methods[request.Method]
+	if !ok {
+		return nil, NewError(METHOD_NOT_FOUND, "", nil)
+	}
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if m.params != nil {
+		params, err := bindParams(request.Params, m.params)
+		if err != nil {
+			return nil, &Error{Code: INVALID_PARAMS, Message: messages[INVALID_PARAMS], Err: ctx.LocalizeError(err)}
+		}
+		args = append(args, params)
+	}
+	out := m.fn.Call(args)
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		return nil, err
+	}
+	if m.result {
+		return out[0].Interface(), nil
+	}
+	return nil, nil
+}
+
+// bindParams binds the params of a request into a new value of type t. By-name params are
+// bound into structs and maps, by-position params into slices and arrays, and missing params
+// are bound as an empty object, so that the required fields are reported.
+func bindParams(params json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	target := t
+	if t.Kind() == reflect.Pointer {
+		target = t.Elem()
+	}
+	value := reflect.New(target)
+	if params == nil {
+		if target.Kind() != reflect.Struct {
+			return value.Elem(), nil
+		}
+		params = json.RawMessage("{}")
+	}
+	if err := Binding.DecodeJSON(json.NewDecoder(bytes.NewReader(params)), value.Interface(), false); err != nil {
+		return reflect.Value{}, err
+	}
+	if t.Kind() == reflect.Pointer {
+		return value, nil
+	}
+	return value.Elem(), nil
+}
+
+// newMethod returns the method of fn if it has one of the forms of Server.
+func newMethod(fn reflect.Value) (*method, bool) {
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return nil, false
+	}
+	t := fn.Type()
+	if t.IsVariadic() || t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != contextType {
+		return nil, false
+	}
+	if t.NumOut() < 1 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType {
+		return nil, false
+	}
+	m := &method{fn: fn, result: t.NumOut() == 2}
+	if t.NumIn() == 2 {
+		m.params = t.In(1)
+	}
+	return m, true
+}
+
+func lowerFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
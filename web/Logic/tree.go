@@ -29,6 +29,9 @@ func (t *TreeNode) Put(path string) {
 			if node.Name == name {
 				t = node // Move to the matching node.
 				isMatch = true
+				if index == len(strs)-1 { // The path may end at a node created by a longer path.
+					node.IsEnd = true
+				}
 				break
 			}
 		}
@@ -52,69 +55,93 @@ func (t *TreeNode) Put(path string) {
 
 // Get retrieves a node from the tree that matches the given path.
 // It supports matching exact paths, path parameters (prefixed with ":"), single wildcards (*),
-// and double wildcards (**). The search priority is in the mentioned order.
+// and double wildcards (**). The search priority is in the mentioned order. When a branch does
+// not lead to the end of a path, the next candidate is tried, so that sibling path parameters
+// with different names (e.g. /articles/:id and /articles/:article_id/comments) both match.
 // Parameters:
 // - path: The path to search for in the tree, starting with a slash (/).
 // Returns:
 // - A pointer to the TreeNode that matches the path, or nil if no match is found.
+// A node that is not the end of a path is only returned if no endpoint matches.
 func (t *TreeNode) Get(path string) *TreeNode {
 	strs := strings.Split(path, "/") // Split the path into segments.
-	for index, name := range strs {
-		if index == 0 { // Skip the first segment if it's empty (leading slash).
-			continue
-		}
-		children := t.Children
-		var matchNode *TreeNode
-		isMatch := false
+	if len(strs) < 2 {
+		return nil
+	}
+	var fallback *TreeNode
+	if node := t.get(strs[1:], &fallback); node != nil {
+		return node
+	}
+	return fallback
+}
 
-		// 1. First, match the exact path.
-		for _, node := range children {
-			if node.Name == name {
-				matchNode = node // Found a match with the exact name.
-				isMatch = true
-				break
+// get matches the segments of a path below t, backtracking on the candidates of each segment.
+// The first node matching every segment without being an endpoint is kept in fallback.
+func (t *TreeNode) get(names []string, fallback **TreeNode) *TreeNode {
+	for _, node := range t.candidates(names[0]) {
+		if node.Name == "**" || len(names) == 1 { // A double wildcard matches the rest of the path.
+			if node.IsEnd {
+				return node
 			}
-		}
-
-		// 2. Second, match the path parameter (prefixed with ":").
-		if !isMatch {
-			for _, node := range children {
-				if strings.HasPrefix(node.Name, ":") {
-					matchNode = node // Found a match with a path parameter.
-					isMatch = true
-					break
-				}
+			if *fallback == nil {
+				*fallback = node
 			}
+			continue
 		}
-
-		// 3. Third, match the single wildcard (*).
-		if !isMatch {
-			for _, node := range children {
-				if node.Name == "*" {
-					matchNode = node // Found a match with a single wildcard.
-					isMatch = true
-					break
-				}
-			}
+		if match := node.get(names[1:], fallback); match != nil {
+			return match
 		}
+	}
+	return nil
+}
 
-		// 4. Lastly, match the double wildcard (**).
-		if !isMatch {
-			for _, node := range children {
-				if node.Name == "**" {
-					return node // Found a match with a double wildcard, return immediately.
-				}
+// candidates returns the children of t matching the segment name, in order of priority:
+// the exact name, the path parameters, the single wildcard (*) and the double wildcard (**).
+func (t *TreeNode) candidates(name string) []*TreeNode {
+	candidates := make([]*TreeNode, 0, 2)
+	// 1. First, match the exact path.
+	for _, node := range t.Children {
+		if node.Name == name {
+			candidates = append(candidates, node)
+		}
+	}
+	// 2. Second, match the path parameters (prefixed with ":").
+	for _, node := range t.Children {
+		if strings.HasPrefix(node.Name, ":") {
+			candidates = append(candidates, node)
+		}
+	}
+	// 3. Third, match the single wildcard (*), and lastly the double wildcard (**).
+	for _, wildcard := range []string{"*", "**"} {
+		for _, node := range t.Children {
+			if node.Name == wildcard {
+				candidates = append(candidates, node)
 			}
 		}
+	}
+	return candidates
+}
 
-		if isMatch {
-			t = matchNode             // Move to the matching node.
-			if index == len(strs)-1 { // If it's the last segment, return the matching node.
-				return matchNode
+// Params extracts the path parameters of the node from a path it matched with Get.
+// Segments of the node path prefixed with ":" give parameters named after the rest of the segment.
+// Parameters:
+// - path: The path matched by the node, starting with a slash (/).
+// Returns:
+// - A map from parameter names to their values, or nil if the node path has no parameters.
+func (t *TreeNode) Params(path string) map[string]string {
+	var params map[string]string
+	patterns := strings.Split(t.Path, "/")
+	strs := strings.Split(path, "/")
+	for index, pattern := range patterns {
+		if index >= len(strs) {
+			break
+		}
+		if strings.HasPrefix(pattern, ":") {
+			if params == nil {
+				params = make(map[string]string)
 			}
-		} else {
-			return nil // No match found, return nil.
+			params[pattern[1:]] = strs[index]
 		}
 	}
-	return nil
+	return params
 }
@@ -21,3 +21,51 @@ func TestTreeNode(t *testing.T) {
 	node = root.Get("/order/get/aaa")
 	fmt.Println(node)
 }
+
+func TestTreeNodeParams(t *testing.T) {
+	root := &TreeNode{Name: "/", Children: make([]*TreeNode, 0)}
+	root.Put("/user/:id/files/:name")
+	root.Put("/order/list")
+
+	node := root.Get("/user/42/files/a.txt")
+	params := node.Params("/user/42/files/a.txt")
+	if params["id"] != "42" || params["name"] != "a.txt" || len(params) != 2 {
+		t.Fatalf("unexpected params %v", params)
+	}
+	if params := root.Get("/order/list").Params("/order/list"); params != nil {
+		t.Fatalf("expected no params, got %v", params)
+	}
+}
+
+func TestTreeNodeBacktracking(t *testing.T) {
+	root := &TreeNode{Name: "/", Children: make([]*TreeNode, 0)}
+	root.Put("/articles/:id")
+	root.Put("/articles/:article_id/comments/:id")
+	root.Put("/articles/new")
+	root.Put("/articles")
+	root.Put("/files/**")
+
+	cases := map[string]string{
+		"/articles":                "/articles",
+		"/articles/new":            "/articles/new",
+		"/articles/1":              "/articles/:id",
+		"/articles/1/comments/2":   "/articles/:article_id/comments/:id",
+		"/articles/new/comments/2": "/articles/:article_id/comments/:id",
+		"/files/a/b":               "/files/**",
+	}
+	for path, expected := range cases {
+		node := root.Get(path)
+		if node == nil || !node.IsEnd || node.Path != expected {
+			t.Errorf("%s: expected %s, got %+v", path, expected, node)
+		}
+	}
+	if params := root.Get("/articles/1/comments/2").Params("/articles/1/comments/2"); params["article_id"] != "1" || params["id"] != "2" {
+		t.Errorf("unexpected params %v", params)
+	}
+	if node := root.Get("/articles/1/comments"); node == nil || node.IsEnd {
+		t.Errorf("expected a node that is not an endpoint, got %+v", node)
+	}
+	if node := root.Get("/users"); node != nil {
+		t.Errorf("expected no node, got %+v", node)
+	}
+}
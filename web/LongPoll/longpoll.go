@@ -0,0 +1,135 @@
+// Package LongPoll lets requests wait for a keyed notification, for clients that cannot use
+// server-sent events or WebSockets: a request waits on a key until another request notifies
+// it, or a timeout expires.
+package LongPoll
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// shards is the number of independently locked parts of a Notifier, so that requests
+// waiting on different keys do not contend on a single lock.
+const shards = 32
+
+// Default is the notifier used by the contexts the engine did not set any on.
+var Default = NewNotifier()
+
+// Notifier wakes the requests waiting on a key. Waiting does not start any goroutine: a
+// waiter is gone as soon as Wait returns, whether it was notified, timed out or canceled.
+type Notifier struct {
+	shards [shards]shard
+}
+
+type shard struct {
+	mu      sync.Mutex
+	waiters map[string]map[*waiter]struct{}
+}
+
+// waiter receives at most one notification, so its buffered channel never blocks Notify.
+type waiter struct {
+	ch chan any
+}
+
+// NewNotifier creates a notifier.
+func NewNotifier() *Notifier {
+	n := &Notifier{}
+	for i := range n.shards {
+		n.shards[i].waiters = make(map[string]map[*waiter]struct{})
+	}
+	return n
+}
+
+// Wait waits for a notification of key, until timeout expires or ctx is done.
+//
+// Parameters:
+//   - ctx: Stops the wait when done, typically the request context.
+//   - key: The key to wait on.
+//   - timeout: The longest wait; zero or less waits until ctx is done.
+//
+// Returns:
+//   - any: The data of the notification.
+//   - bool: true if a notification was received, false if the timeout expired.
+//   - error: The error of ctx if it is done first.
+func (n *Notifier) Wait(ctx context.Context, key string, timeout time.Duration) (any, bool, error) {
+	s := n.shard(key)
+	w := &waiter{ch: make(chan any, 1)}
+	s.mu.Lock()
+	waiters, ok := s.waiters[key]
+	if !ok {
+		waiters = make(map[*waiter]struct{})
+		s.waiters[key] = waiters
+	}
+	waiters[w] = struct{}{}
+	s.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case data := <-w.ch:
+		return data, true, nil
+	case <-expired:
+		if data, notified := s.remove(key, w); notified {
+			return data, true, nil
+		}
+		return nil, false, nil
+	case <-ctx.Done():
+		if data, notified := s.remove(key, w); notified {
+			return data, true, nil
+		}
+		return nil, false, ctx.Err()
+	}
+}
+
+// Notify wakes every request waiting on key with data.
+//
+// Returns:
+//   - int: The number of requests woken.
+func (n *Notifier) Notify(key string, data any) int {
+	s := n.shard(key)
+	s.mu.Lock()
+	waiters := s.waiters[key]
+	delete(s.waiters, key)
+	s.mu.Unlock()
+	for w := range waiters {
+		w.ch <- data
+	}
+	return len(waiters)
+}
+
+// Waiting returns the number of requests waiting on key.
+func (n *Notifier) Waiting(key string) int {
+	s := n.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.waiters[key])
+}
+
+func (n *Notifier) shard(key string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &n.shards[h.Sum32()%shards]
+}
+
+// remove unregisters a waiter giving up. If Notify already took it, the notification is
+// (or is about to be) in its channel and is returned instead, so that it is not lost.
+func (s *shard) remove(key string, w *waiter) (any, bool) {
+	s.mu.Lock()
+	waiters := s.waiters[key]
+	if _, ok := waiters[w]; ok {
+		delete(waiters, w)
+		if len(waiters) == 0 {
+			delete(s.waiters, key)
+		}
+		s.mu.Unlock()
+		return nil, false
+	}
+	s.mu.Unlock()
+	return <-w.ch, true
+}
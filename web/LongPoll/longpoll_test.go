@@ -0,0 +1,99 @@
+package LongPoll
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	n := NewNotifier()
+
+	// timeout
+	if data, ok, err := n.Wait(context.Background(), "k", 10*time.Millisecond); ok || data != nil || err != nil {
+		t.Fatalf("expected a timeout, got %v %v %v", data, ok, err)
+	}
+
+	// notification
+	result := make(chan any, 1)
+	go func() {
+		data, _, _ := n.Wait(context.Background(), "k", 5*time.Second)
+		result <- data
+	}()
+	for n.Waiting("k") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if woken := n.Notify("k", "hello"); woken != 1 {
+		t.Fatalf("expected 1 waiter woken, got %d", woken)
+	}
+	if data := <-result; data != "hello" {
+		t.Fatalf("unexpected data %v", data)
+	}
+
+	// cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for n.Waiting("k") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if _, ok, err := n.Wait(ctx, "k", 0); ok || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled wait, got %v %v", ok, err)
+	}
+	if n.Waiting("k") != 0 || n.Notify("k", nil) != 0 {
+		t.Fatal("canceled waiter still registered")
+	}
+}
+
+func TestConcurrentWaiters(t *testing.T) {
+	n := NewNotifier()
+	goroutines := runtime.NumGoroutine()
+	const waiters = 1000
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	woken, timedOut := 0, 0
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// half of the waiters give up right away, racing with the notification
+			timeout := 5 * time.Second
+			if i%2 == 0 {
+				timeout = time.Microsecond
+			}
+			_, ok, _ := n.Wait(context.Background(), "key", timeout)
+			mu.Lock()
+			if ok {
+				woken++
+			} else {
+				timedOut++
+			}
+			mu.Unlock()
+		}(i)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for n.Waiting("key") < waiters/2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	notified := n.Notify("key", 1)
+	wg.Wait()
+
+	// every waiter taken by Notify received the notification, even if it was timing out
+	if woken != notified || woken+timedOut != waiters || woken < waiters/2 {
+		t.Fatalf("notified %d, woken %d, timed out %d", notified, woken, timedOut)
+	}
+	if n.Waiting("key") != 0 {
+		t.Fatalf("%d waiters left", n.Waiting("key"))
+	}
+	for time.Now().Before(deadline) && runtime.NumGoroutine() > goroutines {
+		time.Sleep(time.Millisecond)
+	}
+	if runtime.NumGoroutine() > goroutines {
+		t.Fatalf("goroutines leaked: %d before, %d after", goroutines, runtime.NumGoroutine())
+	}
+}
@@ -0,0 +1,191 @@
+package OpenAPI
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/Schema"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Load reads an OpenAPI 3.0 or 3.1 document in JSON.
+func Load(data []byte) (*Document, error) {
+	doc := &Document{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, expected 3.x", doc.OpenAPI)
+	}
+	return doc, nil
+}
+
+// LoadFile reads the OpenAPI 3.0 or 3.1 document in JSON of the file name.
+func LoadFile(name string) (*Document, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// component returns the name of the component ref references in the section of the
+// components, e.g. "User" for "#/components/schemas/User" in "schemas".
+func component(ref string, section string) (string, error) {
+	name, ok := strings.CutPrefix(ref, "#/components/"+section+"/")
+	if !ok {
+		return "", fmt.Errorf("unsupported reference %s, expected #/components/%s/", ref, section)
+	}
+	// names are escaped as in JSON pointers
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~"), nil
+}
+
+// Resolve returns the schema of the components ref references, e.g. "#/components/schemas/User".
+// It is the Schema.Resolver of the schemas of the document.
+func (d *Document) Resolve(ref string) (*Schema.Schema, error) {
+	name, err := component(ref, "schemas")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components != nil {
+		if s, ok := d.Components.Schemas[name]; ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unresolved reference %s", ref)
+}
+
+// ResolveParameter returns the parameter of the components p references, or p itself.
+func (d *Document) ResolveParameter(p *Parameter) (*Parameter, error) {
+	if p == nil || p.Ref == "" {
+		return p, nil
+	}
+	name, err := component(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components != nil {
+		if parameter, ok := d.Components.Parameters[name]; ok {
+			return d.ResolveParameter(parameter)
+		}
+	}
+	return nil, fmt.Errorf("unresolved reference %s", p.Ref)
+}
+
+// ResolveRequestBody returns the request body of the components body references, or body itself.
+func (d *Document) ResolveRequestBody(body *RequestBody) (*RequestBody, error) {
+	if body == nil || body.Ref == "" {
+		return body, nil
+	}
+	name, err := component(body.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components != nil {
+		if requestBody, ok := d.Components.RequestBodies[name]; ok {
+			return d.ResolveRequestBody(requestBody)
+		}
+	}
+	return nil, fmt.Errorf("unresolved reference %s", body.Ref)
+}
+
+// ResolveResponse returns the response of the components r references, or r itself.
+func (d *Document) ResolveResponse(r *Response) (*Response, error) {
+	if r == nil || r.Ref == "" {
+		return r, nil
+	}
+	name, err := component(r.Ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components != nil {
+		if response, ok := d.Components.Responses[name]; ok {
+			return d.ResolveResponse(response)
+		}
+	}
+	return nil, fmt.Errorf("unresolved reference %s", r.Ref)
+}
+
+// BasePath returns the path of the URL of the first server of the document, e.g. "/v1" for
+// "https://api.example.com/v1", which the paths of the document are relative to.
+func (d *Document) BasePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// Matcher finds the path of a document matching the path of a request.
+type Matcher struct {
+	paths []matcherPath
+}
+
+type matcherPath struct {
+	template string
+	segments []string
+	item     *PathItem
+}
+
+// NewMatcher compiles the paths of doc.
+func NewMatcher(doc *Document) *Matcher {
+	m := &Matcher{paths: make([]matcherPath, 0, len(doc.Paths))}
+	for template, item := range doc.Paths {
+		m.paths = append(m.paths, matcherPath{template: template, segments: strings.Split(template, "/"), item: item})
+	}
+	// the concrete paths win over the templated ones, e.g. /users/me over /users/{id}
+	sort.Slice(m.paths, func(i, j int) bool {
+		a, b := m.paths[i].segments, m.paths[j].segments
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if isParam(a[k]) != isParam(b[k]) {
+				return !isParam(a[k])
+			}
+		}
+		return m.paths[i].template < m.paths[j].template
+	})
+	return m
+}
+
+// Match returns the template of the path matching path, e.g. "/users/{id}" for "/users/42",
+// with its path item and the values of its parameters. The item is nil if no path matches.
+//
+// path is the escaped path of a URL (see url.URL.EscapedPath), so that an escaped slash
+// stays within its segment: its segments are unescaped once, after splitting it.
+func (m *Matcher) Match(path string) (string, *PathItem, map[string]string) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if value, err := url.PathUnescape(segment); err == nil {
+			segments[i] = value
+		}
+	}
+	for _, p := range m.paths {
+		if len(p.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		matched := true
+		for i, segment := range p.segments {
+			if isParam(segment) {
+				params[segment[1:len(segment)-1]] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return p.template, p.item, params
+		}
+	}
+	return "", nil, nil
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
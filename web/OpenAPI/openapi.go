@@ -0,0 +1,195 @@
+// Package OpenAPI models OpenAPI 3.1 documents, which describe HTTP APIs so that clients
+// can be generated from them.
+package OpenAPI
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Schema"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// VERSION is the version of the OpenAPI specification of the documents.
+const VERSION = "3.1.0"
+
+// SCHEMAS_PREFIX is the prefix of the references to the schemas of the components.
+const SCHEMAS_PREFIX = "#/components/schemas/"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+	Tags       []Tag                `json:"tags,omitempty"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL of the API, e.g. "https://api.example.com/v1".
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations, e.g. by resource.
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the objects referenced by the operations, e.g. "#/components/schemas/User".
+type Components struct {
+	Schemas       map[string]*Schema.Schema `json:"schemas,omitempty"`
+	Parameters    map[string]*Parameter     `json:"parameters,omitempty"`
+	RequestBodies map[string]*RequestBody   `json:"requestBodies,omitempty"`
+	Responses     map[string]*Response      `json:"responses,omitempty"`
+}
+
+// PathItem holds the operations of a path, by method.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	// Parameters are shared by all the operations of the path.
+	Parameters []*Parameter `json:"parameters,omitempty"`
+}
+
+// operation returns the field of the operation of method, or nil for an unknown method.
+func (p *PathItem) operation(method string) **Operation {
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		return &p.Get
+	case http.MethodPut:
+		return &p.Put
+	case http.MethodPost:
+		return &p.Post
+	case http.MethodDelete:
+		return &p.Delete
+	case http.MethodOptions:
+		return &p.Options
+	case http.MethodHead:
+		return &p.Head
+	case http.MethodPatch:
+		return &p.Patch
+	}
+	return nil
+}
+
+// Operation returns the operation of method, or nil.
+func (p *PathItem) Operation(method string) *Operation {
+	if op := p.operation(method); op != nil {
+		return *op
+	}
+	return nil
+}
+
+// SetOperation sets the operation of method. It reports false for the methods OpenAPI
+// does not describe, such as CONNECT.
+func (p *PathItem) SetOperation(method string, operation *Operation) bool {
+	op := p.operation(method)
+	if op == nil {
+		return false
+	}
+	*op = operation
+	return true
+}
+
+// Operation describes a single method of a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path parameter, a query parameter, a header or a cookie of an operation.
+type Parameter struct {
+	// Ref references a parameter of the components, e.g. "#/components/parameters/page".
+	Ref         string         `json:"$ref,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	In          string         `json:"in,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *Schema.Schema `json:"schema,omitempty"`
+	// Style and Explode tell how arrays are serialized: as repeated query parameters by
+	// default, and as comma-separated values in the path and the headers, or with explode false.
+	Style   string `json:"style,omitempty"`
+	Explode *bool  `json:"explode,omitempty"`
+}
+
+// RequestBody describes the body of the requests of an operation, by media type.
+type RequestBody struct {
+	// Ref references a request body of the components, e.g. "#/components/requestBodies/user".
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Response describes a response of an operation, by media type.
+type Response struct {
+	// Ref references a response of the components, e.g. "#/components/responses/NotFound".
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body of some media type.
+type MediaType struct {
+	Schema *Schema.Schema `json:"schema,omitempty"`
+}
+
+// PathTemplate converts a route of the router to a path template of OpenAPI, and returns it
+// with the names of its parameters: the path parameters become {name}, and the wildcards
+// * and ** become {wildcard} and {path}. The wildcards * of a route with several of them are
+// numbered, {wildcard1}, {wildcard2}..., since the parameters of a path must have distinct names.
+//
+// Example:
+//
+//	PathTemplate("/articles/:article_id/comments") // "/articles/{article_id}/comments", ["article_id"]
+//	PathTemplate("/files/*/*")                     // "/files/{wildcard1}/{wildcard2}", ["wildcard1", "wildcard2"]
+func PathTemplate(route string) (string, []string) {
+	segments := strings.Split(route, "/")
+	params := make([]string, 0)
+	wildcards := 0
+	for _, segment := range segments {
+		if segment == "*" {
+			wildcards++
+		}
+	}
+	wildcard := 0
+	for i, segment := range segments {
+		var name string
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name = segment[1:]
+		case segment == "*" && wildcards == 1:
+			name = "wildcard"
+		case segment == "*":
+			wildcard++
+			name = "wildcard" + strconv.Itoa(wildcard)
+		case segment == "**":
+			name = "path"
+		default:
+			continue
+		}
+		segments[i] = "{" + name + "}"
+		params = append(params, name)
+	}
+	return strings.Join(segments, "/"), params
+}
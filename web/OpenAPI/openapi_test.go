@@ -0,0 +1,86 @@
+package OpenAPI
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestPathTemplate(t *testing.T) {
+	template, params := PathTemplate("/articles/:article_id/comments/:id/files/**")
+	if template != "/articles/{article_id}/comments/{id}/files/{path}" || len(params) != 3 || params[0] != "article_id" {
+		t.Errorf("unexpected template %s %v", template, params)
+	}
+	template, params = PathTemplate("/files/*/versions/*")
+	if template != "/files/{wildcard1}/versions/{wildcard2}" || len(params) != 2 || params[1] != "wildcard2" {
+		t.Errorf("unexpected template %s %v", template, params)
+	}
+	if template, _ = PathTemplate("/files/*"); template != "/files/{wildcard}" {
+		t.Errorf("unexpected template %s", template)
+	}
+}
+
+func TestPathItem(t *testing.T) {
+	item := &PathItem{}
+	if !item.SetOperation(http.MethodPatch, &Operation{Summary: "update"}) || item.SetOperation(http.MethodConnect, &Operation{}) {
+		t.Fatal("unexpected SetOperation result")
+	}
+	if item.Operation("patch") == nil || item.Operation(http.MethodGet) != nil {
+		t.Fatal("unexpected operations")
+	}
+	b, _ := json.Marshal(item)
+	if string(b) != `{"patch":{"summary":"update","responses":null}}` {
+		t.Errorf("unexpected path item %s", b)
+	}
+}
+
+func TestLoadAndMatch(t *testing.T) {
+	doc, err := Load([]byte(`{
+		"openapi": "3.0.3",
+		"info": {"title": "users", "version": "1"},
+		"servers": [{"url": "https://example.com/v1/"}],
+		"paths": {
+			"/users/{id}": {"get": {"parameters": [{"$ref": "#/components/parameters/id"}], "responses": {}}},
+			"/users/me": {"get": {"responses": {}}}
+		},
+		"components": {
+			"parameters": {"id": {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}},
+			"schemas": {"User": {"type": "string", "nullable": true}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.BasePath() != "/v1" {
+		t.Errorf("unexpected base path %s", doc.BasePath())
+	}
+	matcher := NewMatcher(doc)
+	if template, _, _ := matcher.Match("/users/me"); template != "/users/me" {
+		t.Errorf("unexpected template %s", template)
+	}
+	template, item, params := matcher.Match("/users/a%20b")
+	if template != "/users/{id}" || params["id"] != "a b" {
+		t.Fatalf("unexpected match %s %v", template, params)
+	}
+	// the path is unescaped once, and an escaped slash stays within its segment
+	if _, _, params := matcher.Match("/users/a%2541"); params["id"] != "a%41" {
+		t.Errorf("unexpected parameters %v", params)
+	}
+	if _, _, params := matcher.Match("/users/a%2Fb"); params["id"] != "a/b" {
+		t.Errorf("unexpected parameters %v", params)
+	}
+	if _, item, _ := matcher.Match("/users"); item != nil {
+		t.Error("unexpected match of /users")
+	}
+	parameter, err := doc.ResolveParameter(item.Get.Parameters[0])
+	if err != nil || parameter.Name != "id" {
+		t.Errorf("unexpected parameter %+v, %v", parameter, err)
+	}
+	user, err := doc.Resolve(SCHEMAS_PREFIX + "User")
+	if err != nil || !user.Type.Has("null") {
+		t.Errorf("unexpected schema %+v, %v", user, err)
+	}
+	if _, err := Load([]byte(`{"swagger": "2.0"}`)); err == nil {
+		t.Error("expected an error for Swagger 2.0")
+	}
+}
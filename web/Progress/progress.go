@@ -0,0 +1,164 @@
+// Package Progress tracks the bytes received by uploads, so that clients can poll
+// the progress of an upload while it is being sent.
+package Progress
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// HEADER is the header (or query parameter) clients identify their upload with.
+const HEADER = "X-Progress-ID"
+
+// Progress is the state of an upload.
+type Progress struct {
+	ID string `json:"id"`
+	// Received is the number of bytes of the request body received so far.
+	Received int64 `json:"received"`
+	// Total is the length of the request body, or -1 if the client did not send it.
+	Total int64 `json:"total"`
+	// Done reports whether the whole body has been received.
+	Done bool `json:"done"`
+	// Error is the reason the upload failed, if it did.
+	Error string `json:"error,omitempty"`
+	// UpdatedAt is the time of the last update.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Percent returns the percentage of the body received, or -1 if the length of the body is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total < 0 {
+		return -1
+	}
+	if p.Total == 0 {
+		return 100
+	}
+	return float64(p.Received) * 100 / float64(p.Total)
+}
+
+// Tracker stores the progress of uploads. Entries are expected to expire some time after
+// their last update, so that abandoned uploads do not pile up. Implementations backed by a
+// shared store (e.g. Redis) let any instance report the progress of an upload received by another.
+type Tracker interface {
+	// Set stores the progress of an upload.
+	Set(ctx context.Context, progress Progress) error
+	// Get returns the progress of the upload id, and whether it is known.
+	Get(ctx context.Context, id string) (Progress, bool, error)
+}
+
+// MemoryTracker is a Tracker keeping the progress of uploads in memory.
+type MemoryTracker struct {
+	// TTL is how long an entry is kept after its last update.
+	TTL time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]Progress
+	lastSweep time.Time
+}
+
+// NewMemoryTracker creates a tracker forgetting uploads ttl after their last update.
+func NewMemoryTracker(ttl time.Duration) *MemoryTracker {
+	return &MemoryTracker{TTL: ttl, entries: make(map[string]Progress)}
+}
+
+// Set stores the progress of an upload, removing the expired entries from time to time.
+func (t *MemoryTracker) Set(ctx context.Context, progress Progress) error {
+	now := time.Now()
+	progress.UpdatedAt = now
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[progress.ID] = progress
+	if now.Sub(t.lastSweep) > t.TTL {
+		for id, entry := range t.entries {
+			if now.Sub(entry.UpdatedAt) > t.TTL {
+				delete(t.entries, id)
+			}
+		}
+		t.lastSweep = now
+	}
+	return nil
+}
+
+// Get returns the progress of the upload id, unless it has expired.
+func (t *MemoryTracker) Get(ctx context.Context, id string) (Progress, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress, ok := t.entries[id]
+	if ok && time.Since(progress.UpdatedAt) > t.TTL {
+		delete(t.entries, id)
+		return Progress{}, false, nil
+	}
+	return progress, ok, nil
+}
+
+// Reader counts the bytes read from a request body and reports them to a tracker.
+// Updates are throttled to one per Interval, plus a final one when the body ends.
+type Reader struct {
+	r        io.ReadCloser
+	tracker  Tracker
+	progress Progress
+	last     time.Time
+	// Interval is the minimal time between two updates.
+	Interval time.Duration
+}
+
+// NewReader wraps body, reporting the bytes read under id. total is the length of the body, or -1.
+func NewReader(body io.ReadCloser, tracker Tracker, id string, total int64) *Reader {
+	r := &Reader{
+		r:        body,
+		tracker:  tracker,
+		progress: Progress{ID: id, Total: total},
+		Interval: 200 * time.Millisecond,
+	}
+	r.report()
+	return r
+}
+
+// Read reads from the body, updating the progress.
+func (r *Reader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.progress.Received += int64(n)
+	switch {
+	case err == io.EOF:
+		r.progress.Done = true
+		r.report()
+	case err != nil:
+		r.Fail(err)
+	case time.Since(r.last) >= r.Interval:
+		r.report()
+	}
+	return n, err
+}
+
+// Close closes the body.
+func (r *Reader) Close() error {
+	return r.r.Close()
+}
+
+// Fail records the failure of the upload, e.g. a rejected file found once the body is parsed.
+func (r *Reader) Fail(err error) {
+	if r.progress.Error != "" {
+		return
+	}
+	r.progress.Error = err.Error()
+	r.report()
+}
+
+// Finish reports the body as fully received, when its reader stopped before seeing io.EOF.
+func (r *Reader) Finish() {
+	if r.progress.Done || r.progress.Error != "" {
+		return
+	}
+	r.progress.Done = true
+	r.report()
+}
+
+func (r *Reader) report() {
+	r.last = time.Now()
+	if err := r.tracker.Set(context.Background(), r.progress); err != nil {
+		log.Printf("[WARN] upload progress %s: %v", r.progress.ID, err)
+	}
+}
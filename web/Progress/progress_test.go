@@ -0,0 +1,43 @@
+package Progress
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryTrackerExpires(t *testing.T) {
+	tracker := NewMemoryTracker(50 * time.Millisecond)
+	_ = tracker.Set(context.Background(), Progress{ID: "a", Received: 1, Total: 2})
+	if progress, ok, _ := tracker.Get(context.Background(), "a"); !ok || progress.Percent() != 50 {
+		t.Fatalf("got %+v %v", progress, ok)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok, _ := tracker.Get(context.Background(), "a"); ok {
+		t.Fatal("expected the entry to expire")
+	}
+}
+
+func TestReader(t *testing.T) {
+	tracker := NewMemoryTracker(time.Minute)
+	reader := NewReader(io.NopCloser(strings.NewReader("0123456789")), tracker, "a", 10)
+	reader.Interval = 0
+	buf := make([]byte, 4)
+	_, _ = reader.Read(buf)
+	if progress, _, _ := tracker.Get(context.Background(), "a"); progress.Received != 4 || progress.Done {
+		t.Fatalf("got %+v", progress)
+	}
+	_, _ = io.ReadAll(reader)
+	if progress, _, _ := tracker.Get(context.Background(), "a"); progress.Received != 10 || !progress.Done {
+		t.Fatalf("got %+v", progress)
+	}
+
+	reader = NewReader(io.NopCloser(strings.NewReader("01234")), tracker, "b", 10)
+	reader.Fail(errors.New("file too large"))
+	if progress, _, _ := tracker.Get(context.Background(), "b"); progress.Error != "file too large" || progress.Done {
+		t.Fatalf("got %+v", progress)
+	}
+}
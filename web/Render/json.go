@@ -16,8 +16,8 @@ type JSONRender struct {
 // It sets the Content-Type header to application/json and serializes the Data field into JSON.
 // If the serialization fails, it returns an error.
 func (r JSONRender) Render(w http.ResponseWriter) error {
-	r.WriteContentType(w)                  // Set the Content-Type header.
-	jsonBytes, err := json.Marshal(r.Data) // Serialize the Data field into JSON.
+	r.WriteContentType(w)                // Set the Content-Type header.
+	jsonBytes, err := EncodeJSON(r.Data) // Serialize the Data field into JSON.
 	if err != nil {
 		return err // Return serialization error.
 	}
@@ -29,3 +29,9 @@ func (r JSONRender) Render(w http.ResponseWriter) error {
 func (r JSONRender) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, Constant.JSON_HEADER_CONTENT_TYPE) // Helper function to set Content-Type.
 }
+
+// EncodeJSON serializes data into JSON. It is the encoder shared by every renderer writing JSON,
+// so that a value is serialized the same way in a JSON response and in a streamed event.
+func EncodeJSON(data any) ([]byte, error) {
+	return json.Marshal(data)
+}
@@ -0,0 +1,251 @@
+package Render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// PROBLEM_XML_NAMESPACE is the XML namespace of problem details.
+const PROBLEM_XML_NAMESPACE = "urn:ietf:rfc:7807"
+
+// problemMembers are the members defined by RFC 9457, which extensions cannot override.
+var problemMembers = map[string]bool{"type": true, "title": true, "status": true, "detail": true, "instance": true}
+
+// Problem is a problem details object (RFC 9457), describing an error of an HTTP API in a
+// machine-readable way.
+type Problem struct {
+	// Type is a URI identifying the problem type. Empty means "about:blank": the problem
+	// has no other semantics than its status code.
+	Type string
+	// Title is a short summary of the problem type.
+	Title string
+	// Status is the HTTP status code of the response.
+	Status int
+	// Detail explains this occurrence of the problem.
+	Detail string
+	// Instance is a URI identifying this occurrence of the problem.
+	Instance string
+	// Extensions are additional members, e.g. "balance" or "errors".
+	Extensions map[string]any
+}
+
+// NewProblem creates the problem of a status code, titled with the text of the status code.
+func NewProblem(status int, detail string) Problem {
+	return Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// With returns a copy of p with the extension member name set to value.
+func (p Problem) With(name string, value any) Problem {
+	extensions := make(map[string]any, len(p.Extensions)+1)
+	for k, v := range p.Extensions {
+		extensions[k] = v
+	}
+	extensions[name] = value
+	p.Extensions = extensions
+	return p
+}
+
+// Error returns the title of the problem, followed by its detail.
+func (p Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// extensionNames returns the names of the extensions, sorted, leaving out the standard members.
+func (p Problem) extensionNames() []string {
+	names := make([]string, 0, len(p.Extensions))
+	for name := range p.Extensions {
+		if !problemMembers[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarshalJSON writes the standard members, then the extension members, at the same level.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	member := func(name string, value any) error {
+		data, err := EncodeJSON(value)
+		if err != nil {
+			return err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(data)
+		return nil
+	}
+	if p.Type != "" {
+		_ = member("type", p.Type)
+	}
+	if p.Title != "" {
+		_ = member("title", p.Title)
+	}
+	if p.Status != 0 {
+		_ = member("status", p.Status)
+	}
+	if p.Detail != "" {
+		_ = member("detail", p.Detail)
+	}
+	if p.Instance != "" {
+		_ = member("instance", p.Instance)
+	}
+	for _, name := range p.extensionNames() {
+		if err := member(name, p.Extensions[name]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads the standard members, and the other members into Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	*p = Problem{}
+	fields := map[string]any{"type": &p.Type, "title": &p.Title, "status": &p.Status, "detail": &p.Detail, "instance": &p.Instance}
+	for name, raw := range members {
+		if field, ok := fields[name]; ok {
+			// members of the wrong type are ignored, as RFC 9457 requires
+			_ = json.Unmarshal(raw, field)
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]any)
+		}
+		p.Extensions[name] = value
+	}
+	return nil
+}
+
+// MarshalXML writes the problem as described in appendix B of RFC 9457: a problem element in
+// the urn:ietf:rfc:7807 namespace, with an element per member. Arrays are written as a list
+// of i elements, and maps as an element per key.
+func (p Problem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: PROBLEM_XML_NAMESPACE, Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	standard := []struct {
+		name  string
+		value any
+		set   bool
+	}{
+		{"type", p.Type, p.Type != ""},
+		{"title", p.Title, p.Title != ""},
+		{"status", p.Status, p.Status != 0},
+		{"detail", p.Detail, p.Detail != ""},
+		{"instance", p.Instance, p.Instance != ""},
+	}
+	for _, member := range standard {
+		if member.set {
+			if err := encodeXMLMember(e, member.name, member.value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range p.extensionNames() {
+		if err := encodeXMLMember(e, name, p.Extensions[name]); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeXMLMember(e *xml.Encoder, name string, value any) error {
+	if value == nil {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	v := reflect.ValueOf(value)
+	switch {
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXMLMember(e, "i", v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := encodeXMLMember(e, key, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(value, start)
+}
+
+// ProblemRender renders a problem details object as JSON (application/problem+json).
+// The status code of the response should be the Status of the problem.
+type ProblemRender struct {
+	Problem Problem
+}
+
+// Render writes the problem to the http.ResponseWriter as JSON.
+func (r ProblemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := EncodeJSON(r.Problem)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteContentType sets the Content-Type header for the response to PROBLEM_JSON_HEADER_CONTENT_TYPE.
+func (r ProblemRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, Constant.PROBLEM_JSON_HEADER_CONTENT_TYPE)
+}
+
+// ProblemXMLRender renders a problem details object as XML (application/problem+xml).
+// The status code of the response should be the Status of the problem.
+type ProblemXMLRender struct {
+	Problem Problem
+}
+
+// Render writes the problem to the http.ResponseWriter as XML.
+func (r ProblemXMLRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(r.Problem)
+}
+
+// WriteContentType sets the Content-Type header for the response to PROBLEM_XML_HEADER_CONTENT_TYPE.
+func (r ProblemXMLRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, Constant.PROBLEM_XML_HEADER_CONTENT_TYPE)
+}
@@ -0,0 +1,69 @@
+package Render
+
+import (
+	"bytes"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEvent is an event of a server-sent event stream (text/event-stream).
+type SSEvent struct {
+	// Event is the name of the event. Empty means the default "message" event.
+	Event string
+	// ID is the id of the event, sent back by the client in Last-Event-ID when it reconnects.
+	ID string
+	// Retry is the reconnection delay the client should use. Zero leaves it unchanged.
+	Retry time.Duration
+	// Data is the payload of the event. Strings and byte slices are sent as they are,
+	// anything else is serialized with EncodeJSON.
+	Data any
+}
+
+// Render writes the event to the http.ResponseWriter. Multi-line data is sent as several
+// data lines, and the names and ids are stripped of line breaks so that they cannot inject fields.
+func (r SSEvent) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	if r.Event != "" {
+		buf.WriteString("event: " + singleLine(r.Event) + "\n")
+	}
+	if r.ID != "" {
+		buf.WriteString("id: " + singleLine(r.ID) + "\n")
+	}
+	if r.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(r.Retry.Milliseconds(), 10) + "\n")
+	}
+	if r.Data != nil {
+		var data string
+		switch value := r.Data.(type) {
+		case string:
+			data = value
+		case []byte:
+			data = string(value)
+		default:
+			encoded, err := EncodeJSON(value)
+			if err != nil {
+				return err
+			}
+			data = string(encoded)
+		}
+		data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+		for _, line := range strings.Split(data, "\n") {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteContentType sets the Content-Type header for the response to SSE_HEADER_CONTENT_TYPE.
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, Constant.SSE_HEADER_CONTENT_TYPE)
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,124 @@
+package Render
+
+import (
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"net/http"
+)
+
+// Seq is an iterator over values of type T: it calls yield for every value, and stops as soon
+// as yield returns false. It has the shape of iter.Seq, so iterators of the standard library
+// can be used as they are.
+type Seq[T any] func(yield func(T) bool)
+
+// Chan returns an iterator over the values received from ch, until ch is closed. The producer
+// should stop sending and close ch when the request context is done: the values sent after
+// the iteration has stopped are never received.
+func Chan[T any](ch <-chan T) Seq[T] {
+	return func(yield func(T) bool) {
+		for value := range ch {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Slice returns an iterator over the elements of s.
+func Slice[T any](s []T) Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range s {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// NDJSONRender streams values as newline-delimited JSON (application/x-ndjson), one line per
+// value, flushing every line to the client as soon as it is written.
+type NDJSONRender[T any] struct {
+	// Items yields the values to write.
+	Items Seq[T]
+	// Done stops the stream when closed, typically with the request context: Request.Context().Done().
+	Done <-chan struct{}
+}
+
+// Render writes the values of Items until it is exhausted, Done is closed, or a write fails.
+// Stopping because the client is gone is not an error.
+func (r NDJSONRender[T]) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return streamJSON(w, r.Items, r.Done, nil, []byte("\n"), nil)
+}
+
+// WriteContentType sets the Content-Type header for the response to NDJSON_HEADER_CONTENT_TYPE.
+func (r NDJSONRender[T]) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, Constant.NDJSON_HEADER_CONTENT_TYPE)
+}
+
+// JSONArrayRender streams values as the elements of a JSON array, flushing every element to
+// the client as soon as it is written. Since the status code has already been sent, a failure
+// midway leaves the array unterminated, which clients detect as invalid JSON.
+type JSONArrayRender[T any] struct {
+	// Items yields the elements of the array.
+	Items Seq[T]
+	// Done stops the stream when closed, typically with the request context: Request.Context().Done().
+	Done <-chan struct{}
+}
+
+// Render writes the elements of Items until it is exhausted, Done is closed, or a write fails.
+// Stopping because the client is gone is not an error.
+func (r JSONArrayRender[T]) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return streamJSON(w, r.Items, r.Done, []byte("["), []byte(","), []byte("]"))
+}
+
+// WriteContentType sets the Content-Type header for the response to JSON_HEADER_CONTENT_TYPE.
+func (r JSONArrayRender[T]) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, Constant.JSON_HEADER_CONTENT_TYPE)
+}
+
+// streamJSON writes the values of items encoded with EncodeJSON, after open, separated by sep
+// (or followed by it if close is nil), then close, flushing after every value. Once streaming,
+// a failed write means that the client is gone: it stops the stream like Done, without error.
+func streamJSON[T any](w http.ResponseWriter, items Seq[T], done <-chan struct{}, open []byte, sep []byte, close []byte) error {
+	controller := http.NewResponseController(w)
+	write := func(data []byte) bool {
+		if _, err := w.Write(data); err != nil {
+			return false
+		}
+		err := controller.Flush()
+		return err == nil || errors.Is(err, http.ErrNotSupported)
+	}
+	if !write(open) {
+		return nil
+	}
+	var err error
+	first, stopped := true, false
+	items(func(value T) bool {
+		select {
+		case <-done:
+			stopped = true
+			return false
+		default:
+		}
+		data, encodeErr := EncodeJSON(value)
+		if encodeErr != nil {
+			err = encodeErr
+			return false
+		}
+		if close == nil {
+			data = append(data, sep...)
+		} else if !first {
+			data = append(append([]byte{}, sep...), data...)
+		}
+		first = false
+		stopped = !write(data)
+		return !stopped
+	})
+	if err != nil || stopped {
+		return err
+	}
+	write(close)
+	return nil
+}
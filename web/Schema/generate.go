@@ -0,0 +1,318 @@
+package Schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Binding"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DEFS_PREFIX is the prefix of the references to the definitions of a JSON Schema.
+const DEFS_PREFIX = "#/$defs/"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+)
+
+// unsafeName matches the characters not allowed in the names of definitions.
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Generator generates the schemas of Go types, the way encoding/json encodes them. The named
+// struct types are generated once, into Defs, and referenced with RefPrefix, so that several
+// schemas share their definitions and recursive types are described.
+//
+// The fields are named after their json tag, and the rules of their gjango tag become keywords:
+// required, min and max (minimum/maximum for numbers, minLength/maxLength for strings, and so
+// on), len, enum and pattern. The fields bound from the path, the query or the headers (see
+// Binding.SOURCE_TAGS) are not part of the body, so they are left out.
+type Generator struct {
+	// RefPrefix is the prefix of the references to Defs, e.g. "#/components/schemas/" in an
+	// OpenAPI document.
+	RefPrefix string
+	// Defs are the schemas of the named struct types met so far, by name.
+	Defs map[string]*Schema
+
+	names map[reflect.Type]string
+}
+
+// NewGenerator creates a Generator referencing its definitions with refPrefix,
+// DEFS_PREFIX if it is empty.
+func NewGenerator(refPrefix string) *Generator {
+	if refPrefix == "" {
+		refPrefix = DEFS_PREFIX
+	}
+	return &Generator{RefPrefix: refPrefix, Defs: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// Generate returns the schema of t. The schema of a named struct type is a reference to its
+// definition in Defs.
+func (g *Generator) Generate(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// encoded its own way
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: Types{"string"}}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: Types{"integer"}, Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		if t == durationType {
+			return &Schema{Type: Types{"integer"}, Description: "duration in nanoseconds"}
+		}
+		return &Schema{Type: Types{"integer"}, Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: Types{"integer"}, Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: Types{"number"}, Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: Types{"number"}, Format: "double"}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, ContentEncoding: "base64"}
+		}
+		return &Schema{Type: Types{"array"}, Items: g.Generate(t.Elem())}
+	case reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: g.Generate(t.Elem()), MinItems: integer(t.Len()), MaxItems: integer(t.Len())}
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: g.Generate(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.generateStruct(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.defName(t)
+			g.names[t] = name
+			// registered before its fields, so that recursive types reference it
+			g.Defs[name] = &Schema{}
+			*g.Defs[name] = *g.generateStruct(t)
+		}
+		return &Schema{Ref: g.RefPrefix + name}
+	}
+	// interfaces accept any value
+	return &Schema{}
+}
+
+// For returns the JSON Schema document (draft 2020-12) of the type of v, e.g. For(User{}):
+// the schema of the JSON that ParseJSON and BindJSON accept into it, with its gjango rules,
+// so that it can be published for the generation of forms or used to validate payloads (see
+// Validate). The named structs it references are defined in its $defs.
+func For(v any) *Schema {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return &Schema{Schema: DRAFT}
+	}
+	g := NewGenerator("")
+	s := g.Generate(t)
+	if name, ok := strings.CutPrefix(s.Ref, DEFS_PREFIX); ok {
+		// the root is the definition itself, unless the definitions reference it
+		referenced := false
+		for _, def := range g.Defs {
+			def.walk(func(sub *Schema) {
+				referenced = referenced || sub.Ref == s.Ref
+			})
+		}
+		if !referenced {
+			s = g.Defs[name]
+			delete(g.Defs, name)
+		}
+	}
+	s.Schema = DRAFT
+	if len(g.Defs) > 0 {
+		s.Defs = g.Defs
+	}
+	return s
+}
+
+// Field returns the schema of a struct field, with the rules of its gjango tag.
+func (g *Generator) Field(field reflect.StructField) *Schema {
+	s := g.Generate(field.Type)
+	if _, options, _ := strings.Cut(field.Tag.Get("json"), ","); hasOption(options, "string") {
+		// the value is quoted in a string
+		s = &Schema{Type: Types{"string"}}
+	}
+	ApplyRules(s, field.Type, Binding.ParseRules(field.Tag.Get("gjango")))
+	return s
+}
+
+// ApplyRules adds the keywords of the gjango rules to the schema s of type t.
+func ApplyRules(s *Schema, t reflect.Type, rules []Binding.Rule) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for _, rule := range rules {
+		switch rule.Name {
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(rule.Param, 64)
+			if err != nil {
+				continue
+			}
+			setBound(s, t, rule.Name, n)
+		case "enum":
+			options := strings.Split(rule.Param, "|")
+			s.Enum = make([]any, 0, len(options))
+			for _, option := range options {
+				s.Enum = append(s.Enum, enumValue(t, option))
+			}
+		case "pattern":
+			s.Pattern = rule.Param
+		}
+	}
+}
+
+// setBound sets the keyword of a min, max or len rule of value n, according to the kind of t.
+func setBound(s *Schema, t reflect.Type, rule string, n float64) {
+	var minimum, maximum **int
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		switch rule {
+		case "min":
+			s.Minimum = float(n)
+		case "max":
+			s.Maximum = float(n)
+		}
+		return
+	case reflect.String:
+		minimum, maximum = &s.MinLength, &s.MaxLength
+	case reflect.Slice, reflect.Array:
+		minimum, maximum = &s.MinItems, &s.MaxItems
+	case reflect.Map:
+		minimum, maximum = &s.MinProperties, &s.MaxProperties
+	default:
+		return
+	}
+	if rule == "min" || rule == "len" {
+		*minimum = integer(int(n))
+	}
+	if rule == "max" || rule == "len" {
+		*maximum = integer(int(n))
+	}
+}
+
+// enumValue converts an option of an enum rule to the JSON value of type t.
+func enumValue(t reflect.Type, option string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(option, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, err := strconv.ParseUint(option, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(option, 64); err == nil {
+			return n
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(option); err == nil {
+			return b
+		}
+	}
+	return option
+}
+
+// generateStruct returns the schema of the fields of struct type t.
+func (g *Generator) generateStruct(t reflect.Type) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema)}
+	g.collectFields(s, t)
+	return s
+}
+
+// collectFields adds the fields of t to s. Like encoding/json, the fields of embedded structs
+// without a json tag are promoted, unless an outer struct declares a field of the same name.
+func (g *Generator) collectFields(s *Schema, t reflect.Type) {
+	embedded := make([]reflect.Type, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if field.Anonymous && jsonName == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if source, _ := Binding.SourceTag(field); source != "" {
+			continue
+		}
+		name := Binding.FieldName(field)
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+		s.Properties[name] = g.Field(field)
+		if Binding.HasRule(Binding.ParseRules(field.Tag.Get("gjango")), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	for _, ft := range embedded {
+		g.collectFields(s, ft)
+	}
+}
+
+// defName returns the name of the definition of the named type t, qualified with its package
+// if another type of the same name is already defined.
+func (g *Generator) defName(t reflect.Type) string {
+	name := unsafeName.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.Defs[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	qualified := unsafeName.ReplaceAllString(pkg, "_") + "." + name
+	for i := 2; ; i++ {
+		if _, taken := g.Defs[qualified]; !taken {
+			return qualified
+		}
+		qualified = unsafeName.ReplaceAllString(pkg, "_") + "." + name + strconv.Itoa(i)
+	}
+}
+
+func hasOption(options string, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func integer(n int) *int {
+	return &n
+}
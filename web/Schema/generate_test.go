@@ -0,0 +1,88 @@
+package Schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city" gjango:"required,min=2"`
+}
+
+type base struct {
+	ID      int64     `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type user struct {
+	base
+	Name    string         `json:"name" gjango:"required,max=20,pattern=^[a-z]+$"`
+	Role    string         `json:"role" gjango:"enum=admin|user"`
+	Level   uint8          `json:"level" gjango:"enum=1|2"`
+	Tags    []string       `json:"tags" gjango:"len=2"`
+	Address *address       `json:"address"`
+	Friends []*user        `json:"friends"`
+	Meta    map[string]any `json:"meta"`
+	Avatar  []byte         `json:"avatar"`
+	Org     string         `path:"org"`
+	Secret  string         `json:"-"`
+	secret  string
+}
+
+func TestGenerate(t *testing.T) {
+	g := NewGenerator("")
+	s := g.Generate(reflect.TypeOf(&user{}))
+	if s.Ref != "#/$defs/user" {
+		t.Fatalf("unexpected schema %+v", s)
+	}
+	b, _ := json.Marshal(g.Defs)
+	expected := `{"address":{"type":"object","properties":{"city":{"type":"string","minLength":2}},"required":["city"]},` +
+		`"user":{"type":"object","properties":{` +
+		`"address":{"$ref":"#/$defs/address"},` +
+		`"avatar":{"type":"string","contentEncoding":"base64"},` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"friends":{"type":"array","items":{"$ref":"#/$defs/user"}},` +
+		`"id":{"type":"integer","format":"int64"},` +
+		`"level":{"type":"integer","enum":[1,2],"minimum":0},` +
+		`"meta":{"type":"object","additionalProperties":{}},` +
+		`"name":{"type":"string","maxLength":20,"pattern":"^[a-z]+$"},` +
+		`"role":{"type":"string","enum":["admin","user"]},` +
+		`"tags":{"type":"array","items":{"type":"string"},"minItems":2,"maxItems":2}},` +
+		`"required":["name"]}}`
+	if string(b) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	var s Schema
+	if err := json.Unmarshal([]byte(`{"type": ["string", "null"]}`), &s); err != nil || !s.Type.Has("null") {
+		t.Fatalf("unexpected types %v, %v", s.Type, err)
+	}
+	if b, _ := json.Marshal(Schema{Type: Types{"string"}}); string(b) != `{"type":"string"}` {
+		t.Errorf("unexpected schema %s", b)
+	}
+}
+
+func TestFor(t *testing.T) {
+	s := For(address{})
+	b, _ := json.Marshal(s)
+	expected := `{"$schema":"` + DRAFT + `","type":"object","properties":{"city":{"type":"string","minLength":2}},"required":["city"]}`
+	if string(b) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b)
+	}
+	// recursive types stay a reference to their definition
+	s = For(&user{})
+	if s.Ref != "#/$defs/user" || s.Defs["user"] == nil || s.Defs["address"] == nil {
+		t.Fatalf("unexpected schema %+v", s)
+	}
+	rebased := s.Rebase("#/components/schemas/")
+	if rebased.Ref != "#/components/schemas/user" || rebased.Defs["user"].Properties["address"].Ref != "#/components/schemas/address" {
+		t.Errorf("unexpected rebased schema %+v", rebased)
+	}
+	if s.Defs["user"].Properties["address"].Ref != "#/$defs/address" {
+		t.Error("Rebase modified the original schema")
+	}
+}
@@ -0,0 +1,300 @@
+// Package Schema describes the structs that request data is bound into as JSON Schema
+// (draft 2020-12), the dialect of the schemas of OpenAPI 3.1 documents.
+package Schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DRAFT is the URI of the JSON Schema dialect of the schemas, draft 2020-12.
+const DRAFT = "https://json-schema.org/draft/2020-12/schema"
+
+// Types is the type keyword of a schema: a single type, e.g. "string", or several,
+// e.g. "string" and "null". It is written as a string when it holds a single type.
+type Types []string
+
+// MarshalJSON writes a single type as a string, and several as an array.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON reads a single type or an array of types.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var several []string
+	if err := json.Unmarshal(data, &several); err != nil {
+		return err
+	}
+	*t = several
+	return nil
+}
+
+// Has reports whether name is one of the types.
+func (t Types) Has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema is a JSON Schema, with the keywords used to describe request and response bodies.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type   Types  `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+	Enum   []any  `json:"enum,omitempty"`
+	// Const is the only value accepted, and Default the value of a missing property. Since nil
+	// leaves them out, the JSON null is written Null.
+	Const   any `json:"const,omitempty"`
+	Default any `json:"default,omitempty"`
+
+	// numbers
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+
+	// strings
+	MinLength       *int   `json:"minLength,omitempty"`
+	MaxLength       *int   `json:"maxLength,omitempty"`
+	Pattern         string `json:"pattern,omitempty"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+
+	// arrays: PrefixItems are the schemas of the first items, and Items the schema of the
+	// items after them
+	PrefixItems      []*Schema `json:"prefixItems,omitempty"`
+	Items            *Schema   `json:"items,omitempty"`
+	Contains         *Schema   `json:"contains,omitempty"`
+	MinContains      *int      `json:"minContains,omitempty"`
+	MaxContains      *int      `json:"maxContains,omitempty"`
+	MinItems         *int      `json:"minItems,omitempty"`
+	MaxItems         *int      `json:"maxItems,omitempty"`
+	UniqueItems      bool      `json:"uniqueItems,omitempty"`
+	UnevaluatedItems *Schema   `json:"unevaluatedItems,omitempty"`
+
+	// objects
+	Properties            map[string]*Schema  `json:"properties,omitempty"`
+	PatternProperties     map[string]*Schema  `json:"patternProperties,omitempty"`
+	Required              []string            `json:"required,omitempty"`
+	DependentRequired     map[string][]string `json:"dependentRequired,omitempty"`
+	DependentSchemas      map[string]*Schema  `json:"dependentSchemas,omitempty"`
+	PropertyNames         *Schema             `json:"propertyNames,omitempty"`
+	AdditionalProperties  *Schema             `json:"additionalProperties,omitempty"`
+	UnevaluatedProperties *Schema             `json:"unevaluatedProperties,omitempty"`
+	MinProperties         *int                `json:"minProperties,omitempty"`
+	MaxProperties         *int                `json:"maxProperties,omitempty"`
+
+	// composition
+	AllOf []*Schema `json:"allOf,omitempty"`
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+	OneOf []*Schema `json:"oneOf,omitempty"`
+	Not   *Schema   `json:"not,omitempty"`
+	If    *Schema   `json:"if,omitempty"`
+	Then  *Schema   `json:"then,omitempty"`
+	Else  *Schema   `json:"else,omitempty"`
+
+	// Defs are the schemas referenced by the others, as "#/$defs/name".
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Null is the value of Const and Default standing for the JSON null, e.g. for "const": null.
+var Null = json.RawMessage("null")
+
+// unsupported are the keywords of JSON Schema that the validator does not implement, mostly
+// from the drafts before 2020-12, rejected rather than ignored, so that a schema never accepts
+// what it was written to reject.
+var unsupported = []string{"$dynamicRef", "$recursiveRef", "additionalItems", "dependencies"}
+
+// UnmarshalJSON reads a schema, including the forms of OpenAPI 3.0 documents: the boolean
+// exclusiveMinimum and exclusiveMaximum, and nullable, which adds "null" to the types.
+// The boolean schemas true and false become an empty schema and {"not": {}}, and the null of
+// const and default becomes Null. A schema using a keyword the validator does not implement,
+// e.g. "dependencies", fails to be read.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{Not: &Schema{}}
+		return nil
+	}
+	type plain Schema
+	var raw struct {
+		plain
+		ExclusiveMinimum json.RawMessage `json:"exclusiveMinimum"`
+		ExclusiveMaximum json.RawMessage `json:"exclusiveMaximum"`
+		Nullable         bool            `json:"nullable"`
+		Const            json.RawMessage `json:"const"`
+		Default          json.RawMessage `json:"default"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+	for _, keyword := range unsupported {
+		if _, ok := keywords[keyword]; ok {
+			return fmt.Errorf("unsupported schema keyword %s", keyword)
+		}
+	}
+	*s = Schema(raw.plain)
+	var err error
+	if s.Const, err = constValue(raw.Const); err != nil {
+		return err
+	}
+	if s.Default, err = constValue(raw.Default); err != nil {
+		return err
+	}
+	if err := exclusiveBound(raw.ExclusiveMinimum, &s.Minimum, &s.ExclusiveMinimum); err != nil {
+		return err
+	}
+	if err := exclusiveBound(raw.ExclusiveMaximum, &s.Maximum, &s.ExclusiveMaximum); err != nil {
+		return err
+	}
+	if raw.Nullable && len(s.Type) > 0 && !s.Type.Has("null") {
+		s.Type = append(s.Type, "null")
+		if s.Enum != nil {
+			s.Enum = append(s.Enum, nil)
+		}
+	}
+	return nil
+}
+
+// constValue reads the value of const or default, Null for null and nil when it is missing.
+func constValue(data json.RawMessage) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if string(bytes.TrimSpace(data)) == "null" {
+		return Null, nil
+	}
+	var value any
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// exclusiveBound reads an exclusiveMinimum or an exclusiveMaximum: a number, or a boolean
+// making the bound exclusive in OpenAPI 3.0 documents.
+func exclusiveBound(data json.RawMessage, bound **float64, exclusive **float64) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var flag bool
+	if err := json.Unmarshal(data, &flag); err == nil {
+		if flag {
+			*exclusive, *bound = *bound, nil
+		}
+		return nil
+	}
+	return json.Unmarshal(data, exclusive)
+}
+
+// IsFalse reports whether s is the false schema, which no value matches.
+func (s *Schema) IsFalse() bool {
+	return s.Not != nil && reflect.DeepEqual(*s.Not, Schema{}) && reflect.DeepEqual(*s, Schema{Not: s.Not})
+}
+
+// Rebase returns a copy of s, its definitions included, whose references to its definitions
+// start with prefix instead of DEFS_PREFIX, e.g. "#/components/schemas/", so that the
+// definitions can be moved to the components of an OpenAPI document.
+func (s *Schema) Rebase(prefix string) *Schema {
+	rebased := s.clone()
+	rebased.walk(func(sub *Schema) {
+		if name, ok := strings.CutPrefix(sub.Ref, DEFS_PREFIX); ok {
+			sub.Ref = prefix + name
+		}
+	})
+	return rebased
+}
+
+// walk calls visit on s and on its subschemas, its definitions included.
+func (s *Schema) walk(visit func(*Schema)) {
+	if s == nil {
+		return
+	}
+	visit(s)
+	for _, sub := range []*Schema{s.Items, s.Contains, s.UnevaluatedItems, s.PropertyNames,
+		s.AdditionalProperties, s.UnevaluatedProperties, s.Not, s.If, s.Then, s.Else} {
+		sub.walk(visit)
+	}
+	for _, subs := range [][]*Schema{s.PrefixItems, s.AllOf, s.AnyOf, s.OneOf} {
+		for _, sub := range subs {
+			sub.walk(visit)
+		}
+	}
+	for _, subs := range []map[string]*Schema{s.Properties, s.PatternProperties, s.DependentSchemas, s.Defs} {
+		for _, sub := range subs {
+			sub.walk(visit)
+		}
+	}
+}
+
+// clone returns a deep copy of the subschemas of s; the values of enum, const and default
+// are shared.
+func (s *Schema) clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Type = append(Types(nil), s.Type...)
+	c.Required = append([]string(nil), s.Required...)
+	if s.DependentRequired != nil {
+		c.DependentRequired = make(map[string][]string, len(s.DependentRequired))
+		for name, required := range s.DependentRequired {
+			c.DependentRequired[name] = append([]string(nil), required...)
+		}
+	}
+	c.Items, c.Contains, c.UnevaluatedItems = s.Items.clone(), s.Contains.clone(), s.UnevaluatedItems.clone()
+	c.PropertyNames = s.PropertyNames.clone()
+	c.AdditionalProperties, c.UnevaluatedProperties = s.AdditionalProperties.clone(), s.UnevaluatedProperties.clone()
+	c.Not, c.If, c.Then, c.Else = s.Not.clone(), s.If.clone(), s.Then.clone(), s.Else.clone()
+	c.PrefixItems = cloneAll(s.PrefixItems)
+	c.AllOf, c.AnyOf, c.OneOf = cloneAll(s.AllOf), cloneAll(s.AnyOf), cloneAll(s.OneOf)
+	c.Properties, c.PatternProperties = cloneMap(s.Properties), cloneMap(s.PatternProperties)
+	c.DependentSchemas, c.Defs = cloneMap(s.DependentSchemas), cloneMap(s.Defs)
+	return &c
+}
+
+func cloneAll(schemas []*Schema) []*Schema {
+	if schemas == nil {
+		return nil
+	}
+	clones := make([]*Schema, len(schemas))
+	for i, s := range schemas {
+		clones[i] = s.clone()
+	}
+	return clones
+}
+
+func cloneMap(schemas map[string]*Schema) map[string]*Schema {
+	if schemas == nil {
+		return nil
+	}
+	clones := make(map[string]*Schema, len(schemas))
+	for name, s := range schemas {
+		clones[name] = s.clone()
+	}
+	return clones
+}
@@ -0,0 +1,624 @@
+package Schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ROOT is the field the errors about the validated value itself are reported on.
+const ROOT = "$"
+
+// Resolver returns the schema referenced by ref, e.g. "#/$defs/user".
+type Resolver func(ref string) (*Schema, error)
+
+// Decode decodes a JSON document into the values Validate expects: nil, bool, json.Number,
+// string, []any and map[string]any. Numbers are kept as json.Number, so that large integers
+// are checked exactly.
+func Decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// Validate checks value, decoded from JSON (see Decode), against s. References are resolved
+// against the definitions of s (see Defs). See ValidateAt.
+func (s *Schema) Validate(value any) error {
+	return s.ValidateAt(value, "", s.DefsResolver())
+}
+
+// ValidateAt checks value against s, reporting the errors on the fields below path, e.g.
+// "address.city" below "user". References are resolved with resolve.
+//
+// The returned error joins an *I18n.Error per violation, so that all of them can be reported
+// to the client, localized: a value of the wrong type is a BIND_TYPE_MISMATCH, a missing
+// property a VALIDATE_REQUIRED, a number too small a VALIDATE_MIN, and so on.
+func (s *Schema) ValidateAt(value any, path string, resolve Resolver) error {
+	v := &validator{resolve: resolve}
+	v.validate(s, value, path)
+	return errors.Join(v.errs...)
+}
+
+// DefsResolver returns a Resolver of the references to the definitions of s, "#/$defs/name".
+func (s *Schema) DefsResolver() Resolver {
+	return func(ref string) (*Schema, error) {
+		if name, ok := strings.CutPrefix(ref, DEFS_PREFIX); ok {
+			if def, ok := s.Defs[name]; ok {
+				return def, nil
+			}
+		}
+		if ref == "#" {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unresolved schema reference %s", ref)
+	}
+}
+
+// validator collects the violations of a value.
+type validator struct {
+	resolve Resolver
+	errs    []error
+}
+
+func (v *validator) fail(key string, path string, params map[string]any) {
+	if params == nil {
+		params = make(map[string]any, 1)
+	}
+	if path == "" {
+		path = ROOT
+	}
+	params["field"] = path
+	v.errs = append(v.errs, I18n.NewError(key, params))
+}
+
+// evaluated are the properties and the items of a value evaluated by a schema and by its
+// subschemas that the value matches, which unevaluatedProperties and unevaluatedItems skip.
+type evaluated struct {
+	properties map[string]bool
+	// items is the number of first items evaluated, e.g. by prefixItems
+	items int
+	// allItems reports whether all the items are evaluated, e.g. by items
+	allItems bool
+	// contained are the indexes of the items matching contains
+	contained map[int]bool
+}
+
+// merge adds the evaluations of a subschema, nil if the value does not match it.
+func (e *evaluated) merge(other *evaluated) {
+	if other == nil {
+		return
+	}
+	for name := range other.properties {
+		e.property(name)
+	}
+	if other.items > e.items {
+		e.items = other.items
+	}
+	e.allItems = e.allItems || other.allItems
+	for i := range other.contained {
+		if e.contained == nil {
+			e.contained = make(map[int]bool)
+		}
+		e.contained[i] = true
+	}
+}
+
+func (e *evaluated) property(name string) {
+	if e.properties == nil {
+		e.properties = make(map[string]bool)
+	}
+	e.properties[name] = true
+}
+
+func (e *evaluated) item(i int) bool {
+	return e.allItems || i < e.items || e.contained[i]
+}
+
+// matches returns what s evaluates of value if value matches s, without reporting the
+// violations, and nil if it does not.
+func (v *validator) matches(s *Schema, value any, path string) *evaluated {
+	inner := &validator{resolve: v.resolve}
+	return inner.validate(s, value, path)
+}
+
+// validate reports the violations of s by value, and returns what s evaluates of value,
+// nil if value does not match s.
+func (v *validator) validate(s *Schema, value any, path string) *evaluated {
+	seen := &evaluated{}
+	if s == nil {
+		return seen
+	}
+	failed := len(v.errs)
+	if s.Ref != "" {
+		if v.resolve == nil {
+			v.errs = append(v.errs, fmt.Errorf("unresolved schema reference %s", s.Ref))
+		} else if target, err := v.resolve(s.Ref); err != nil {
+			v.errs = append(v.errs, err)
+		} else {
+			seen.merge(v.validate(target, value, path))
+		}
+	}
+	if s.IsFalse() {
+		v.fail(I18n.VALIDATE_SCHEMA, path, nil)
+		return nil
+	}
+	if len(s.Type) > 0 && !matchesType(value, s.Type) {
+		v.fail(I18n.BIND_TYPE_MISMATCH, path, map[string]any{"expected": strings.Join(s.Type, " or "), "actual": typeOf(value)})
+		return nil
+	}
+	if s.Enum != nil && !inEnum(value, s.Enum) {
+		options := make([]string, len(s.Enum))
+		for i, option := range s.Enum {
+			options[i] = fmt.Sprint(jsonValue(option))
+		}
+		v.fail(I18n.VALIDATE_ENUM, path, map[string]any{"param": strings.Join(options, ", ")})
+	}
+	if s.Const != nil && !equal(value, jsonValue(s.Const)) {
+		v.fail(I18n.VALIDATE_ENUM, path, map[string]any{"param": fmt.Sprint(jsonValue(s.Const))})
+	}
+	switch value := value.(type) {
+	case string:
+		v.validateString(s, value, path)
+	case []any:
+		seen.merge(v.validateArray(s, value, path))
+	case map[string]any:
+		seen.merge(v.validateObject(s, value, path))
+	default:
+		if n, ok := number(value); ok {
+			v.validateNumber(s, n, path)
+		}
+	}
+	for _, sub := range s.AllOf {
+		seen.merge(v.validate(sub, value, path))
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		// all the subschemas are tried, for what they evaluate
+		for _, sub := range s.AnyOf {
+			if e := v.matches(sub, value, path); e != nil {
+				matched = true
+				seen.merge(e)
+			}
+		}
+		if !matched {
+			v.fail(I18n.VALIDATE_SCHEMA, path, nil)
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := make([]*evaluated, 0, 1)
+		for _, sub := range s.OneOf {
+			if e := v.matches(sub, value, path); e != nil {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) != 1 {
+			v.fail(I18n.VALIDATE_SCHEMA, path, nil)
+		} else {
+			seen.merge(matched[0])
+		}
+	}
+	if s.Not != nil && v.matches(s.Not, value, path) != nil {
+		v.fail(I18n.VALIDATE_SCHEMA, path, nil)
+	}
+	if s.If != nil {
+		if e := v.matches(s.If, value, path); e != nil {
+			seen.merge(e)
+			seen.merge(v.validate(s.Then, value, path))
+		} else {
+			seen.merge(v.validate(s.Else, value, path))
+		}
+	}
+	// once all the other keywords have evaluated the value
+	switch value := value.(type) {
+	case []any:
+		v.validateUnevaluatedItems(s, value, path, seen)
+	case map[string]any:
+		v.validateUnevaluatedProperties(s, value, path, seen)
+	}
+	if len(v.errs) > failed {
+		return nil
+	}
+	return seen
+}
+
+func (v *validator) validateNumber(s *Schema, n float64, path string) {
+	if s.Minimum != nil && n < *s.Minimum {
+		v.fail(I18n.VALIDATE_MIN, path, map[string]any{"param": formatNumber(*s.Minimum)})
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		v.fail(I18n.VALIDATE_MAX, path, map[string]any{"param": formatNumber(*s.Maximum)})
+	}
+	if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+		v.fail(I18n.VALIDATE_EXCLUSIVE_MIN, path, map[string]any{"param": formatNumber(*s.ExclusiveMinimum)})
+	}
+	if s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum {
+		v.fail(I18n.VALIDATE_EXCLUSIVE_MAX, path, map[string]any{"param": formatNumber(*s.ExclusiveMaximum)})
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		quotient := n / *s.MultipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(I18n.VALIDATE_MULTIPLE_OF, path, map[string]any{"param": formatNumber(*s.MultipleOf)})
+		}
+	}
+}
+
+func (v *validator) validateString(s *Schema, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		v.fail(I18n.VALIDATE_MIN, path, map[string]any{"param": *s.MinLength})
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.fail(I18n.VALIDATE_MAX, path, map[string]any{"param": *s.MaxLength})
+	}
+	if s.Pattern != "" {
+		re, err := compilePattern(s.Pattern)
+		if err != nil {
+			v.errs = append(v.errs, err)
+		} else if !re.MatchString(value) {
+			v.fail(I18n.VALIDATE_PATTERN, path, map[string]any{"param": s.Pattern})
+		}
+	}
+	if s.Format != "" && !validFormat(s.Format, value) {
+		v.fail(I18n.VALIDATE_FORMAT, path, map[string]any{"param": s.Format})
+	}
+}
+
+func (v *validator) validateArray(s *Schema, items []any, path string) *evaluated {
+	seen := &evaluated{}
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		v.fail(I18n.VALIDATE_MIN, path, map[string]any{"param": *s.MinItems})
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		v.fail(I18n.VALIDATE_MAX, path, map[string]any{"param": *s.MaxItems})
+	}
+	if s.UniqueItems {
+	unique:
+		for i := range items {
+			for j := 0; j < i; j++ {
+				if equal(items[i], items[j]) {
+					v.fail(I18n.VALIDATE_UNIQUE, path, nil)
+					break unique
+				}
+			}
+		}
+	}
+	for i, item := range items {
+		if i < len(s.PrefixItems) {
+			v.validate(s.PrefixItems[i], item, itemPath(path, i))
+			seen.items = i + 1
+		} else if s.Items != nil {
+			v.validate(s.Items, item, itemPath(path, i))
+			seen.allItems = true
+		}
+	}
+	if s.Contains != nil {
+		seen.contained = make(map[int]bool)
+		for i, item := range items {
+			if v.matches(s.Contains, item, itemPath(path, i)) != nil {
+				seen.contained[i] = true
+			}
+		}
+		// minContains is 1 by default, and 0 accepts an array without matching items
+		minContains := 1
+		if s.MinContains != nil {
+			minContains = *s.MinContains
+		}
+		if len(seen.contained) < minContains {
+			v.fail(I18n.VALIDATE_MIN_CONTAINS, path, map[string]any{"param": minContains})
+		}
+		if s.MaxContains != nil && len(seen.contained) > *s.MaxContains {
+			v.fail(I18n.VALIDATE_MAX_CONTAINS, path, map[string]any{"param": *s.MaxContains})
+		}
+	}
+	return seen
+}
+
+// validateUnevaluatedItems checks the items that no other keyword evaluated against
+// unevaluatedItems.
+func (v *validator) validateUnevaluatedItems(s *Schema, items []any, path string, seen *evaluated) {
+	if s.UnevaluatedItems == nil {
+		return
+	}
+	for i, item := range items {
+		if seen.item(i) {
+			continue
+		}
+		if s.UnevaluatedItems.IsFalse() {
+			v.fail(I18n.BIND_UNKNOWN_FIELD, itemPath(path, i), nil)
+			continue
+		}
+		v.validate(s.UnevaluatedItems, item, itemPath(path, i))
+	}
+	seen.allItems = true
+}
+
+func (v *validator) validateObject(s *Schema, object map[string]any, path string) *evaluated {
+	seen := &evaluated{}
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			v.fail(I18n.VALIDATE_REQUIRED, joinPath(path, name), nil)
+		}
+	}
+	if s.MinProperties != nil && len(object) < *s.MinProperties {
+		v.fail(I18n.VALIDATE_MIN, path, map[string]any{"param": *s.MinProperties})
+	}
+	if s.MaxProperties != nil && len(object) > *s.MaxProperties {
+		v.fail(I18n.VALIDATE_MAX, path, map[string]any{"param": *s.MaxProperties})
+	}
+	// sorted, so that the errors come in a stable order
+	names := sortedNames(object)
+	for _, name := range names {
+		if _, ok := object[name]; !ok {
+			continue
+		}
+		for _, required := range s.DependentRequired[name] {
+			if _, ok := object[required]; !ok {
+				v.fail(I18n.VALIDATE_REQUIRED, joinPath(path, required), nil)
+			}
+		}
+		if dependent, ok := s.DependentSchemas[name]; ok {
+			seen.merge(v.validate(dependent, object, path))
+		}
+	}
+	for _, name := range names {
+		if s.PropertyNames != nil && v.matches(s.PropertyNames, name, joinPath(path, name)) == nil {
+			v.fail(I18n.BIND_UNKNOWN_FIELD, joinPath(path, name), nil)
+		}
+		matched := false
+		if property, ok := s.Properties[name]; ok {
+			v.validate(property, object[name], joinPath(path, name))
+			matched = true
+		}
+		for pattern, property := range s.PatternProperties {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				v.errs = append(v.errs, err)
+			} else if re.MatchString(name) {
+				v.validate(property, object[name], joinPath(path, name))
+				matched = true
+			}
+		}
+		if !matched && s.AdditionalProperties != nil {
+			if s.AdditionalProperties.IsFalse() {
+				v.fail(I18n.BIND_UNKNOWN_FIELD, joinPath(path, name), nil)
+			} else {
+				v.validate(s.AdditionalProperties, object[name], joinPath(path, name))
+			}
+			matched = true
+		}
+		if matched {
+			seen.property(name)
+		}
+	}
+	return seen
+}
+
+// validateUnevaluatedProperties checks the properties that no other keyword evaluated against
+// unevaluatedProperties.
+func (v *validator) validateUnevaluatedProperties(s *Schema, object map[string]any, path string, seen *evaluated) {
+	if s.UnevaluatedProperties == nil {
+		return
+	}
+	for _, name := range sortedNames(object) {
+		if seen.properties[name] {
+			continue
+		}
+		if s.UnevaluatedProperties.IsFalse() {
+			v.fail(I18n.BIND_UNKNOWN_FIELD, joinPath(path, name), nil)
+		} else {
+			v.validate(s.UnevaluatedProperties, object[name], joinPath(path, name))
+		}
+		seen.property(name)
+	}
+}
+
+func sortedNames(object map[string]any) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// itemPath returns the path of the item i of the array of path, e.g. "tags[2]".
+func itemPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// joinPath appends the name of a property to the path of its object, e.g. "user" + "address" = "user.address".
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// typeOf returns the JSON type of value.
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if n, ok := number(value); ok {
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// matchesType reports whether value is of one of types. An integer is a number as well.
+func matchesType(value any, types Types) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// number returns the value of a number, decoded as a json.Number or a Go number.
+func number(value any) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	}
+	return 0, false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// equal reports whether two JSON values are equal, whatever the Go types of their numbers.
+func equal(a any, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, x := range a {
+			y, ok := b[k]
+			if !ok || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// jsonValue returns the value of a const, a default or an option of an enum: nil for Null.
+func jsonValue(value any) any {
+	if raw, ok := value.(json.RawMessage); ok {
+		decoded, err := Decode(raw)
+		if err == nil {
+			return decoded
+		}
+	}
+	return value
+}
+
+func inEnum(value any, options []any) bool {
+	for _, option := range options {
+		if equal(value, jsonValue(option)) {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns caches the compiled regular expressions of the schemas.
+var patterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema pattern %s: %w", pattern, err)
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// validFormat checks the formats of strings the validator knows; the others always pass.
+func validFormat(format string, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", value)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	case "uri-reference":
+		_, err := url.Parse(value)
+		return err == nil
+	case "hostname":
+		return len(value) <= 253 && hostnamePattern.MatchString(value)
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ".") && !strings.Contains(value, ":")
+	case "ipv6":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package Schema
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var s Schema
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["name", "email"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 2},
+			"email": {"type": "string", "format": "email"},
+			"age": {"type": "integer", "minimum": 0, "exclusiveMinimum": true, "nullable": true},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "uniqueItems": true}
+		},
+		"$defs": {"tag": {"type": "string", "enum": ["a", "b"]}}
+	}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, _ := Decode([]byte(`{"name": "jo", "email": "jo@example.com", "age": null, "tags": ["a", "b"]}`))
+	if err := s.Validate(valid); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	invalid, _ := Decode([]byte(`{"name": "j", "age": 0, "tags": ["a", "a", "c"], "role": "x"}`))
+	err = s.Validate(invalid)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	expected := map[string]string{
+		"email":   I18n.VALIDATE_REQUIRED,
+		"name":    I18n.VALIDATE_MIN,
+		"age":     I18n.VALIDATE_EXCLUSIVE_MIN,
+		"tags":    I18n.VALIDATE_UNIQUE,
+		"tags[2]": I18n.VALIDATE_ENUM,
+		"role":    I18n.BIND_UNKNOWN_FIELD,
+	}
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != len(expected) {
+		t.Fatalf("unexpected errors %v", err)
+	}
+	for _, e := range errs {
+		var i18nErr *I18n.Error
+		if !errors.As(e, &i18nErr) || expected[i18nErr.Field()] != i18nErr.Key {
+			t.Errorf("unexpected error %v", e)
+		}
+	}
+}
+
+func TestValidateComposition(t *testing.T) {
+	var s Schema
+	if err := json.Unmarshal([]byte(`{"oneOf": [{"type": "integer", "multipleOf": 2}, {"type": "integer", "multipleOf": 3}]}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	for value, ok := range map[string]bool{"4": true, "9": true, "6": false, "5": false, `"4"`: false} {
+		decoded, _ := Decode([]byte(value))
+		if err := s.Validate(decoded); (err == nil) != ok {
+			t.Errorf("unexpected result for %s: %v", value, err)
+		}
+	}
+}
+
+func TestValidateKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		valid  []string
+		// invalid maps the invalid values to the key of their first error
+		invalid map[string]string
+	}{
+		{"patternProperties", `{"type": "object", "additionalProperties": false, "patternProperties": {"^x-": {"type": "string"}}}`,
+			[]string{`{"x-id": "1"}`}, map[string]string{`{"x-id": 1}`: I18n.BIND_TYPE_MISMATCH, `{"id": "1"}`: I18n.BIND_UNKNOWN_FIELD}},
+		{"propertyNames", `{"propertyNames": {"maxLength": 3}}`,
+			[]string{`{"abc": 1}`}, map[string]string{`{"abcd": 1}`: I18n.BIND_UNKNOWN_FIELD}},
+		{"dependentRequired", `{"dependentRequired": {"card": ["cvc"]}}`,
+			[]string{`{}`, `{"card": "1", "cvc": "2"}`}, map[string]string{`{"card": "1"}`: I18n.VALIDATE_REQUIRED}},
+		{"dependentSchemas", `{"dependentSchemas": {"card": {"required": ["cvc"]}}}`,
+			[]string{`{}`, `{"card": "1", "cvc": "2"}`}, map[string]string{`{"card": "1"}`: I18n.VALIDATE_REQUIRED}},
+		{"prefixItems", `{"prefixItems": [{"type": "string"}, {"type": "integer"}], "items": false}`,
+			[]string{`["a", 1]`, `["a"]`}, map[string]string{`[1, 1]`: I18n.BIND_TYPE_MISMATCH, `["a", 1, 2]`: I18n.VALIDATE_SCHEMA}},
+		{"contains", `{"contains": {"type": "integer"}, "minContains": 2, "maxContains": 3}`,
+			[]string{`[1, "a", 2]`}, map[string]string{`[1, "a"]`: I18n.VALIDATE_MIN_CONTAINS, `[1, 2, 3, 4]`: I18n.VALIDATE_MAX_CONTAINS}},
+		{"if then else", `{"if": {"properties": {"kind": {"const": "card"}}}, "then": {"required": ["number"]}, "else": {"required": ["iban"]}}`,
+			[]string{`{"kind": "card", "number": "1"}`, `{"kind": "transfer", "iban": "FR"}`},
+			map[string]string{`{"kind": "card"}`: I18n.VALIDATE_REQUIRED, `{"kind": "transfer"}`: I18n.VALIDATE_REQUIRED}},
+		{"unevaluatedProperties", `{"allOf": [{"properties": {"name": {"type": "string"}}}], "anyOf": [{"properties": {"age": {}}}, {"required": ["id"], "properties": {"id": {}}}], "unevaluatedProperties": false}`,
+			[]string{`{"name": "jo", "age": 3}`, `{"id": 1}`}, map[string]string{`{"name": "jo", "role": "x"}`: I18n.BIND_UNKNOWN_FIELD}},
+		{"unevaluatedItems", `{"prefixItems": [{"type": "string"}], "contains": {"type": "integer"}, "unevaluatedItems": false}`,
+			[]string{`["a", 1, 2]`}, map[string]string{`["a", 1, true]`: I18n.BIND_UNKNOWN_FIELD}},
+		{"const null", `{"const": null}`, []string{`null`}, map[string]string{`0`: I18n.VALIDATE_ENUM, `{}`: I18n.VALIDATE_ENUM}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s Schema
+			if err := json.Unmarshal([]byte(test.schema), &s); err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range test.valid {
+				decoded, _ := Decode([]byte(value))
+				if err := s.Validate(decoded); err != nil {
+					t.Errorf("%s: unexpected error %v", value, err)
+				}
+			}
+			for value, key := range test.invalid {
+				decoded, _ := Decode([]byte(value))
+				var i18nErr *I18n.Error
+				if err := s.Validate(decoded); !errors.As(err, &i18nErr) || i18nErr.Key != key {
+					t.Errorf("%s: expected %s, got %v", value, key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConstNull(t *testing.T) {
+	data, err := json.Marshal(&Schema{Const: Null})
+	if err != nil || string(data) != `{"const":null}` {
+		t.Errorf("expected {\"const\":null}, got %s %v", data, err)
+	}
+	var s Schema
+	if err := json.Unmarshal([]byte(`{"type": "string"}`), &s); err != nil || s.Const != nil || s.Default != nil {
+		t.Errorf("expected no const, got %#v %v", s.Const, err)
+	}
+}
+
+func TestUnsupportedKeywords(t *testing.T) {
+	for _, schema := range []string{`{"dependencies": {"a": ["b"]}}`, `{"properties": {"a": {"additionalItems": false}}}`} {
+		var s Schema
+		if err := json.Unmarshal([]byte(schema), &s); err == nil {
+			t.Errorf("%s: expected the schema to be rejected", schema)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package Storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tempPrefix marks the temp files Put writes before renaming them, which List skips.
+const tempPrefix = ".storage-"
+
+// Local is a Storage keeping objects as files under a directory.
+// The content type of an object is derived from the extension of its key.
+type Local struct {
+	// Dir is the directory objects are stored in. It is created on the first Put.
+	Dir string
+	// DirPerm are the permissions of the created directories. Zero means 0755.
+	DirPerm os.FileMode
+	// FilePerm are the permissions of the stored files. Zero means 0644.
+	FilePerm os.FileMode
+	// Signer signs the URLs returned by SignedURL. Without it, SignedURL returns ErrNotSupported.
+	Signer *URLSigner
+}
+
+// NewLocal creates a storage keeping objects under dir.
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+// Put writes the content of r to the file of key. The file is written to a temp file first
+// and renamed, so that a partial object is never visible.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (Object, error) {
+	name, key, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	dirPerm := l.DirPerm
+	if dirPerm == 0 {
+		dirPerm = 0755
+	}
+	if err := os.MkdirAll(filepath.Dir(name), dirPerm); err != nil {
+		return Object{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), tempPrefix+"*")
+	if err != nil {
+		return Object{}, err
+	}
+	// removing the temp file fails harmlessly once it has been renamed
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, contextReader{ctx: ctx, r: r}); err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		perm := l.FilePerm
+		if perm == 0 {
+			perm = 0644
+		}
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return Object{}, err
+	}
+	return l.Stat(ctx, key)
+}
+
+// Get opens the file of key. The returned reader is an *os.File.
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	name, key, err := l.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, Object{}, notExist(err)
+	}
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		if err == nil {
+			err = ErrNotExist
+		}
+		return nil, Object{}, err
+	}
+	return file, fileObject(key, info), nil
+}
+
+// Delete removes the file of key.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, _, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stat describes the file of key.
+func (l *Local) Stat(ctx context.Context, key string) (Object, error) {
+	name, key, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return Object{}, notExist(err)
+	}
+	if info.IsDir() {
+		return Object{}, ErrNotExist
+	}
+	return fileObject(key, info), nil
+}
+
+// List describes the files whose key starts with prefix.
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	err := filepath.WalkDir(l.Dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(l.Dir, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return notExist(err)
+		}
+		objects = append(objects, fileObject(key, info))
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+// SignedURL returns a URL signed by l.Signer.
+func (l *Local) SignedURL(ctx context.Context, key string, method string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return l.Signer.Sign(key, method, expires)
+}
+
+// path returns the file name of a key, along with the cleaned key.
+func (l *Local) path(key string) (string, string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), key, nil
+}
+
+func fileObject(key string, info fs.FileInfo) Object {
+	return Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+		ETag:        strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36),
+	}
+}
+
+func notExist(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotExist
+	}
+	return err
+}
+
+// contextReader stops reading once its context is done, so that canceled requests stop copying.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(b)
+}
@@ -0,0 +1,131 @@
+package Storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a Storage keeping objects in memory, meant for tests and single-instance caches.
+type Memory struct {
+	// Signer signs the URLs returned by SignedURL. Without it, SignedURL returns ErrNotSupported.
+	Signer *URLSigner
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	info Object
+	data []byte
+}
+
+// readSeekCloser lets a bytes.Reader be returned by Get.
+type readSeekCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekCloser) Close() error {
+	return nil
+}
+
+// NewMemory creates an empty in-memory storage.
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[string]memoryObject)}
+}
+
+// Put stores the content of r under key.
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return Object{}, err
+	}
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return Object{}, err
+	}
+	sum := md5.Sum(data)
+	info := Object{
+		Key:         key,
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		ModTime:     time.Now(),
+		ETag:        hex.EncodeToString(sum[:]),
+	}
+	m.mu.Lock()
+	m.objects[key] = memoryObject{info: info, data: data}
+	m.mu.Unlock()
+	return info, nil
+}
+
+// Get opens the object stored under key.
+func (m *Memory) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	object, err := m.lookup(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	return readSeekCloser{bytes.NewReader(object.data)}, object.info, nil
+}
+
+// Delete removes the object stored under key.
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// Stat describes the object stored under key.
+func (m *Memory) Stat(ctx context.Context, key string) (Object, error) {
+	object, err := m.lookup(key)
+	return object.info, err
+}
+
+// List describes the objects whose key starts with prefix.
+func (m *Memory) List(ctx context.Context, prefix string) ([]Object, error) {
+	m.mu.RLock()
+	objects := make([]Object, 0)
+	for key, object := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, object.info)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+// SignedURL returns a URL signed by m.Signer.
+func (m *Memory) SignedURL(ctx context.Context, key string, method string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return m.Signer.Sign(key, method, expires)
+}
+
+func (m *Memory) lookup(key string) (memoryObject, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return memoryObject{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return memoryObject{}, ErrNotExist
+	}
+	return object, nil
+}
@@ -0,0 +1,503 @@
+package Storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+	// s3MinPartSize is the smallest size of the parts of a multipart upload but the last one.
+	s3MinPartSize = 5 << 20
+)
+
+// S3Config configures an S3-compatible storage (AWS S3, MinIO, Ceph, R2...).
+type S3Config struct {
+	// Endpoint is the base URL of the API, e.g. "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint string
+	// Bucket is the bucket objects are stored in. Requests use path-style URLs: Endpoint/Bucket/key.
+	Bucket string
+	// Region is the region the requests are signed for. Empty means "us-east-1".
+	Region string
+	// AccessKey and SecretKey are the credentials requests are signed with (AWS Signature Version 4).
+	AccessKey string
+	SecretKey string
+	// Client sends the requests. Nil means http.DefaultClient.
+	Client *http.Client
+	// PartSize is the size of the parts the content of unknown size is uploaded in (see S3.Put).
+	// Zero means 8 MiB, and it is at least 5 MiB, the smallest part accepted by S3.
+	PartSize int64
+}
+
+// S3 is a Storage keeping objects in a bucket of an S3-compatible object store.
+// Requests are signed with AWS Signature Version 4; payloads are sent unsigned
+// (UNSIGNED-PAYLOAD) so that uploads can be streamed.
+type S3 struct {
+	config S3Config
+	// now returns the time requests are signed at, replaced in tests.
+	now func() time.Time
+}
+
+// S3Error is returned when the object store answers with an unexpected status.
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s (%d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// NewS3 creates a storage backed by an S3-compatible object store.
+func NewS3(config S3Config) *S3 {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.PartSize == 0 {
+		config.PartSize = 8 << 20
+	}
+	if config.PartSize < s3MinPartSize {
+		config.PartSize = s3MinPartSize
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &S3{config: config, now: time.Now}
+}
+
+// Put uploads the content of r under key. The object store needs the length of every request
+// beforehand: when size is unknown (-1), the content is read in parts of S3Config.PartSize
+// buffered in memory, one at a time, and sent with a multipart upload, or with a single request
+// if it is shorter than one part. A multipart upload failing midway is aborted.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return Object{}, err
+	}
+	if size < 0 {
+		return s.putParts(ctx, key, contextReader{ctx: ctx, r: r}, contentType)
+	}
+	return s.put(ctx, key, r, size, contentType)
+}
+
+// put uploads the content of r, of the given size, under key with a single request.
+func (s *S3) put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (Object, error) {
+	req, err := s.request(ctx, http.MethodPut, key, nil, r)
+	if err != nil {
+		return Object{}, err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return Object{}, err
+	}
+	res.Body.Close()
+	return Object{
+		Key:         key,
+		Size:        size,
+		ContentType: contentType,
+		ModTime:     s.now(),
+		ETag:        strings.Trim(res.Header.Get("ETag"), `"`),
+	}, nil
+}
+
+// completedPart is a part of a multipart upload, listed to complete it.
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putParts uploads the content of r, of unknown size, under key: with a single request if it
+// is shorter than one part, with a multipart upload otherwise.
+func (s *S3) putParts(ctx context.Context, key string, r io.Reader, contentType string) (Object, error) {
+	part := make([]byte, s.config.PartSize)
+	n, err := io.ReadFull(r, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.put(ctx, key, bytes.NewReader(part[:n]), int64(n), contentType)
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	uploadID, err := s.createMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return Object{}, err
+	}
+	parts := make([]completedPart, 0)
+	size := int64(0)
+	for n > 0 {
+		etag, err := s.uploadPart(ctx, key, uploadID, len(parts)+1, part[:n])
+		if err != nil {
+			s.abortMultipartUpload(key, uploadID)
+			return Object{}, err
+		}
+		parts = append(parts, completedPart{PartNumber: len(parts) + 1, ETag: etag})
+		size += int64(n)
+		// the last part is shorter, and the read after it gives io.EOF with nothing read
+		n, err = io.ReadFull(r, part)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			s.abortMultipartUpload(key, uploadID)
+			return Object{}, err
+		}
+	}
+	etag, err := s.completeMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		s.abortMultipartUpload(key, uploadID)
+		return Object{}, err
+	}
+	return Object{Key: key, Size: size, ContentType: contentType, ModTime: s.now(), ETag: etag}, nil
+}
+
+// createMultipartUpload starts a multipart upload of key, and returns its ID.
+func (s *S3) createMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	req, err := s.request(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads the part number of a multipart upload, and returns its ETag.
+func (s *S3) uploadPart(ctx context.Context, key string, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	req, err := s.request(ctx, http.MethodPut, key, query, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	return res.Header.Get("ETag"), nil
+}
+
+// completeMultipartUpload assembles the parts of a multipart upload, and returns the ETag of
+// the object.
+func (s *S3) completeMultipartUpload(ctx context.Context, key string, uploadID string, parts []completedPart) (string, error) {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return "", err
+	}
+	req, err := s.request(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	// the object store may answer 200 and fail while assembling the parts
+	var result struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.XMLName.Local == "Error" {
+		return "", &S3Error{StatusCode: res.StatusCode, Code: result.Code, Message: result.Message}
+	}
+	return strings.Trim(result.ETag, `"`), nil
+}
+
+// abortMultipartUpload aborts a multipart upload, so that its parts are not kept by the object
+// store, even if the context of the upload is canceled.
+func (s *S3) abortMultipartUpload(key string, uploadID string) {
+	req, err := s.request(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return
+	}
+	if res, err := s.do(req); err == nil {
+		res.Body.Close()
+	}
+}
+
+// Get downloads the object stored under key. The returned reader is the body of the response.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	req, err := s.request(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	return res.Body, headerObject(key, res), nil
+}
+
+// Delete removes the object stored under key.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	req, err := s.request(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(req)
+	if err == ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Stat describes the object stored under key with a HEAD request.
+func (s *S3) Stat(ctx context.Context, key string) (Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return Object{}, err
+	}
+	req, err := s.request(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return Object{}, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return Object{}, err
+	}
+	res.Body.Close()
+	return headerObject(key, res), nil
+}
+
+// listBucketResult is the answer of ListObjectsV2.
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+	} `xml:"Contents"`
+}
+
+// List describes the objects whose key starts with prefix, following the pages of ListObjectsV2.
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, Object{
+				Key:     content.Key,
+				Size:    content.Size,
+				ModTime: content.LastModified,
+				ETag:    strings.Trim(content.ETag, `"`),
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+// SignedURL returns a presigned URL granting method on key, valid for expires (at most 7 days).
+func (s *S3) SignedURL(ctx context.Context, key string, method string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", fmt.Errorf("s3: presigned URLs must expire within 7 days, got %s", expires)
+	}
+	target, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", err
+	}
+	now := s.now().UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	target.RawQuery = canonicalQuery(query)
+	headers := http.Header{}
+	headers.Set("Host", target.Host)
+	signature := s.signature(now, strings.ToUpper(method), target, headers, []string{"host"}, s3UnsignedPayload)
+	target.RawQuery += "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
+
+// objectURL returns the path-style URL of key; an empty key gives the URL of the bucket.
+func (s *S3) objectURL(key string) string {
+	target := s.config.Endpoint + "/" + escapeKey(s.config.Bucket)
+	if key != "" {
+		target += "/" + escapeKey(key)
+	}
+	return target
+}
+
+// request creates a signed request for key.
+func (s *S3) request(ctx context.Context, method string, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	target := s.objectURL(key)
+	if len(query) > 0 {
+		target += "?" + canonicalQuery(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req)
+	return req, nil
+}
+
+// sign adds the Authorization header of AWS Signature Version 4 to req.
+func (s *S3) sign(req *http.Request) {
+	now := s.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	headers := req.Header.Clone()
+	headers.Set("Host", req.URL.Host)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	signature := s.signature(now, req.Method, req.URL, headers, signed, s3UnsignedPayload)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKey, s.scope(now), strings.Join(signed, ";"), signature))
+}
+
+// signature computes the Signature Version 4 of a request.
+func (s *S3) signature(now time.Time, method string, target *url.URL, headers http.Header, signed []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers.Get(name)) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		target.EscapedPath(),
+		canonicalQuery(target.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), now.Format(s3DateFormat))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format(s3DateFormat) + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// do sends req, turning 404 answers into ErrNotExist and other failures into *S3Error.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	res, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotExist
+	}
+	s3Err := &S3Error{StatusCode: res.StatusCode}
+	_ = xml.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(s3Err)
+	return nil, s3Err
+}
+
+func headerObject(key string, res *http.Response) Object {
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return Object{
+		Key:         key,
+		Size:        res.ContentLength,
+		ContentType: res.Header.Get("Content-Type"),
+		ModTime:     modTime,
+		ETag:        strings.Trim(res.Header.Get("ETag"), `"`),
+	}
+}
+
+// canonicalQuery encodes a query the way Signature Version 4 expects: sorted by key,
+// with spaces escaped as %20.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
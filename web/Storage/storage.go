@@ -0,0 +1,61 @@
+// Package Storage provides the backends uploaded files can be stored in:
+// the local filesystem, memory and S3-compatible object stores.
+package Storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotExist is returned when an object does not exist in the storage.
+	ErrNotExist = errors.New("object does not exist")
+	// ErrNotSupported is returned when a storage cannot perform an operation, e.g. sign URLs without a secret.
+	ErrNotSupported = errors.New("operation not supported by the storage")
+	// ErrInvalidKey is returned for keys that are empty, absolute or escape the storage with "..".
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType,omitempty"`
+	ModTime     time.Time `json:"modTime"`
+	ETag        string    `json:"etag,omitempty"`
+}
+
+// Storage is a backend objects can be streamed into and out of.
+// Keys are slash-separated paths such as "avatars/42.png".
+type Storage interface {
+	// Put stores the content of r under key. size is the length of the content, or -1 if unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (Object, error)
+	// Get opens the object stored under key. The returned reader must be closed.
+	// When the backend allows it, the reader also implements io.Seeker.
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Stat describes the object stored under key, or returns ErrNotExist.
+	Stat(ctx context.Context, key string) (Object, error)
+	// List describes the objects whose key starts with prefix, ordered by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignedURL returns a URL that grants method (e.g. GET) on key until expires has elapsed.
+	SignedURL(ctx context.Context, key string, method string, expires time.Duration) (string, error)
+}
+
+// CleanKey validates a key and returns it in canonical form, e.g. "a//b/./c" gives "a/b/c".
+// Keys that are empty, absolute or that would escape the storage with ".." are rejected with ErrInvalidKey.
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsRune(key, '\\') || strings.ContainsRune(key, 0) {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
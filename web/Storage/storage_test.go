@@ -0,0 +1,317 @@
+package Storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal stand-in for an S3-compatible server, checking the signature of every request.
+type fakeS3 struct {
+	signer  *S3
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	// uploads are the parts of the multipart uploads in progress, by upload ID
+	uploads map[string]map[int][]byte
+	// multipart counts the completed multipart uploads
+	multipart int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error>")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "bucket" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		type content struct {
+			Key  string `xml:"Key"`
+			Size int64  `xml:"Size"`
+		}
+		// one object per page, to exercise continuation tokens
+		keys := make([]string, 0)
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) && k > r.URL.Query().Get("continuation-token") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		result := struct {
+			XMLName               xml.Name  `xml:"ListBucketResult"`
+			IsTruncated           bool      `xml:"IsTruncated"`
+			NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+			Contents              []content `xml:"Contents"`
+		}{}
+		if len(keys) > 0 {
+			result.Contents = []content{{Key: keys[0], Size: int64(len(f.objects[keys[0]]))}}
+			result.IsTruncated = len(keys) > 1
+			if result.IsTruncated {
+				result.NextContinuationToken = keys[0]
+			}
+		}
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+		id := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[id] = map[int][]byte{}
+		f.types[key] = r.Header.Get("Content-Type")
+		io.WriteString(w, "<InitiateMultipartUploadResult><UploadId>"+id+"</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == http.MethodPut && r.URL.Query().Has("uploadId"):
+		number, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		f.uploads[r.URL.Query().Get("uploadId")][number] = data
+		w.Header().Set("ETag", `"part`+strconv.Itoa(number)+`"`)
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploadId"):
+		var complete struct {
+			Parts []completedPart `xml:"Part"`
+		}
+		xml.NewDecoder(r.Body).Decode(&complete)
+		parts := f.uploads[r.URL.Query().Get("uploadId")]
+		data := make([]byte, 0)
+		for i, part := range complete.Parts {
+			if part.PartNumber != i+1 || part.ETag != `"part`+strconv.Itoa(i+1)+`"` {
+				io.WriteString(w, "<Error><Code>InvalidPart</Code><Message>invalid part</Message></Error>")
+				return
+			}
+			data = append(data, parts[part.PartNumber]...)
+		}
+		delete(f.uploads, r.URL.Query().Get("uploadId"))
+		f.objects[key] = data
+		f.multipart++
+		io.WriteString(w, `<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && r.URL.Query().Has("uploadId"):
+		delete(f.uploads, r.URL.Query().Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		if r.ContentLength < 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		f.types[key] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verify recomputes the signature of a request, sent either in the Authorization header or presigned.
+func (f *fakeS3) verify(r *http.Request) bool {
+	target := *r.URL
+	query := target.Query()
+	now := f.signer.now().UTC()
+	headers := r.Header.Clone()
+	headers.Set("Host", r.Host)
+	if signature := query.Get("X-Amz-Signature"); signature != "" {
+		query.Del("X-Amz-Signature")
+		target.RawQuery = canonicalQuery(query)
+		return signature == f.signer.signature(now, r.Method, &target, headers, []string{"host"}, s3UnsignedPayload)
+	}
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	signature := f.signer.signature(now, r.Method, &target, headers, signed, s3UnsignedPayload)
+	return strings.HasSuffix(r.Header.Get("Authorization"), "Signature="+signature)
+}
+
+func newTestS3() (*S3, *httptest.Server) {
+	store, _, server := newFakeS3()
+	return store, server
+}
+
+func newFakeS3() (*S3, *fakeS3, *httptest.Server) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	config := S3Config{Bucket: "bucket", Region: "eu-west-1", AccessKey: "AKID", SecretKey: "secret"}
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}, uploads: map[string]map[int][]byte{}}
+	server := httptest.NewServer(fake)
+	config.Endpoint = server.URL
+	store := NewS3(config)
+	store.now = func() time.Time { return fixed }
+	fake.signer = store
+	return store, fake, server
+}
+
+// failingReader returns its content, then fails.
+type failingReader struct {
+	r io.Reader
+}
+
+func (f failingReader) Read(b []byte) (int, error) {
+	n, err := f.r.Read(b)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestS3MultipartUpload(t *testing.T) {
+	store, fake, server := newFakeS3()
+	defer server.Close()
+	// smaller than the parts accepted by S3, to keep the test fast
+	store.config.PartSize = 4
+	ctx := context.Background()
+	for _, content := range []string{"", "abc", "abcd", "abcdefghij", "abcdefgh"} {
+		object, err := store.Put(ctx, "big.txt", strings.NewReader(content), -1, "text/plain")
+		if err != nil {
+			t.Fatalf("%q: %v", content, err)
+		}
+		if object.Size != int64(len(content)) || string(fake.objects["big.txt"]) != content {
+			t.Errorf("%q: got %+v %q", content, object, fake.objects["big.txt"])
+		}
+	}
+	// the contents of one part or more only
+	if fake.multipart != 3 {
+		t.Errorf("expected 3 multipart uploads, got %d", fake.multipart)
+	}
+	if _, err := store.Put(ctx, "broken.txt", failingReader{strings.NewReader("abcdefghij")}, -1, ""); err == nil {
+		t.Error("expected the error of the reader")
+	}
+	if _, ok := fake.objects["broken.txt"]; ok || len(fake.uploads) != 0 {
+		t.Errorf("the failed upload was not aborted: %v", fake.uploads)
+	}
+}
+
+func TestStorages(t *testing.T) {
+	s3, server := newTestS3()
+	defer server.Close()
+	storages := map[string]Storage{
+		"memory": NewMemory(),
+		"local":  NewLocal(t.TempDir()),
+		"s3":     s3,
+	}
+	for name, store := range storages {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Put(ctx, "docs/a b.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+				t.Fatal(err)
+			}
+			// unknown size
+			if _, err := store.Put(ctx, "docs/b.txt", strings.NewReader("world!"), -1, "text/plain"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Put(ctx, "other.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, object, err := store.Get(ctx, "docs/a b.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(reader)
+			reader.Close()
+			if string(data) != "hello" || object.Size != 5 || !strings.HasPrefix(object.ContentType, "text/plain") {
+				t.Fatalf("got %q %+v", data, object)
+			}
+
+			object, err = store.Stat(ctx, "docs/b.txt")
+			if err != nil || object.Size != 6 {
+				t.Fatalf("stat: %+v %v", object, err)
+			}
+
+			objects, err := store.List(ctx, "docs/")
+			if err != nil || len(objects) != 2 || objects[0].Key != "docs/a b.txt" || objects[1].Key != "docs/b.txt" {
+				t.Fatalf("list: %+v %v", objects, err)
+			}
+
+			if err := store.Delete(ctx, "docs/a b.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Stat(ctx, "docs/a b.txt"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("expected ErrNotExist, got %v", err)
+			}
+			if _, _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("expected ErrNotExist, got %v", err)
+			}
+			if err := store.Delete(ctx, "missing"); err != nil {
+				t.Fatalf("deleting a missing object: %v", err)
+			}
+			if _, err := store.Put(ctx, "../escape", strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+				t.Fatalf("expected ErrInvalidKey, got %v", err)
+			}
+		})
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	store, server := newTestS3()
+	defer server.Close()
+	if _, err := store.Put(context.Background(), "photos/cat.png", strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := store.SignedURL(context.Background(), "photos/cat.png", http.MethodGet, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(data) != "png" {
+		t.Fatalf("got %d %q", res.StatusCode, data)
+	}
+
+	tampered := strings.Replace(signed, "cat.png", "dog.png", 1)
+	if res, err = http.Get(tampered); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("tampered URL: got %d", res.StatusCode)
+	}
+}
+
+func TestURLSigner(t *testing.T) {
+	store := NewMemory()
+	if _, err := store.SignedURL(context.Background(), "a.txt", http.MethodGet, time.Minute); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+	store.Signer = &URLSigner{BaseURL: "https://example.com/files/", Secret: []byte("secret")}
+	signed, err := store.SignedURL(context.Background(), "dir/a b.txt", http.MethodGet, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(signed)
+	if parsed.Path != "/files/dir/a b.txt" {
+		t.Fatalf("unexpected path %q", parsed.Path)
+	}
+	if err := store.Signer.Verify("dir/a b.txt", http.MethodGet, parsed.Query()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Signer.Verify("dir/other.txt", http.MethodGet, parsed.Query()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := store.Signer.Verify("dir/a b.txt", http.MethodPut, parsed.Query()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package Tus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Storage"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store for unknown uploads.
+var ErrNotFound = errors.New("upload not found")
+
+// Upload is the state of a resumable upload.
+type Upload struct {
+	// ID identifies the upload in its URL.
+	ID string `json:"id"`
+	// Size is the total length of the upload, in bytes (Upload-Length).
+	Size int64 `json:"size"`
+	// Offset is the number of bytes received so far (Upload-Offset).
+	Offset int64 `json:"offset"`
+	// Metadata are the key-value pairs sent by the client with Upload-Metadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Chunks are the storage keys of the chunks received so far, in order.
+	Chunks []string `json:"chunks,omitempty"`
+	// Key is the storage key of the assembled file, set once the upload is complete.
+	Key string `json:"key,omitempty"`
+	// CreatedAt is the time the upload was created.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsComplete reports whether all the bytes of the upload have been received.
+func (u Upload) IsComplete() bool {
+	return u.Offset == u.Size
+}
+
+// Store persists the state of uploads, so that they can be resumed after a restart
+// or served by several instances sharing the store.
+type Store interface {
+	// Create saves a new upload.
+	Create(ctx context.Context, upload Upload) error
+	// Get returns the upload of id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Upload, error)
+	// Update saves the new state of an existing upload.
+	Update(ctx context.Context, upload Upload) error
+	// Delete forgets the upload of id.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore is a Store keeping uploads in memory. Uploads do not survive a restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	uploads map[string]Upload
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{uploads: make(map[string]Upload)}
+}
+
+// Create saves a new upload.
+func (s *MemoryStore) Create(ctx context.Context, upload Upload) error {
+	s.mu.Lock()
+	s.uploads[upload.ID] = clone(upload)
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns the upload of id.
+func (s *MemoryStore) Get(ctx context.Context, id string) (Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	upload, ok := s.uploads[id]
+	if !ok {
+		return Upload{}, ErrNotFound
+	}
+	return clone(upload), nil
+}
+
+// Update saves the new state of an existing upload.
+func (s *MemoryStore) Update(ctx context.Context, upload Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[upload.ID]; !ok {
+		return ErrNotFound
+	}
+	s.uploads[upload.ID] = clone(upload)
+	return nil
+}
+
+// Delete forgets the upload of id.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.uploads, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// clone copies the slice of an upload, so that callers cannot modify the stored state.
+func clone(upload Upload) Upload {
+	upload.Chunks = append([]string(nil), upload.Chunks...)
+	return upload
+}
+
+// StorageStore is a Store keeping the state of every upload as a JSON object in a storage backend,
+// next to its chunks. Used with a shared backend such as S3, uploads can be resumed on any instance.
+type StorageStore struct {
+	// Storage is the backend the states are stored in.
+	Storage Storage.Storage
+	// Prefix is prepended to the keys of the states, e.g. "tus/".
+	Prefix string
+}
+
+// NewStorageStore creates a store keeping the states of uploads in store, under prefix.
+func NewStorageStore(store Storage.Storage, prefix string) *StorageStore {
+	return &StorageStore{Storage: store, Prefix: prefix}
+}
+
+// Create saves a new upload.
+func (s *StorageStore) Create(ctx context.Context, upload Upload) error {
+	return s.put(ctx, upload)
+}
+
+// Get returns the upload of id.
+func (s *StorageStore) Get(ctx context.Context, id string) (Upload, error) {
+	reader, _, err := s.Storage.Get(ctx, s.key(id))
+	if errors.Is(err, Storage.ErrNotExist) {
+		return Upload{}, ErrNotFound
+	}
+	if err != nil {
+		return Upload{}, err
+	}
+	defer reader.Close()
+	var upload Upload
+	if err := json.NewDecoder(reader).Decode(&upload); err != nil {
+		return Upload{}, err
+	}
+	return upload, nil
+}
+
+// Update saves the new state of an existing upload.
+func (s *StorageStore) Update(ctx context.Context, upload Upload) error {
+	return s.put(ctx, upload)
+}
+
+// Delete forgets the upload of id.
+func (s *StorageStore) Delete(ctx context.Context, id string) error {
+	return s.Storage.Delete(ctx, s.key(id))
+}
+
+func (s *StorageStore) put(ctx context.Context, upload Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	_, err = s.Storage.Put(ctx, s.key(upload.ID), bytes.NewReader(data), int64(len(data)), "application/json")
+	return err
+}
+
+func (s *StorageStore) key(id string) string {
+	return s.Prefix + id + ".info"
+}
@@ -0,0 +1,452 @@
+// Package Tus implements the server side of the tus 1.0 resumable upload protocol
+// (https://tus.io/protocols/resumable-upload), with the creation and termination extensions.
+// Chunks and assembled files are written to a Storage backend, and the state of the uploads
+// is persisted in a pluggable Store.
+package Tus
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Storage"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Version is the version of the protocol implemented by the handler.
+	Version = "1.0.0"
+	// Extensions are the extensions of the protocol supported by the handler.
+	Extensions = "creation,termination"
+	// ContentType is the content type of the PATCH requests.
+	ContentType = "application/offset+octet-stream"
+)
+
+// Config configures a Handler.
+type Config struct {
+	// Storage receives the chunks and the assembled files. It is required.
+	Storage Storage.Storage
+	// Store persists the state of the uploads. Nil means an in-memory store, which loses
+	// the uploads on restart; use NewStorageStore to keep them next to the chunks.
+	Store Store
+	// Prefix is prepended to the storage keys, e.g. "uploads/". The assembled file of an upload
+	// is stored under Prefix+ID and its chunks under Prefix+ID+".part/".
+	Prefix string
+	// MaxSize is the largest upload accepted, in bytes. Zero means no limit.
+	MaxSize int64
+	// OnComplete is called with the assembled file once all of its bytes have been received.
+	// The file can be read from Storage under upload.Key. An error answers the last PATCH
+	// request with 500 Internal Server Error; the assembled file is kept.
+	OnComplete func(ctx *context.Context, upload Upload) error
+}
+
+// Router is the part of a router group a Handler is mounted on.
+type Router interface {
+	Any(name string, handler web.Handler, middlewareHandler ...web.MiddlewareHandler)
+}
+
+// Handler serves the tus protocol.
+type Handler struct {
+	config Config
+	// locked holds the IDs of the uploads a request is working on, and only those, so that
+	// it does not grow with the uploads served.
+	mu     sync.Mutex
+	locked map[string]bool
+}
+
+// New creates a handler for the tus protocol.
+func New(config Config) *Handler {
+	if config.Storage == nil {
+		panic("[ERROR] tus: a storage is required")
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	return &Handler{config: config, locked: make(map[string]bool)}
+}
+
+// Mount binds the handler to path (the collection uploads are created in) and path+"/:id"
+// (the uploads themselves) of a router group.
+//
+// Example:
+//
+//	uploads := Tus.New(Tus.Config{Storage: Storage.NewLocal("./upload"), Prefix: "tus/"})
+//	uploads.Mount(engine.Router.NewGroup("api"), "/files")
+func (h *Handler) Mount(r Router, path string, middlewareHandler ...web.MiddlewareHandler) {
+	path = strings.TrimSuffix(path, "/")
+	r.Any(path, h.Serve, middlewareHandler...)
+	r.Any(path+"/:id", h.Serve, middlewareHandler...)
+}
+
+// Serve answers a tus request. The upload is identified by the "id" path parameter;
+// requests without it target the collection.
+func (h *Handler) Serve(ctx *context.Context) {
+	header := ctx.W.Header()
+	header.Set("Tus-Resumable", Version)
+	method := ctx.R.Method
+	if override := ctx.R.Header.Get("X-HTTP-Method-Override"); override != "" {
+		method = strings.ToUpper(override)
+	}
+	if method == http.MethodOptions {
+		h.options(ctx)
+		return
+	}
+	if ctx.R.Header.Get("Tus-Resumable") != Version {
+		header.Set("Tus-Version", Version)
+		http.Error(ctx.W, "unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+	id := ctx.Param("id")
+	switch {
+	case id == "" && method == http.MethodPost:
+		h.create(ctx)
+	case id != "" && method == http.MethodHead:
+		h.head(ctx, id)
+	case id != "" && method == http.MethodPatch:
+		h.patch(ctx, id)
+	case id != "" && method == http.MethodDelete:
+		h.terminate(ctx, id)
+	default:
+		http.Error(ctx.W, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// options describes the capabilities of the server.
+func (h *Handler) options(ctx *context.Context) {
+	header := ctx.W.Header()
+	header.Set("Tus-Version", Version)
+	header.Set("Tus-Extension", Extensions)
+	if h.config.MaxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(h.config.MaxSize, 10))
+	}
+	ctx.W.WriteHeader(http.StatusNoContent)
+}
+
+// create starts a new upload (creation extension).
+func (h *Handler) create(ctx *context.Context) {
+	size, err := strconv.ParseInt(ctx.R.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		http.Error(ctx.W, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if h.config.MaxSize > 0 && size > h.config.MaxSize {
+		http.Error(ctx.W, "upload exceeds Tus-Max-Size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := ParseMetadata(ctx.R.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(ctx.W, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	upload := Upload{ID: id, Size: size, Metadata: metadata, CreatedAt: time.Now()}
+	if err := h.config.Store.Create(ctx.R.Context(), upload); err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	if upload.IsComplete() {
+		if err := h.complete(ctx, &upload); err != nil {
+			h.fail(ctx, err)
+			return
+		}
+	}
+	ctx.W.Header().Set("Location", strings.TrimSuffix(ctx.R.URL.Path, "/")+"/"+id)
+	ctx.W.WriteHeader(http.StatusCreated)
+}
+
+// head reports the offset of an upload.
+func (h *Handler) head(ctx *context.Context, id string) {
+	header := ctx.W.Header()
+	header.Set("Cache-Control", "no-store")
+	upload, err := h.config.Store.Get(ctx.R.Context(), id)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	header.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		header.Set("Upload-Metadata", FormatMetadata(upload.Metadata))
+	}
+	ctx.W.WriteHeader(http.StatusOK)
+}
+
+// patch appends a chunk to an upload.
+func (h *Handler) patch(ctx *context.Context, id string) {
+	if ctx.R.Header.Get("Content-Type") != ContentType {
+		http.Error(ctx.W, "Content-Type must be "+ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.R.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(ctx.W, "invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	unlock, ok := h.lock(id)
+	if !ok {
+		http.Error(ctx.W, "upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer unlock()
+
+	upload, err := h.config.Store.Get(ctx.R.Context(), id)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	if offset != upload.Offset {
+		http.Error(ctx.W, "Upload-Offset does not match the offset of the upload", http.StatusConflict)
+		return
+	}
+	remaining := upload.Size - upload.Offset
+	if ctx.R.ContentLength > remaining {
+		http.Error(ctx.W, "chunk exceeds Upload-Length", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if remaining > 0 {
+		// the chunk is stored even if the client disconnects, so that the bytes received are
+		// not lost, hence a context that is not canceled with the request
+		body := &partialReader{r: io.LimitReader(ctx.R.Body, remaining)}
+		key := h.chunkKey(id, upload.Offset)
+		object, err := h.config.Storage.Put(stdcontext.Background(), key, body, -1, ContentType)
+		if err != nil {
+			h.fail(ctx, err)
+			return
+		}
+		if object.Size == 0 {
+			_ = h.config.Storage.Delete(stdcontext.Background(), key)
+		} else {
+			upload.Chunks = append(upload.Chunks, key)
+			upload.Offset += object.Size
+			if err := h.config.Store.Update(stdcontext.Background(), upload); err != nil {
+				h.fail(ctx, err)
+				return
+			}
+		}
+		if body.err != nil {
+			// the client is gone, it resumes from the stored offset
+			return
+		}
+	}
+
+	if upload.IsComplete() && upload.Key == "" {
+		if err := h.complete(ctx, &upload); err != nil {
+			h.fail(ctx, err)
+			return
+		}
+	}
+	ctx.W.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.W.WriteHeader(http.StatusNoContent)
+}
+
+// terminate deletes an upload and its data (termination extension).
+func (h *Handler) terminate(ctx *context.Context, id string) {
+	unlock, ok := h.lock(id)
+	if !ok {
+		http.Error(ctx.W, "upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer unlock()
+	upload, err := h.config.Store.Get(ctx.R.Context(), id)
+	if err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	for _, key := range upload.Chunks {
+		if err := h.config.Storage.Delete(ctx.R.Context(), key); err != nil {
+			h.fail(ctx, err)
+			return
+		}
+	}
+	if err := h.config.Storage.Delete(ctx.R.Context(), h.config.Prefix+id); err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	if err := h.config.Store.Delete(ctx.R.Context(), id); err != nil {
+		h.fail(ctx, err)
+		return
+	}
+	ctx.W.WriteHeader(http.StatusNoContent)
+}
+
+// complete assembles the chunks of an upload into a single file, then calls OnComplete.
+func (h *Handler) complete(ctx *context.Context, upload *Upload) error {
+	background := stdcontext.Background()
+	chunks := &chunkReader{ctx: background, storage: h.config.Storage, keys: upload.Chunks}
+	key := h.config.Prefix + upload.ID
+	_, err := h.config.Storage.Put(background, key, chunks, upload.Size, upload.Metadata["filetype"])
+	chunks.Close()
+	if err != nil {
+		return err
+	}
+	for _, chunk := range upload.Chunks {
+		if err := h.config.Storage.Delete(background, chunk); err != nil {
+			logger(ctx).Printf("[WARN] tus: cannot delete chunk %s: %v", chunk, err)
+		}
+	}
+	upload.Chunks = nil
+	upload.Key = key
+	if err := h.config.Store.Update(background, *upload); err != nil {
+		return err
+	}
+	if h.config.OnComplete != nil {
+		return h.config.OnComplete(ctx, *upload)
+	}
+	return nil
+}
+
+// fail answers 404 Not Found for unknown uploads and 500 Internal Server Error otherwise.
+func (h *Handler) fail(ctx *context.Context, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(ctx.W, "upload not found", http.StatusNotFound)
+		return
+	}
+	logger(ctx).Printf("[ERROR] tus: %s %s: %v", ctx.R.Method, ctx.R.URL.Path, err)
+	http.Error(ctx.W, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// lock prevents concurrent requests on the same upload within the process. The upload is
+// forgotten once unlocked.
+func (h *Handler) lock(id string) (func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.locked[id] {
+		return nil, false
+	}
+	h.locked[id] = true
+	return func() {
+		h.mu.Lock()
+		delete(h.locked, id)
+		h.mu.Unlock()
+	}, true
+}
+
+// logger returns the logger of the engine, or the standard logger outside of an engine.
+func logger(ctx *context.Context) *log.Logger {
+	if ctx.Logger == nil {
+		return log.Default()
+	}
+	return ctx.Logger
+}
+
+func (h *Handler) chunkKey(id string, offset int64) string {
+	return fmt.Sprintf("%s%s.part/%020d", h.config.Prefix, id, offset)
+}
+
+func newID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// ParseMetadata decodes an Upload-Metadata header: comma-separated pairs of a key
+// and a base64-encoded value, e.g. "filename d29ybGQudHh0,private".
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata: empty key")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata: value of %s is not base64", key)
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata, nil
+}
+
+// FormatMetadata encodes metadata as an Upload-Metadata header, with sorted keys.
+func FormatMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key
+		if metadata[key] != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(metadata[key]))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// partialReader ends at the first read error, keeping it aside, so that the bytes
+// received before a client disconnects can still be stored.
+type partialReader struct {
+	r   io.Reader
+	err error
+}
+
+func (p *partialReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if err != nil && err != io.EOF {
+		p.err = err
+		return n, io.EOF
+	}
+	return n, err
+}
+
+// chunkReader reads the chunks of an upload one after the other, opening them as they are reached.
+type chunkReader struct {
+	ctx     stdcontext.Context
+	storage Storage.Storage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (c *chunkReader) Read(b []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.keys) == 0 {
+				return 0, io.EOF
+			}
+			reader, _, err := c.storage.Get(c.ctx, c.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			c.current, c.keys = reader, c.keys[1:]
+		}
+		n, err := c.current.Read(b)
+		if err == io.EOF {
+			c.current.Close()
+			c.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.current != nil {
+		return c.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package Tus
+
+import (
+	stdcontext "context"
+	"github.com/Jerry20000730/Gjango/web"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func request(t *testing.T, engine http.Handler, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Tus-Resumable", Version)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestTus(t *testing.T) {
+	store := Storage.NewMemory()
+	var completed Upload
+	handler := New(Config{
+		Storage: store,
+		Store:   NewStorageStore(store, "state/"),
+		Prefix:  "tus/",
+		MaxSize: 100,
+		OnComplete: func(ctx *context.Context, upload Upload) error {
+			completed = upload
+			return nil
+		},
+	})
+	engine := web.NewEngine()
+	handler.Mount(engine.Router.NewGroup("api"), "/files")
+
+	w := request(t, engine, http.MethodOptions, "/api/files", "", nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("Tus-Extension") != Extensions || w.Header().Get("Tus-Max-Size") != "100" {
+		t.Fatalf("options: %d %v", w.Code, w.Header())
+	}
+	if w = request(t, engine, http.MethodPost, "/api/files", "", map[string]string{"Upload-Length": "101"}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+
+	w = request(t, engine, http.MethodPost, "/api/files", "", map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": FormatMetadata(map[string]string{"filename": "hello.txt", "filetype": "text/plain"}),
+	})
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusCreated || !strings.HasPrefix(location, "/api/files/") {
+		t.Fatalf("create: %d %q", w.Code, location)
+	}
+
+	patch := map[string]string{"Content-Type": ContentType, "Upload-Offset": "0"}
+	if w = request(t, engine, http.MethodPatch, location, "hello ", patch); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "6" {
+		t.Fatalf("patch: %d %v", w.Code, w.Header())
+	}
+	// a chunk sent again with a stale offset is rejected
+	if w = request(t, engine, http.MethodPatch, location, "hello ", patch); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+
+	w = request(t, engine, http.MethodHead, location, "", nil)
+	metadata, _ := ParseMetadata(w.Header().Get("Upload-Metadata"))
+	if w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "6" || w.Header().Get("Upload-Length") != "11" || metadata["filename"] != "hello.txt" {
+		t.Fatalf("head: %d %v", w.Code, w.Header())
+	}
+
+	patch["Upload-Offset"] = "6"
+	if w = request(t, engine, http.MethodPatch, location, "world", patch); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("patch: %d %v", w.Code, w.Header())
+	}
+	reader, object, err := store.Get(stdcontext.Background(), completed.Key)
+	if err != nil {
+		t.Fatalf("assembled file: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	if string(data) != "hello world" || object.ContentType != "text/plain" {
+		t.Fatalf("assembled file: %q %+v", data, object)
+	}
+	if chunks, _ := store.List(stdcontext.Background(), completed.Key+".part/"); len(chunks) != 0 {
+		t.Fatalf("chunks left behind: %v", chunks)
+	}
+
+	if w = request(t, engine, http.MethodDelete, location, "", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("terminate: %d", w.Code)
+	}
+	if w = request(t, engine, http.MethodHead, location, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after termination, got %d", w.Code)
+	}
+	if objects, _ := store.List(stdcontext.Background(), ""); len(objects) != 0 {
+		t.Fatalf("objects left behind: %v", objects)
+	}
+	if len(handler.locked) != 0 {
+		t.Fatalf("locks left behind: %v", handler.locked)
+	}
+}
+
+func TestTusLock(t *testing.T) {
+	handler := New(Config{Storage: Storage.NewMemory()})
+	engine := web.NewEngine()
+	handler.Mount(engine.Router.NewGroup("api"), "/files")
+	w := request(t, engine, http.MethodPost, "/api/files", "", map[string]string{"Upload-Length": "3"})
+	location := w.Header().Get("Location")
+	id := location[strings.LastIndex(location, "/")+1:]
+
+	unlock, ok := handler.lock(id)
+	if !ok {
+		t.Fatal("the upload should not be locked")
+	}
+	patch := map[string]string{"Content-Type": ContentType, "Upload-Offset": "0"}
+	if w = request(t, engine, http.MethodPatch, location, "abc", patch); w.Code != http.StatusLocked {
+		t.Fatalf("expected 423, got %d", w.Code)
+	}
+	if w = request(t, engine, http.MethodDelete, location, "", nil); w.Code != http.StatusLocked {
+		t.Fatalf("expected 423, got %d", w.Code)
+	}
+	unlock()
+	if w = request(t, engine, http.MethodPatch, location, "abc", patch); w.Code != http.StatusNoContent {
+		t.Fatalf("patch: %d", w.Code)
+	}
+	if len(handler.locked) != 0 {
+		t.Fatalf("locks left behind: %v", handler.locked)
+	}
+}
+
+func TestTusPreconditions(t *testing.T) {
+	engine := web.NewEngine()
+	New(Config{Storage: Storage.NewMemory()}).Mount(engine.Router.NewGroup("api"), "/files")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/files", nil)
+	r.Header.Set("Upload-Length", "1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed || w.Header().Get("Tus-Version") != Version {
+		t.Fatalf("expected 412, got %d", w.Code)
+	}
+
+	w = request(t, engine, http.MethodPost, "/api/files", "", map[string]string{"Upload-Length": "3"})
+	location := w.Header().Get("Location")
+	if w = request(t, engine, http.MethodPatch, location, "abc", map[string]string{"Upload-Offset": "0"}); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", w.Code)
+	}
+	if w = request(t, engine, http.MethodPatch, location, "abcd", map[string]string{"Content-Type": ContentType, "Upload-Offset": "0"}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+	if w = request(t, engine, http.MethodPatch, "/api/files/unknown", "a", map[string]string{"Content-Type": ContentType, "Upload-Offset": "0"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
@@ -1,8 +1,10 @@
 package Utils
 
 import (
+	"path/filepath"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 func SubStringLast(str string, substr string) string {
@@ -21,3 +23,35 @@ func IsASCII(s string) bool {
 	}
 	return true
 }
+
+// SanitizeFilename turns a file name sent by a client into a name that is safe to use on the
+// local filesystem: directories ("../../etc/x", "C:\x") are dropped, control characters and
+// characters reserved on common filesystems are replaced by "_", leading dots are removed and the
+// name is cut to 255 bytes. If nothing usable is left, "file" is returned.
+func SanitizeFilename(name string) string {
+	// clients may send Windows paths, whatever the server OS is
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = name[:255-len(ext)]
+		// do not cut a multi-byte character in half
+		for !utf8.ValidString(name) {
+			name = name[:len(name)-1]
+		}
+		name += ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
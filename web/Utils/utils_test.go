@@ -0,0 +1,44 @@
+package Utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := map[string]string{
+		"report.pdf":             "report.pdf",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\jo\report.pdf`: "report.pdf",
+		"dir/":                   "file",
+		"":                       "file",
+		"..":                     "file",
+		"...":                    "file",
+		".bashrc":                "bashrc",
+		"  name.txt  ":           "name.txt",
+		"a\x00b\nc.txt":          "a_b_c.txt",
+		`a<b>c:d"e|f?g*.txt`:     "a_b_c_d_e_f_g_.txt",
+		"résumé.pdf":             "résumé.pdf",
+	}
+	for name, expected := range cases {
+		if sanitized := SanitizeFilename(name); sanitized != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, sanitized)
+		}
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	long := SanitizeFilename(strings.Repeat("a", 300) + ".txt")
+	if len(long) != 255 || !strings.HasSuffix(long, ".txt") {
+		t.Errorf("expected 255 bytes ending with .txt, got %d bytes %q", len(long), long)
+	}
+	// a too long extension is not kept
+	if ext := SanitizeFilename("a." + strings.Repeat("x", 300)); len(ext) != 255 {
+		t.Errorf("expected 255 bytes, got %d", len(ext))
+	}
+	multibyte := SanitizeFilename(strings.Repeat("é", 200) + ".txt")
+	if len(multibyte) > 255 || !utf8.ValidString(multibyte) || !strings.HasSuffix(multibyte, ".txt") {
+		t.Errorf("expected a valid name of at most 255 bytes, got %d bytes %q", len(multibyte), multibyte)
+	}
+}
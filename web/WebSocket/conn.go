@@ -0,0 +1,440 @@
+// Package WebSocket implements the WebSocket protocol (RFC 6455) on top of the standard library:
+// the opening handshake of servers (Upgrade) and clients (Dial), and connections exchanging
+// text and binary messages with ping/pong, close frames and fragmentation.
+package WebSocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, the opcodes of RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes of RFC 6455.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finalBit     = 0x80
+	reservedMask = 0x70
+	opcodeMask   = 0x0f
+	maskBit      = 0x80
+
+	maxControlPayload = 125
+)
+
+var (
+	// ErrReadLimit is returned when a message exceeds the read limit of the connection.
+	ErrReadLimit = errors.New("websocket: message exceeds the read limit")
+	// ErrClosed is returned when writing to a connection that has sent a close frame.
+	ErrClosed = errors.New("websocket: connection closed")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Text)
+}
+
+// IsCloseError reports whether err is a CloseError with one of the given codes.
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a WebSocket connection. Reads must be made from a single goroutine,
+// while writes may be made from several goroutines at once.
+type Conn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	isServer bool
+
+	subprotocol string
+	readLimit   int64
+
+	pingHandler func(data string) error
+	pongHandler func(data string) error
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, reader *bufio.Reader, isServer bool, readLimit int64) *Conn {
+	c := &Conn{conn: conn, reader: reader, isServer: isServer, readLimit: readLimit}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	return c
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake, if any.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the network address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the largest message accepted, in bytes. A larger message closes the
+// connection with CloseMessageTooBig and makes ReadMessage fail with ErrReadLimit. Zero means no limit.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline of the reads of the connection, see net.Conn.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline of the writes of the connection, see net.Conn.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler sets the function called with the payload of the pings received by ReadMessage.
+// The default handler answers with a pong. Nil restores the default handler.
+func (c *Conn) SetPingHandler(handler func(data string) error) {
+	if handler == nil {
+		handler = func(data string) error {
+			err := c.WriteControl(PongMessage, []byte(data))
+			if errors.Is(err, ErrClosed) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.pingHandler = handler
+}
+
+// SetPongHandler sets the function called with the payload of the pongs received by ReadMessage,
+// e.g. to extend a read deadline. Nil restores the default handler, which does nothing.
+func (c *Conn) SetPongHandler(handler func(data string) error) {
+	if handler == nil {
+		handler = func(string) error { return nil }
+	}
+	c.pongHandler = handler
+}
+
+// ReadMessage reads the next text or binary message, reassembling fragmented messages.
+// Pings, pongs and close frames received meanwhile are handled: pings are answered,
+// and a close frame is answered and returned as a *CloseError.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		final, opcode, payload, err := c.readFrame(int64(len(data)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message before the end of a fragmented message")
+			}
+			messageType, data = opcode, payload
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "continuation frame without a message")
+			}
+			data = append(data, payload...)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+		if final {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in text message")
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+// WriteMessage sends a text or binary message in a single frame.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// WriteFragmented sends a text or binary message split into frames of at most fragmentSize bytes,
+// e.g. to interleave pings with a large message.
+func (c *Conn) WriteFragmented(messageType int, data []byte, fragmentSize int) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	if fragmentSize <= 0 {
+		return c.writeFrame(true, messageType, data)
+	}
+	opcode := messageType
+	for {
+		fragment := data
+		if len(fragment) > fragmentSize {
+			fragment = data[:fragmentSize]
+		}
+		data = data[len(fragment):]
+		if err := c.writeFrame(len(data) == 0, opcode, fragment); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		opcode = continuationFrame
+	}
+}
+
+// WriteControl sends a ping, pong or close frame. Control payloads are limited to 125 bytes.
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != PingMessage && messageType != PongMessage && messageType != CloseMessage {
+		return fmt.Errorf("websocket: invalid control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control payload exceeds 125 bytes")
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// Ping sends a ping, answered by the peer with a pong (see SetPongHandler).
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data)
+}
+
+// WriteClose starts the closing handshake by sending a close frame. The connection should then
+// be read until ReadMessage returns the *CloseError of the peer's answer, and be closed.
+func (c *Conn) WriteClose(code int, reason string) error {
+	return c.WriteControl(CloseMessage, closePayload(code, reason))
+}
+
+// Close sends a normal close frame, unless one has been sent already, and closes the connection.
+func (c *Conn) Close() error {
+	err := c.WriteClose(CloseNormalClosure, "")
+	if errors.Is(err, ErrClosed) {
+		err = nil
+	}
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// handleClose answers a close frame and returns it as a *CloseError.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in close reason")
+		}
+	}
+	answer := []byte{}
+	if closeErr.Code != CloseNoStatusReceived {
+		answer = closePayload(closeErr.Code, "")
+	}
+	if err := c.WriteControl(CloseMessage, answer); err != nil && !errors.Is(err, ErrClosed) {
+		return err
+	}
+	return closeErr
+}
+
+// fail closes the connection after a protocol violation of the peer.
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.WriteClose(code, reason)
+	_ = c.conn.Close()
+	if code == CloseMessageTooBig {
+		return ErrReadLimit
+	}
+	return &CloseError{Code: code, Text: reason}
+}
+
+// readFrame reads a frame. buffered is the length of the fragments of the message read so far,
+// counted against the read limit.
+func (c *Conn) readFrame(buffered int64) (final bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	final = header[0]&finalBit != 0
+	opcode = int(header[0] & opcodeMask)
+	if header[0]&reservedMask != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set without a negotiated extension")
+	}
+	masked := header[1]&maskBit != 0
+	if masked != c.isServer {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid masking")
+	}
+
+	length := int64(header[1] &^ maskBit)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		if extended[0]&0x80 != 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid frame length")
+		}
+		length = int64(binary.BigEndian.Uint64(extended[:]))
+	}
+
+	if opcode >= CloseMessage {
+		if !final || length > maxControlPayload {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+		}
+	} else if c.readLimit > 0 && buffered+length > c.readLimit {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return final, opcode, payload, nil
+}
+
+// writeFrame sends a frame, masked when the connection is a client.
+func (c *Conn) writeFrame(final bool, opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	frame := make([]byte, 0, len(payload)+14)
+	first := byte(opcode)
+	if final {
+		first |= finalBit
+	}
+	frame = append(frame, first)
+	var maskFlag byte
+	if !c.isServer {
+		maskFlag = maskBit
+	}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, maskFlag|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskFlag|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskFlag|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	start := len(frame)
+	if !c.isServer {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start += 4
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	if _, err := c.conn.Write(frame); err != nil {
+		return err
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	return nil
+}
+
+func maskBytes(mask [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= mask[i&3]
+	}
+}
+
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(payload, reason...)
+}
+
+// validCloseCode reports whether a close code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
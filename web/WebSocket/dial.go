@@ -0,0 +1,110 @@
+package WebSocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrBadHandshake is returned by Dial when the server does not accept the upgrade.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Dial opens a client connection to a ws:// or wss:// URL, e.g. to test a server or to talk
+// to another service.
+//
+// Parameters:
+//   - ctx: Bounds the connection and the handshake.
+//   - rawURL: The URL of the server.
+//   - header: Extra headers of the handshake request, e.g. Origin or Sec-WebSocket-Protocol.
+//
+// Returns:
+//   - *Conn: The WebSocket connection, with no read limit. It must be closed by the caller.
+//   - *http.Response: The handshake response, also returned with ErrBadHandshake.
+//   - error: ErrBadHandshake or the error of the connection.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	address := target.Host
+	if target.Port() == "" {
+		if target.Scheme == "wss" {
+			address = net.JoinHostPort(target.Hostname(), "443")
+		} else {
+			address = net.JoinHostPort(target.Hostname(), "80")
+		}
+	}
+
+	var dialer net.Dialer
+	var netConn net.Conn
+	switch target.Scheme {
+	case "ws":
+		netConn, err = dialer.DialContext(ctx, "tcp", address)
+	case "wss":
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: &tls.Config{ServerName: target.Hostname()}}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported scheme %q", target.Scheme)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(random)
+	target.Scheme = strings.Replace(target.Scheme, "ws", "http", 1)
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        target,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       target.Host,
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(netConn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContains(res.Header, "Upgrade", "websocket") ||
+		res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, res, ErrBadHandshake
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	conn := newConn(netConn, reader, false, 0)
+	conn.subprotocol = res.Header.Get("Sec-WebSocket-Protocol")
+	return conn, res, nil
+}
@@ -0,0 +1,167 @@
+package WebSocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// acceptGUID is the GUID of RFC 6455 the Sec-WebSocket-Accept header is derived with.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DEFAULT_READ_LIMIT is the read limit of the connections upgraded without Options.ReadLimit.
+const DEFAULT_READ_LIMIT = 1 << 20 // 1 MB
+
+// Options configures the upgrade of a request.
+type Options struct {
+	// Subprotocols are the subprotocols supported by the server, in order of preference.
+	// The first one requested by the client is selected.
+	Subprotocols []string
+	// CheckOrigin reports whether the Origin of the request is allowed. Nil allows requests
+	// without an Origin header and requests whose Origin matches the Host header, protecting
+	// against cross-site WebSocket hijacking.
+	CheckOrigin func(r *http.Request) bool
+	// ReadLimit is the largest message accepted, in bytes. Zero means DEFAULT_READ_LIMIT,
+	// and a negative value no limit.
+	ReadLimit int64
+	// Header are extra headers of the handshake response, e.g. Set-Cookie.
+	Header http.Header
+}
+
+// HandshakeError is returned by Upgrade when the request is not a valid WebSocket handshake.
+// The matching HTTP error has already been answered.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// Upgrade performs the opening handshake of a WebSocket request, taking over the connection
+// of the response with http.Hijacker. On failure, the HTTP error has already been answered.
+//
+// Parameters:
+//   - w: The response writer of the request, which must implement http.Hijacker.
+//   - r: The handshake request.
+//   - options: How the connection is negotiated; nil means the default options.
+//
+// Returns:
+//   - *Conn: The WebSocket connection. It must be closed by the caller.
+//   - error: A *HandshakeError, or the error of the hijacking.
+func Upgrade(w http.ResponseWriter, r *http.Request, options *Options) (*Conn, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "the handshake must be a GET request")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(w, http.StatusBadRequest, "the request does not ask for a websocket upgrade")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "unsupported Sec-WebSocket-Version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, handshakeError(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := options.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, handshakeError(w, http.StatusForbidden, "origin not allowed")
+	}
+	subprotocol := selectSubprotocol(r, options.Subprotocols)
+
+	netConn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, handshakeError(w, http.StatusInternalServerError, "the connection cannot be hijacked")
+	}
+
+	var response strings.Builder
+	response.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	response.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		response.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	for name, values := range options.Header {
+		for _, value := range values {
+			response.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+	response.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(response.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	readLimit := options.ReadLimit
+	if readLimit == 0 {
+		readLimit = DEFAULT_READ_LIMIT
+	} else if readLimit < 0 {
+		readLimit = 0
+	}
+	conn := newConn(netConn, buffered.Reader, true, readLimit)
+	conn.subprotocol = subprotocol
+	return conn, nil
+}
+
+// IsUpgradeRequest reports whether r asks for a WebSocket upgrade.
+func IsUpgradeRequest(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+func handshakeError(w http.ResponseWriter, status int, message string) error {
+	http.Error(w, message, status)
+	return &HandshakeError{Status: status, Message: message}
+}
+
+// acceptKey computes the Sec-WebSocket-Accept header answering a Sec-WebSocket-Key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains reports whether a comma-separated header contains token, ignoring case.
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, element := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(element), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(parsed.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, requested := range strings.Split(value, ",") {
+			requested = strings.TrimSpace(requested)
+			for _, protocol := range supported {
+				if protocol == requested {
+					return protocol
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package WebSocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer echoes every message back, until the client closes the connection.
+func echoServer(t *testing.T, options *Options) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, options)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}))
+}
+
+func dial(t *testing.T, server *httptest.Server, header http.Header) *Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestEcho(t *testing.T) {
+	server := echoServer(t, &Options{Subprotocols: []string{"chat"}})
+	defer server.Close()
+	conn := dial(t, server, http.Header{"Sec-WebSocket-Protocol": {"other, chat"}})
+	if conn.Subprotocol() != "chat" {
+		t.Fatalf("expected the chat subprotocol, got %q", conn.Subprotocol())
+	}
+
+	if err := conn.WriteMessage(TextMessage, []byte("héllo")); err != nil {
+		t.Fatal(err)
+	}
+	if messageType, data, err := conn.ReadMessage(); err != nil || messageType != TextMessage || string(data) != "héllo" {
+		t.Fatalf("got %d %q %v", messageType, data, err)
+	}
+
+	// a large binary message, fragmented, with a ping between the fragments
+	large := bytes.Repeat([]byte{1, 2, 3}, 50000)
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	if err := conn.writeFrame(false, BinaryMessage, large[:70000]); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Ping([]byte("are you there")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.writeFrame(true, continuationFrame, large[70000:]); err != nil {
+		t.Fatal(err)
+	}
+	if messageType, data, err := conn.ReadMessage(); err != nil || messageType != BinaryMessage || !bytes.Equal(data, large) {
+		t.Fatalf("got %d %d bytes %v", messageType, len(data), err)
+	}
+	if err := conn.WriteFragmented(TextMessage, []byte("fragmented message"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "fragmented message" {
+		t.Fatalf("got %q %v", data, err)
+	}
+	if data := <-pong; data != "are you there" {
+		t.Fatalf("unexpected pong %q", data)
+	}
+
+	// closing handshake
+	if err := conn.WriteClose(CloseNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseNormalClosure) {
+		t.Fatalf("expected a normal close, got %v", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestReadLimit(t *testing.T) {
+	server := echoServer(t, &Options{ReadLimit: 10})
+	defer server.Close()
+	conn := dial(t, server, nil)
+	defer conn.Close()
+	_ = conn.WriteMessage(TextMessage, []byte("short"))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "short" {
+		t.Fatalf("got %q %v", data, err)
+	}
+	// the limit applies to the whole message, not to every fragment
+	_ = conn.WriteFragmented(TextMessage, []byte("far too long"), 4)
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("expected close code %d, got %v", CloseMessageTooBig, err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	server := echoServer(t, nil)
+	defer server.Close()
+
+	// clients must mask their frames
+	conn := dial(t, server, nil)
+	conn.isServer = true
+	_ = conn.WriteMessage(TextMessage, []byte("unmasked"))
+	conn.isServer = false
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseProtocolError) {
+		t.Fatalf("expected close code %d, got %v", CloseProtocolError, err)
+	}
+	conn.Close()
+
+	// text messages must be valid UTF-8
+	conn = dial(t, server, nil)
+	_ = conn.WriteMessage(TextMessage, []byte{0xff, 0xfe})
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseInvalidFramePayloadData) {
+		t.Fatalf("expected close code %d, got %v", CloseInvalidFramePayloadData, err)
+	}
+	conn.Close()
+}
+
+func TestHandshake(t *testing.T) {
+	server := echoServer(t, nil)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// cross-origin requests are rejected by default
+	_, res, err := Dial(context.Background(), url, http.Header{"Origin": {"https://evil.example"}})
+	if !errors.Is(err, ErrBadHandshake) || res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a forbidden handshake, got %v", err)
+	}
+
+	// plain HTTP requests are not upgraded
+	plain, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Body.Close()
+	if plain.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", plain.StatusCode)
+	}
+
+	if key := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", key)
+	}
+}
@@ -0,0 +1,418 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/OpenAPI"
+	"github.com/Jerry20000730/Gjango/web/Schema"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OpenAPIValidation configures ValidateOpenAPI.
+type OpenAPIValidation struct {
+	// BasePath is the path the paths of the document are relative to, e.g. "/api"; the path of
+	// the URL of the first server of the document by default.
+	BasePath string
+	// Body caches the request body, so that the handler can bind it after its validation;
+	// see context.CacheBody. A Body.MaxSize of zero means Constant.DEFAULT_VALIDATED_BODY_MAX_SIZE,
+	// and a negative one no limit; larger bodies are answered with 413 Request Entity Too Large.
+	Body context.BodyCacheConfig
+	// ValidateResponses validates the responses as well, e.g. in tests. The responses are
+	// buffered, and those not matching the document are replaced by a 500 Internal Server Error
+	// listing the mismatches; streaming responses are not supported.
+	ValidateResponses bool
+}
+
+// ValidateOpenAPI returns a middleware validating the requests against doc, a contract-first
+// OpenAPI 3 document (see OpenAPI.LoadFile): the path parameters, the query parameters, the
+// headers and the cookies of the operation matching the request, and its JSON body.
+//
+// Requests not matching the document are answered by the ErrorHandler of the engine with a
+// 400 Bad Request HTTPError listing every mismatch in Errors, e.g.
+// {"code": "validation_failed", "message": "...", "errors": [{"code": "validate.min",
+// "field": "page", "message": "field [page] must be at least 1"}]}, and bodies of a media type
+// the operation does not accept with 415 Unsupported Media Type. The paths and the methods the
+// document does not describe are left to the router.
+//
+// Example:
+//
+//	doc, err := OpenAPI.LoadFile("openapi.json")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	g := engine.Router.NewGroup("api")
+//	g.MiddlewareRegister(web.ValidateOpenAPI(doc, web.OpenAPIValidation{BasePath: "/api"}))
+func ValidateOpenAPI(doc *OpenAPI.Document, options OpenAPIValidation) MiddlewareHandler {
+	matcher := OpenAPI.NewMatcher(doc)
+	basePath := strings.TrimSuffix(options.BasePath, "/")
+	if basePath == "" {
+		basePath = doc.BasePath()
+	}
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			path, ok := strings.CutPrefix(ctx.R.URL.EscapedPath(), basePath)
+			if !ok {
+				next(ctx)
+				return
+			}
+			if path == "" {
+				path = "/"
+			}
+			_, item, params := matcher.Match(path)
+			if item == nil || item.Operation(ctx.R.Method) == nil {
+				next(ctx)
+				return
+			}
+			operation := item.Operation(ctx.R.Method)
+			if err := validateRequest(ctx, doc, item, operation, params, options); err != nil {
+				ctx.HandleError(err)
+				return
+			}
+			if !options.ValidateResponses {
+				next(ctx)
+				return
+			}
+			recorder := &responseRecorder{ResponseWriter: ctx.W}
+			ctx.W = recorder
+			// the writer of the response is given back even if the handler panics, so that the
+			// panic can be answered, e.g. by Recovery
+			defer func() { ctx.W = recorder.ResponseWriter }()
+			next(ctx)
+			ctx.W = recorder.ResponseWriter
+			if errs := validateResponse(doc, operation, recorder); len(errs) > 0 {
+				ctx.HandleError(&HTTPError{
+					Status:  http.StatusInternalServerError,
+					Code:    "response_mismatch",
+					Message: "the response does not match the specification",
+					Err:     errors.Join(errs...),
+					Errors:  errs,
+				})
+				return
+			}
+			recorder.flush()
+		}
+	}
+}
+
+// validateRequest validates the parameters and the body of a request against its operation.
+func validateRequest(ctx *context.Context, doc *OpenAPI.Document, item *OpenAPI.PathItem, operation *OpenAPI.Operation, pathParams map[string]string, options OpenAPIValidation) error {
+	errs := make([]error, 0)
+	for _, p := range parameters(item, operation) {
+		parameter, err := doc.ResolveParameter(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values, present := parameterValues(ctx, parameter, pathParams)
+		if !present {
+			if parameter.Required || parameter.In == "path" {
+				errs = append(errs, I18n.NewError(I18n.PARAM_MISSING, map[string]any{"field": parameter.Name}))
+			}
+			continue
+		}
+		value, err := parameterValue(doc, parameter, values)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if parameter.Schema != nil {
+			if err := parameter.Schema.ValidateAt(value, parameter.Name, doc.Resolve); err != nil {
+				errs = append(errs, unjoin(err)...)
+			}
+		}
+	}
+
+	body, err := doc.ResolveRequestBody(operation.RequestBody)
+	if err != nil {
+		errs = append(errs, err)
+	} else if body != nil {
+		bodyErrs, err := validateRequestBody(ctx, doc, body, options)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, bodyErrs...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return validationFailed(ctx, "the request does not match the specification", errs)
+}
+
+// validationFailed returns the 400 Bad Request HTTPError of a request failing the checks of
+// errs, localized in the locale of the request.
+func validationFailed(ctx *context.Context, message string, errs []error) *HTTPError {
+	for i, err := range errs {
+		errs[i] = ctx.LocalizeError(err)
+	}
+	return &HTTPError{
+		Status:  http.StatusBadRequest,
+		Code:    "validation_failed",
+		Message: message,
+		Err:     errors.Join(errs...),
+		Errors:  errs,
+	}
+}
+
+// validateRequestBody validates the body of a request. The violations of the schema of the
+// body are returned as errs, and the errors failing the request right away as err.
+func validateRequestBody(ctx *context.Context, doc *OpenAPI.Document, body *OpenAPI.RequestBody, options OpenAPIValidation) (errs []error, err error) {
+	if err := ctx.CacheBody(validatedBody(options.Body)); err != nil {
+		return nil, err
+	}
+	data, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		if body.Required {
+			return []error{I18n.NewError(I18n.VALIDATE_REQUIRED, map[string]any{"field": "body"})}, nil
+		}
+		return nil, nil
+	}
+	contentType := ctx.R.Header.Get("Content-Type")
+	media, mediaType := findMediaType(body.Content, contentType)
+	if media == nil {
+		accepted := make([]string, 0, len(body.Content))
+		for mediaType := range body.Content {
+			accepted = append(accepted, mediaType)
+		}
+		return nil, NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"unsupported Content-Type "+contentType+", expected "+strings.Join(accepted, ", "))
+	}
+	if media.Schema == nil || !isJSON(mediaType) {
+		return nil, nil
+	}
+	value, err := Schema.Decode(data)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, "bad_request", "malformed request body").Wrap(err)
+	}
+	if err := media.Schema.ValidateAt(value, "", doc.Resolve); err != nil {
+		return unjoin(err), nil
+	}
+	return nil, nil
+}
+
+// validateResponse validates a buffered response against the responses of its operation.
+func validateResponse(doc *OpenAPI.Document, operation *OpenAPI.Operation, recorder *responseRecorder) []error {
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	r := operation.Responses[strconv.Itoa(status)]
+	if r == nil {
+		r = operation.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if r == nil {
+		r = operation.Responses["default"]
+	}
+	if r == nil {
+		return []error{errors.New("status " + strconv.Itoa(status) + " is not documented")}
+	}
+	response, err := doc.ResolveResponse(r)
+	if err != nil {
+		return []error{err}
+	}
+	if recorder.body.Len() == 0 {
+		return nil
+	}
+	contentType := recorder.Header().Get("Content-Type")
+	media, mediaType := findMediaType(response.Content, contentType)
+	if media == nil {
+		return []error{errors.New("Content-Type " + contentType + " is not documented for status " + strconv.Itoa(status))}
+	}
+	if media.Schema == nil || !isJSON(mediaType) {
+		return nil
+	}
+	value, err := Schema.Decode(recorder.body.Bytes())
+	if err != nil {
+		return []error{err}
+	}
+	if err := media.Schema.ValidateAt(value, "", doc.Resolve); err != nil {
+		return unjoin(err)
+	}
+	return nil
+}
+
+// parameters returns the parameters of an operation, those of the path item included
+// unless the operation overrides them.
+func parameters(item *OpenAPI.PathItem, operation *OpenAPI.Operation) []*OpenAPI.Parameter {
+	all := make([]*OpenAPI.Parameter, 0, len(item.Parameters)+len(operation.Parameters))
+	all = append(all, operation.Parameters...)
+	for _, shared := range item.Parameters {
+		overridden := false
+		for _, p := range operation.Parameters {
+			if p.Ref == "" && p.Name == shared.Name && p.In == shared.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			all = append(all, shared)
+		}
+	}
+	return all
+}
+
+// parameterValues returns the raw values of a parameter in the request, and whether it is present.
+func parameterValues(ctx *context.Context, parameter *OpenAPI.Parameter, pathParams map[string]string) ([]string, bool) {
+	var values []string
+	switch parameter.In {
+	case "path":
+		if value, ok := pathParams[parameter.Name]; ok {
+			values = []string{value}
+		}
+	case "query":
+		values = ctx.R.URL.Query()[parameter.Name]
+	case "header":
+		values = ctx.R.Header.Values(parameter.Name)
+	case "cookie":
+		if cookie, err := ctx.R.Cookie(parameter.Name); err == nil {
+			values = []string{cookie.Value}
+		}
+	}
+	return values, len(values) > 0 && (len(values) > 1 || values[0] != "" || parameter.In == "query")
+}
+
+// parameterValue converts the raw values of a parameter to the JSON value its schema
+// describes, e.g. the number 2 for "?page=2", or a list for an array.
+func parameterValue(doc *OpenAPI.Document, parameter *OpenAPI.Parameter, values []string) (any, error) {
+	s := parameter.Schema
+	for s != nil && s.Ref != "" {
+		resolved, err := doc.Resolve(s.Ref)
+		if err != nil {
+			return nil, err
+		}
+		s = resolved
+	}
+	if s == nil {
+		return values[0], nil
+	}
+	if s.Type.Has("array") {
+		exploded := parameter.In == "query" || parameter.In == "cookie"
+		if parameter.Explode != nil {
+			exploded = *parameter.Explode
+		}
+		if !exploded || parameter.Style == "simple" {
+			values = strings.Split(strings.Join(values, ","), ",")
+		}
+		items := make([]any, len(values))
+		for i, value := range values {
+			item, err := scalarValue(parameter.Name, s.Items, value)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return scalarValue(parameter.Name, s, values[0])
+}
+
+// scalarValue converts a raw value to the number, the boolean or the string of schema s.
+func scalarValue(name string, s *Schema.Schema, value string) (any, error) {
+	if s == nil {
+		return value, nil
+	}
+	invalid := func(expected string) error {
+		return I18n.NewError(I18n.PARAM_INVALID, map[string]any{"field": name, "expected": expected, "value": value})
+	}
+	switch {
+	case s.Type.Has("integer"):
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, invalid("integer")
+		}
+		return json.Number(value), nil
+	case s.Type.Has("number"):
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, invalid("number")
+		}
+		return json.Number(value), nil
+	case s.Type.Has("boolean"):
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalid("boolean")
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// findMediaType returns the media type of content matching contentType, trying the exact
+// media type, then its range (e.g. "application/*"), then "*/*".
+func findMediaType(content map[string]*OpenAPI.MediaType, contentType string) (*OpenAPI.MediaType, string) {
+	if len(content) == 0 {
+		return &OpenAPI.MediaType{}, ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ""
+	}
+	candidates := []string{mediaType, mediaType[:strings.IndexByte(mediaType, '/')+1] + "*", "*/*"}
+	for _, candidate := range candidates {
+		for key, media := range content {
+			if k, _, err := mime.ParseMediaType(key); err == nil && k == candidate {
+				return media, mediaType
+			}
+		}
+	}
+	return nil, ""
+}
+
+// isJSON reports whether mediaType is JSON, e.g. application/json or application/problem+json.
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// validatedBody returns the configuration caching a body to validate it, config with a
+// bounded size unless a limit is set.
+func validatedBody(config context.BodyCacheConfig) context.BodyCacheConfig {
+	if config.MaxSize == 0 {
+		config.MaxSize = Constant.DEFAULT_VALIDATED_BODY_MAX_SIZE
+	}
+	return config
+}
+
+// unjoin returns the errors joined in err.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// responseRecorder buffers a response, so that it can be validated before it is sent.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+// flush sends the buffered response.
+func (r *responseRecorder) flush() {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	r.ResponseWriter.WriteHeader(status)
+	_, _ = r.ResponseWriter.Write(r.body.Bytes())
+}
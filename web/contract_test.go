@@ -0,0 +1,147 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/OpenAPI"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const contractDocument = `{
+	"openapi": "3.0.3",
+	"info": {"title": "users", "version": "1"},
+	"paths": {
+		"/users/{id}": {
+			"get": {
+				"parameters": [
+					{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}},
+					{"name": "page", "in": "query", "schema": {"type": "integer", "minimum": 1}}
+				],
+				"responses": {"200": {"description": "a user", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}}
+			}
+		},
+		"/users": {
+			"post": {
+				"requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}},
+				"responses": {"201": {"description": "created"}}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"User": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string", "minLength": 2}}}
+		}
+	}
+}`
+
+// contractEngine returns an engine validating the requests of the group "api" against the
+// contract document, the handlers answering with body.
+func contractEngine(t *testing.T, options OpenAPIValidation, body string) *Engine {
+	t.Helper()
+	doc, err := OpenAPI.Load([]byte(contractDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.BasePath = "/api"
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	g.MiddlewareRegister(ValidateOpenAPI(doc, options))
+	answer := func(ctx *context.Context) {
+		_ = ctx.JSON(http.StatusOK, json.RawMessage(body))
+	}
+	g.Get("/users/:id", answer)
+	g.Post("/users", answer)
+	g.Get("/health", answer)
+	return e
+}
+
+func TestValidateOpenAPI(t *testing.T) {
+	e := contractEngine(t, OpenAPIValidation{}, `{"name": "jo"}`)
+	if w := serve(e, http.MethodGet, "/api/users/42?page=2", nil); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d %s", w.Code, w.Body)
+	}
+
+	w := serve(e, http.MethodGet, "/api/users/me?page=0", nil)
+	var answer struct {
+		Code   string           `json:"code"`
+		Errors []map[string]any `json:"errors"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &answer)
+	if w.Code != http.StatusBadRequest || answer.Code != "validation_failed" || len(answer.Errors) != 2 {
+		t.Fatalf("expected 400 with 2 errors, got %d %s", w.Code, w.Body)
+	}
+	if answer.Errors[0]["field"] != "id" || answer.Errors[1]["field"] != "page" || answer.Errors[1]["code"] != "validate.min" {
+		t.Errorf("unexpected errors %v", answer.Errors)
+	}
+
+	w = serve(e, http.MethodPost, "/api/users", strings.NewReader(`{"name": "x"}`), "Content-Type", "application/json")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"name"`) {
+		t.Errorf("expected 400 on name, got %d %s", w.Code, w.Body)
+	}
+	w = serve(e, http.MethodPost, "/api/users", strings.NewReader(`name=jo`), "Content-Type", "application/x-www-form-urlencoded")
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d %s", w.Code, w.Body)
+	}
+	// the routes the document does not describe are left to the router
+	if w = serve(e, http.MethodGet, "/api/health", nil); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d %s", w.Code, w.Body)
+	}
+	if w = serve(e, http.MethodGet, "/api/unknown", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestValidateOpenAPIBodySize(t *testing.T) {
+	body := `{"name": "` + strings.Repeat("a", 64) + `"}`
+	e := contractEngine(t, OpenAPIValidation{}, `{}`)
+	large := `{"name": "` + strings.Repeat("a", 2<<20) + `"}`
+	if w := serve(e, http.MethodPost, "/api/users", strings.NewReader(large), "Content-Type", "application/json"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 by default, got %d", w.Code)
+	}
+	e = contractEngine(t, OpenAPIValidation{Body: context.BodyCacheConfig{MaxSize: 32}}, `{}`)
+	if w := serve(e, http.MethodPost, "/api/users", strings.NewReader(body), "Content-Type", "application/json"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestValidateOpenAPIResponses(t *testing.T) {
+	e := contractEngine(t, OpenAPIValidation{ValidateResponses: true}, `{"name": "x"}`)
+	w := serve(e, http.MethodGet, "/api/users/42", nil)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "response_mismatch") {
+		t.Errorf("expected a response mismatch, got %d %s", w.Code, w.Body)
+	}
+	e = contractEngine(t, OpenAPIValidation{ValidateResponses: true}, `{"name": "jo"}`)
+	if w = serve(e, http.MethodGet, "/api/users/42", nil); w.Code != http.StatusOK || w.Body.String() != `{"name":"jo"}` {
+		t.Errorf("expected the response, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestValidateOpenAPIResponsesPanic(t *testing.T) {
+	doc, err := OpenAPI.Load([]byte(contractDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	// the middlewares registered last run first: the recovering one wraps the validator
+	g.MiddlewareRegister(ValidateOpenAPI(doc, OpenAPIValidation{BasePath: "/api", ValidateResponses: true}))
+	g.MiddlewareRegister(func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			defer func() {
+				if recover() != nil {
+					ctx.W.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}()
+			next(ctx)
+		}
+	})
+	g.Get("/users/:id", func(ctx *context.Context) {
+		panic("boom")
+	})
+	// the panic is answered through the writer of the response, not the recorder of the validator
+	if w := serve(e, http.MethodGet, "/api/users/42", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the panic to be answered, got %d", w.Code)
+	}
+}
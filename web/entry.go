@@ -1,13 +1,19 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"github.com/Jerry20000730/Gjango/web/Constant"
 	"github.com/Jerry20000730/Gjango/web/Context"
 	"github.com/Jerry20000730/Gjango/web/File"
+	"github.com/Jerry20000730/Gjango/web/I18n"
 	"github.com/Jerry20000730/Gjango/web/Logic"
+	"github.com/Jerry20000730/Gjango/web/LongPoll"
+	"github.com/Jerry20000730/Gjango/web/Progress"
 	"github.com/Jerry20000730/Gjango/web/Render"
+	"github.com/Jerry20000730/Gjango/web/Schema"
 	"github.com/Jerry20000730/Gjango/web/Utils"
+	"github.com/Jerry20000730/Gjango/web/WebSocket"
 	"html/template"
 	"log"
 	"net/http"
@@ -35,6 +41,12 @@ type routerGroup struct {
 	handleFuncMap   map[string]map[string]Handler
 	handleMethodMap map[string][]string
 	treeNode        *Logic.TreeNode
+	// typedRoutes are the input and output types of the routes registered with Handle
+	typedRoutes map[string]map[string]typedRoute
+	// routeDocs document the routes in the OpenAPI document, see Describe
+	routeDocs map[string]map[string]RouteDoc
+	// bodySchemas are the schemas the bodies of the routes are validated against, see BodySchema
+	bodySchemas map[string]map[string]*Schema.Schema
 
 	// for middlewares
 	Middlewares   []MiddlewareHandler
@@ -73,6 +85,48 @@ func (r *routerGroup) bind(name string, method string, handler Handler, middlewa
 	r.treeNode.Put(name)
 }
 
+// Locale returns a middleware that pins the locale of the requests going through a route
+// or a router group, overriding the locale negotiated from the Accept-Language header.
+func Locale(lang string) MiddlewareHandler {
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			ctx.SetLocale(lang)
+			next(ctx)
+		}
+	}
+}
+
+// CacheBody returns a middleware that caches the request body (see context.CacheBody),
+// so that the middlewares and the handler after it can all read and bind the body.
+// The errors are answered by the error handler of the engine (see ToHTTPError): bodies larger
+// than config.MaxSize with 413 Request Entity Too Large, bodies that cannot be read with
+// 400 Bad Request.
+func CacheBody(config context.BodyCacheConfig) MiddlewareHandler {
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			if err := ctx.CacheBody(config); err != nil {
+				if !errors.Is(err, context.ErrBodyTooLarge) {
+					err = NewHTTPError(http.StatusBadRequest, "bad_request", "the request body cannot be read").Wrap(err)
+				}
+				ctx.HandleError(err)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// UploadLimit returns a middleware that applies its own upload limits to a route or a router group,
+// instead of Engine.UploadLimits.
+func UploadLimit(limits context.UploadLimits) MiddlewareHandler {
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			ctx.UploadLimits = limits
+			next(ctx)
+		}
+	}
+}
+
 func (r *routerGroup) MiddlewareRegister(middlewareHandler ...MiddlewareHandler) {
 	r.Middlewares = append(r.Middlewares, middlewareHandler...)
 }
@@ -146,6 +200,26 @@ func (r *routerGroup) Options(name string, handler Handler, middlewareHandler ..
 	r.bind(name, Constant.OPTIONS, handler, middlewareHandler...)
 }
 
+// WebSocketHandler the abstract backend logic function of a WebSocket route, called with
+// the upgraded connection, which is closed when the handler returns
+type WebSocketHandler func(ctx *context.Context, conn *WebSocket.Conn)
+
+// WebSocket function allows the binding of
+// 1) URL and a handler of WebSocket connections
+// 2) URL and HTTP request method "GET", the method of the WebSocket handshake
+// The connections are upgraded with the default WebSocket.Options; use ctx.Upgrade
+// in a Get handler for other options.
+func (r *routerGroup) WebSocket(name string, handler WebSocketHandler, middlewareHandler ...MiddlewareHandler) {
+	r.bind(name, Constant.GET, func(ctx *context.Context) {
+		conn, err := ctx.Upgrade(nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(ctx, conn)
+	}, middlewareHandler...)
+}
+
 // Head function allows the binding of
 // 1) URL and handler
 // 2) URL and HTTP request method "Head"
@@ -160,13 +234,36 @@ type Engine struct {
 	Router        router
 	HTMLPreloader Render.HTMLPreloader
 	FileManager   File.FileManager
+	// Catalog holds the messages used to localize validation and binding errors
+	Catalog *I18n.Catalog
+	// UploadLimits are the default limits of multipart uploads, see also UploadLimit
+	UploadLimits context.UploadLimits
+	// ProgressTracker receives the progress of the uploads identified by an X-Progress-ID
+	// header or query parameter, see also UploadProgress
+	ProgressTracker Progress.Tracker
+	// Notifier wakes the long-polling requests, see Context.LongPoll and Context.Notify
+	Notifier *LongPoll.Notifier
+	// ErrorHandler answers the errors returned by the handlers (see Fallible) and passed to
+	// ctx.HandleError; nil means DefaultErrorHandler, or ProblemErrorHandler with ProblemDetails
+	ErrorHandler ErrorHandler
+	// ProblemDetails answers the errors of the framework (404, 405, and the errors of the
+	// handlers without an ErrorHandler) with problem details objects (RFC 9457)
+	ProblemDetails bool
+	// Logger receives the logs of the framework, such as the errors and the panics of the
+	// handlers; nil means the standard logger
+	Logger *log.Logger
+	// Debug shows the details of the panics to the developers, see Recovery; it must not be
+	// enabled in production
+	Debug bool
 }
 
 // NewEngine create a new web framework engine with default port of 8321
 func NewEngine() *Engine {
 	engine := &Engine{
-		port:   "8321",
-		Router: router{},
+		port:     "8321",
+		Router:   router{},
+		Catalog:  I18n.NewCatalog(),
+		Notifier: LongPoll.NewNotifier(),
 	}
 	engine.pool.New = func() any {
 		return &context.Context{}
@@ -177,8 +274,10 @@ func NewEngine() *Engine {
 // NewEngineWithPort create a new web framework engine with user-defined port
 func NewEngineWithPort(port int) *Engine {
 	engine := &Engine{
-		port:   strconv.Itoa(port),
-		Router: router{},
+		port:     strconv.Itoa(port),
+		Router:   router{},
+		Catalog:  I18n.NewCatalog(),
+		Notifier: LongPoll.NewNotifier(),
 	}
 	engine.pool.New = func() any {
 		return &context.Context{}
@@ -194,9 +293,26 @@ func (e *Engine) GetPort() string {
 // ServeHTTP the function that process http request
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := e.pool.Get().(*context.Context)
-	ctx.W = w
-	ctx.R = r
+	ctx.Reset(w, r)
+	ctx.Catalog = e.Catalog
+	ctx.UploadLimits = e.UploadLimits
+	ctx.ProgressTracker = e.ProgressTracker
+	ctx.Notifier = e.Notifier
+	ctx.Logger = e.Logger
+	if ctx.Logger == nil {
+		ctx.Logger = log.Default()
+	}
+	ctx.Debug = e.Debug
+	ctx.ErrorHandler = e.ErrorHandler
+	if ctx.ErrorHandler == nil {
+		if e.ProblemDetails {
+			ctx.ErrorHandler = ProblemErrorHandler
+		} else {
+			ctx.ErrorHandler = DefaultErrorHandler
+		}
+	}
 	e.httpRequestHandle(ctx, w, r)
+	ctx.Finish()
 	e.pool.Put(ctx)
 }
 
@@ -220,25 +336,39 @@ func (e *Engine) httpRequestHandle(ctx *context.Context, w http.ResponseWriter,
 		routerName := Utils.SubStringLast(r.URL.Path, "/"+g.groupName)
 		node := g.treeNode.Get(routerName)
 		if node != nil && node.IsEnd {
+			ctx.SetParams(node.Params(routerName))
+			ctx.SetRoute("/" + g.groupName + node.Path)
 
 			// 1. check if it is ANY method matching
 			if handle, ok := g.handleFuncMap[node.Path][Constant.ANY]; ok {
-				g.processHandler(routerName, Constant.ANY, ctx, handle)
+				g.processHandler(node.Path, Constant.ANY, ctx, handle)
 				return
 			}
 
 			// 2. check if it is other method matching
 			if handle, ok := g.handleFuncMap[node.Path][method]; ok {
-				g.processHandler(routerName, method, ctx, handle)
+				g.processHandler(node.Path, method, ctx, handle)
 				return
 			}
 			// if URL exists, but the method does not, return 405
+			if e.ProblemDetails {
+				problem := Render.NewProblem(http.StatusMethodNotAllowed, fmt.Sprintf("%s [%s] is not allowed", r.RequestURI, method))
+				problem.Instance = r.URL.Path
+				_ = ctx.Problem(problem)
+				return
+			}
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			_, _ = fmt.Fprintf(w, "%s [%s] is not allowed\n", r.RequestURI, method)
 			return
 		}
 	}
 	// if the URL is not found, and the method, return 404
+	if e.ProblemDetails {
+		problem := Render.NewProblem(http.StatusNotFound, fmt.Sprintf("%s [%s] is not found", r.RequestURI, method))
+		problem.Instance = r.URL.Path
+		_ = ctx.Problem(problem)
+		return
+	}
 	w.WriteHeader(http.StatusNotFound)
 	_, _ = fmt.Fprintf(w, "%s [%s] is not found\n", r.RequestURI, method)
 }
@@ -0,0 +1,262 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"io"
+	"log"
+	"net/http"
+)
+
+// FallibleHandler the abstract backend logic function of a route that can fail: the error it
+// returns is answered by the ErrorHandler of the engine, see Fallible
+type FallibleHandler func(ctx *context.Context) error
+
+// Fallible adapts a handler returning an error to a Handler. When the handler returns an error,
+// the request is answered by Engine.ErrorHandler (see context.HandleError), so that the handler
+// does not answer errors itself. It should return the error before writing to the response.
+//
+// Example:
+//
+//	g.Post("/users", web.Fallible(func(ctx *context.Context) error {
+//		user := &User{}
+//		if err := ctx.ParseJSON(user, true, true); err != nil {
+//			return err // 400, with the localized validation error
+//		}
+//		if exists(user.Email) {
+//			return web.NewHTTPError(http.StatusConflict, "user_exists", "the user already exists")
+//		}
+//		return ctx.JSON(http.StatusCreated, user)
+//	}))
+func Fallible(handler FallibleHandler) Handler {
+	return func(ctx *context.Context) {
+		if err := handler(ctx); err != nil {
+			ctx.HandleError(err)
+		}
+	}
+}
+
+// ErrorHandler answers a request failed with an error, see Engine.ErrorHandler
+type ErrorHandler func(ctx *context.Context, err error)
+
+// HTTPError is an error answered with its own status code, e.g. 404 Not Found, along with
+// a machine-readable code and a message for the client.
+type HTTPError struct {
+	// Status is the HTTP status code of the response.
+	Status int
+	// Code identifies the error for clients, e.g. "user_not_found".
+	Code string
+	// Message describes the error to the client.
+	Message string
+	// Err is the underlying error; it is logged, but not sent to the client.
+	Err error
+	// Errors are the individual errors of a request failing several checks, e.g. the
+	// violations of a schema; they are sent to the client.
+	Errors []error
+}
+
+// NewHTTPError creates an HTTPError. An empty message means the text of the status code.
+func NewHTTPError(status int, code string, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+// Error returns the message of the error, followed by the underlying error, if any.
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e with err as its underlying error.
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// MarshalJSON renders the error as {"code": code, "message": message, "errors": [...]},
+// leaving out the underlying error.
+func (e *HTTPError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code    string           `json:"code,omitempty"`
+		Message string           `json:"message"`
+		Errors  []map[string]any `json:"errors,omitempty"`
+	}{Code: e.Code, Message: e.Message, Errors: errorDetails(e.Errors)})
+}
+
+// errorDetails renders the individual errors of an HTTPError for the client: the errors of
+// the catalog as {"code": key, "field": field, "message": message}, the others as
+// {"message": message}.
+func errorDetails(errs []error) []map[string]any {
+	if len(errs) == 0 {
+		return nil
+	}
+	details := make([]map[string]any, 0, len(errs))
+	for _, err := range errs {
+		var i18nErr *I18n.Error
+		if errors.As(err, &i18nErr) {
+			detail := map[string]any{"code": i18nErr.Key, "message": i18nErr.Error()}
+			if field := i18nErr.Field(); field != "" {
+				detail["field"] = field
+			}
+			details = append(details, detail)
+			continue
+		}
+		details = append(details, map[string]any{"message": err.Error()})
+	}
+	return details
+}
+
+// ToHTTPError converts err into the HTTPError it is answered with by DefaultErrorHandler:
+//   - an *HTTPError (possibly wrapped) is kept as it is, and a Render.Problem keeps its status,
+//   - binding and validation errors (*I18n.Error, malformed JSON) become 400 Bad Request,
+//     with the code of the catalog message, localized to the locale of the request,
+//   - the upload and body limits of the Context become 413 Request Entity Too Large,
+//     or 415 Unsupported Media Type, even when they are localized errors, which keep the
+//     code and the localized message of the catalog,
+//   - a *PanicError (see Recovery), and any other error, become 500 Internal Server Error,
+//     without their details.
+func ToHTTPError(ctx *context.Context, err error) *HTTPError {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		// whatever the value of the panic, the handler failed
+		return &HTTPError{
+			Status:  http.StatusInternalServerError,
+			Code:    "internal_error",
+			Message: http.StatusText(http.StatusInternalServerError),
+			Err:     err,
+		}
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	var problem Render.Problem
+	if errors.As(err, &problem) {
+		message := problem.Detail
+		if message == "" {
+			message = problem.Title
+		}
+		status := problem.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return &HTTPError{Status: status, Code: problem.Type, Message: message, Err: err}
+	}
+	// the upload and body limits come first: the upload limits are *I18n.Error wrapping
+	// their sentinel, and keep the code and the localized message of the catalog
+	var i18nErr *I18n.Error
+	localized := func(code string) (string, string) {
+		if i18nErr != nil {
+			return i18nErr.Key, ctx.LocalizeError(i18nErr).Error()
+		}
+		return code, err.Error()
+	}
+	errors.As(err, &i18nErr)
+	switch {
+	case errors.Is(err, context.ErrBodyTooLarge), errors.Is(err, context.ErrUploadTooLarge),
+		errors.Is(err, context.ErrFileTooLarge), errors.Is(err, context.ErrTooManyFiles):
+		code, message := localized("too_large")
+		return &HTTPError{Status: http.StatusRequestEntityTooLarge, Code: code, Message: message, Err: err}
+	case errors.Is(err, context.ErrUnsupportedMediaType):
+		code, message := localized("unsupported_media_type")
+		return &HTTPError{Status: http.StatusUnsupportedMediaType, Code: code, Message: message, Err: err}
+	case i18nErr != nil:
+		code, message := localized("")
+		return &HTTPError{Status: http.StatusBadRequest, Code: code, Message: message, Err: err}
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return &HTTPError{Status: http.StatusBadRequest, Code: "bad_request", Message: "malformed request body", Err: err}
+	}
+	return &HTTPError{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal_error",
+		Message: http.StatusText(http.StatusInternalServerError),
+		Err:     err,
+	}
+}
+
+// DefaultErrorHandler is the ErrorHandler of the engines that do not set any: it answers
+// the HTTPError of err (see ToHTTPError) as JSON, {"code": code, "message": message},
+// and logs the errors answered with a 5xx status.
+func DefaultErrorHandler(ctx *context.Context, err error) {
+	httpErr := ToHTTPError(ctx, err)
+	if httpErr.Status >= http.StatusInternalServerError {
+		logError(ctx, err)
+	}
+	_ = ctx.JSON(httpErr.Status, httpErr)
+}
+
+// ToProblem converts err into the problem details object (RFC 9457) it is answered with by
+// ProblemErrorHandler: a Render.Problem is kept as it is, and any other error is converted
+// with ToHTTPError, its code, the field of validation errors and its individual errors
+// becoming extension members.
+// The instance of the problem is the path of the request.
+func ToProblem(ctx *context.Context, err error) Render.Problem {
+	var problem Render.Problem
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) || !errors.As(err, &problem) {
+		httpErr := ToHTTPError(ctx, err)
+		detail := httpErr.Message
+		if detail == http.StatusText(httpErr.Status) {
+			detail = ""
+		}
+		problem = Render.NewProblem(httpErr.Status, detail)
+		if httpErr.Code != "" {
+			problem = problem.With("code", httpErr.Code)
+		}
+		var i18nErr *I18n.Error
+		if len(httpErr.Errors) > 0 {
+			problem = problem.With("errors", errorDetails(httpErr.Errors))
+		} else if errors.As(err, &i18nErr) && i18nErr.Field() != "" {
+			problem = problem.With("field", i18nErr.Field())
+		}
+	}
+	if problem.Instance == "" {
+		problem.Instance = ctx.R.URL.Path
+	}
+	return problem
+}
+
+// ProblemErrorHandler is the ErrorHandler of the engines with ProblemDetails: it answers the
+// problem of err (see ToProblem) as application/problem+json, or application/problem+xml for
+// the clients preferring XML, and logs the errors answered with a 5xx status.
+func ProblemErrorHandler(ctx *context.Context, err error) {
+	problem := ToProblem(ctx, err)
+	if problem.Status >= http.StatusInternalServerError {
+		logError(ctx, err)
+	}
+	_ = ctx.Problem(problem)
+}
+
+// logError logs an error answered with a 5xx status to the logger of the engine. Panics are
+// left out, since Recovery has already logged them along with their stack.
+func logError(ctx *context.Context, err error) {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return
+	}
+	logger(ctx).Printf("[ERROR] %s %s: %v", ctx.R.Method, ctx.R.URL.Path, err)
+}
+
+// logger returns the logger of the engine, or the standard logger outside of an engine.
+func logger(ctx *context.Context) *log.Logger {
+	if ctx.Logger == nil {
+		return log.Default()
+	}
+	return ctx.Logger
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serve answers a request of method to target with body through e, and returns the recorder.
+func serve(e *Engine, method string, target string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	return w
+}
+
+func TestToHTTPError(t *testing.T) {
+	var syntaxErr error = &json.SyntaxError{}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"http error", fmt.Errorf("wrapped: %w", NewHTTPError(http.StatusNotFound, "not_found", "")), http.StatusNotFound, "not_found"},
+		{"problem", Render.NewProblem(http.StatusConflict, "taken"), http.StatusConflict, ""},
+		{"i18n", I18n.NewError(I18n.VALIDATE_REQUIRED, map[string]any{"field": "name"}), http.StatusBadRequest, I18n.VALIDATE_REQUIRED},
+		{"syntax", syntaxErr, http.StatusBadRequest, "bad_request"},
+		{"type", &json.UnmarshalTypeError{}, http.StatusBadRequest, "bad_request"},
+		{"eof", io.ErrUnexpectedEOF, http.StatusBadRequest, "bad_request"},
+		{"body too large", context.ErrBodyTooLarge, http.StatusRequestEntityTooLarge, "too_large"},
+		{"upload too large", &I18n.Error{Key: I18n.UPLOAD_TOO_LARGE, Err: context.ErrUploadTooLarge}, http.StatusRequestEntityTooLarge, I18n.UPLOAD_TOO_LARGE},
+		{"file too large", &I18n.Error{Key: I18n.UPLOAD_FILE_TOO_LARGE, Err: context.ErrFileTooLarge}, http.StatusRequestEntityTooLarge, I18n.UPLOAD_FILE_TOO_LARGE},
+		{"too many files", &I18n.Error{Key: I18n.UPLOAD_TOO_MANY_FILES, Err: context.ErrTooManyFiles}, http.StatusRequestEntityTooLarge, I18n.UPLOAD_TOO_MANY_FILES},
+		{"unsupported type", &I18n.Error{Key: I18n.UPLOAD_UNSUPPORTED_TYPE, Err: context.ErrUnsupportedMediaType}, http.StatusUnsupportedMediaType, I18n.UPLOAD_UNSUPPORTED_TYPE},
+		{"unsupported type sentinel", context.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"panic", &PanicError{Value: "boom"}, http.StatusInternalServerError, "internal_error"},
+		{"other", errors.New("secret"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := &context.Context{R: httptest.NewRequest(http.MethodGet, "/", nil)}
+			httpErr := ToHTTPError(ctx, test.err)
+			if httpErr.Status != test.status || httpErr.Code != test.code {
+				t.Errorf("expected %d %q, got %d %q", test.status, test.code, httpErr.Status, httpErr.Code)
+			}
+			if test.status == http.StatusInternalServerError && strings.Contains(httpErr.Message, "secret") {
+				t.Errorf("the details of the error are sent to the client: %s", httpErr.Message)
+			}
+			problem := ToProblem(ctx, test.err)
+			if problem.Status != test.status {
+				t.Errorf("expected a problem of status %d, got %d", test.status, problem.Status)
+			}
+		})
+	}
+}
+
+func TestUploadLimitErrors(t *testing.T) {
+	for _, problemDetails := range []bool{false, true} {
+		e := NewEngine()
+		e.ProblemDetails = problemDetails
+		e.UploadLimits = context.UploadLimits{MaxFileSize: 4}
+		g := e.Router.NewGroup("api")
+		g.Post("/upload", Fallible(func(ctx *context.Context) error {
+			_, err := ctx.FormFile("file")
+			return err
+		}))
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "a.txt")
+		_, _ = part.Write([]byte("hello world"))
+		_ = writer.Close()
+		w := serve(e, http.MethodPost, "/api/upload", &body, "Content-Type", writer.FormDataContentType())
+		if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), I18n.UPLOAD_FILE_TOO_LARGE) {
+			t.Errorf("expected 413 %s, got %d %s", I18n.UPLOAD_FILE_TOO_LARGE, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCacheBodyErrors(t *testing.T) {
+	for _, problemDetails := range []bool{false, true} {
+		e := NewEngine()
+		e.ProblemDetails = problemDetails
+		g := e.Router.NewGroup("api")
+		g.Post("/echo", func(ctx *context.Context) {
+			data, _ := io.ReadAll(ctx.R.Body)
+			_ = ctx.String(http.StatusOK, string(data))
+		}, CacheBody(context.BodyCacheConfig{MaxSize: 5}))
+		if w := serve(e, http.MethodPost, "/api/echo", strings.NewReader("hello")); w.Code != http.StatusOK || w.Body.String() != "hello" {
+			t.Errorf("expected 200 hello, got %d %s", w.Code, w.Body.String())
+		}
+		w := serve(e, http.MethodPost, "/api/echo", strings.NewReader("hello world"))
+		if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "too_large") {
+			t.Errorf("expected 413 too_large, got %d %s", w.Code, w.Body.String())
+		}
+		if problemDetails && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+			t.Errorf("expected a problem, got %s", w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/JSONRPC"
+	"net/http"
+	"runtime/debug"
+)
+
+// JSONRPC registers a JSON-RPC 2.0 endpoint on the route name of group, answering the POST
+// requests through the middlewares of the group, and returns its server, so that more
+// methods can be registered by name (see JSONRPC.Server.Register). service is either a
+// *JSONRPC.Server, or a struct whose methods are registered with
+// JSONRPC.Server.RegisterService, e.g. "add" for the method Add, or nil.
+//
+// The endpoint answers single requests and batches, the requests of a batch being called in
+// order; the notifications are called but not answered, and a request made of notifications
+// only is answered with 204 No Content. The body is limited to Body.MaxSize of the server
+// (1 MB by default), a larger body being answered with 413 Request Entity Too Large. The
+// errors follow the specification:
+//   - a body that is not JSON is a PARSE_ERROR, and a message that is not a request object
+//     an INVALID_REQUEST,
+//   - an unknown method is a METHOD_NOT_FOUND,
+//   - params failing to bind or to validate (see Binding.DecodeJSON) are an INVALID_PARAMS,
+//     listing the localized errors in its data, like the errors of HTTPError,
+//   - a *JSONRPC.Error returned by a method is answered as it is, an error converted by
+//     ToHTTPError into a 4xx HTTPError becomes a SERVER_ERROR with the HTTPError as its data,
+//     and any other error an INTERNAL_ERROR, logged but without its details,
+//   - a method that panics is an INTERNAL_ERROR as well, for its request only: the panic and
+//     its stack are logged, and the other requests of the batch are still answered.
+//
+// Example:
+//
+//	type Calculator struct{}
+//
+//	type Operands struct {
+//		A int `json:"a" gjango:"required"`
+//		B int `json:"b" gjango:"required"`
+//	}
+//
+//	func (Calculator) Add(ctx *context.Context, params Operands) (int, error) {
+//		return params.A + params.B, nil
+//	}
+//
+//	// POST /rpc {"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": 1}
+//	rpc := g.JSONRPC("/rpc", Calculator{})
+//	rpc.Register("ping", func(ctx *context.Context) (string, error) { return "pong", nil })
+//	rpc.Body.MaxSize = 64 << 10
+func (r *routerGroup) JSONRPC(name string, service any, middlewareHandler ...MiddlewareHandler) *JSONRPC.Server {
+	server, ok := service.(*JSONRPC.Server)
+	if !ok {
+		server = JSONRPC.NewServer()
+		if service != nil {
+			server.RegisterService("", service)
+		}
+	}
+	r.bind(name, http.MethodPost, func(ctx *context.Context) {
+		serveJSONRPC(ctx, server)
+	}, middlewareHandler...)
+	return server
+}
+
+// serveJSONRPC answers the JSON-RPC requests of ctx with server.
+func serveJSONRPC(ctx *context.Context, server *JSONRPC.Server) {
+	if err := ctx.CacheBody(validatedBody(server.Body)); err != nil {
+		ctx.HandleError(err)
+		return
+	}
+	data, err := ctx.Body()
+	if err != nil {
+		ctx.HandleError(err)
+		return
+	}
+	messages, batch, rpcErr := JSONRPC.Decode(data)
+	if rpcErr != nil {
+		_ = ctx.JSON(http.StatusOK, JSONRPC.NewResponse(nil, nil, rpcErr))
+		return
+	}
+	responses := make([]*JSONRPC.Response, 0, len(messages))
+	for _, message := range messages {
+		if response := callJSONRPC(ctx, server, message); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	switch {
+	case len(responses) == 0:
+		ctx.W.WriteHeader(http.StatusNoContent)
+	case batch:
+		_ = ctx.JSON(http.StatusOK, responses)
+	default:
+		_ = ctx.JSON(http.StatusOK, responses[0])
+	}
+}
+
+// callJSONRPC calls the request of message, and returns its response, nil for the
+// notifications. A panic of the method is recovered into a *PanicError, answered with an
+// INTERNAL_ERROR.
+func callJSONRPC(ctx *context.Context, server *JSONRPC.Server, message json.RawMessage) *JSONRPC.Response {
+	request, rpcErr := JSONRPC.ParseRequest(message)
+	if rpcErr != nil {
+		var id json.RawMessage
+		if request != nil {
+			id = request.ID
+		}
+		return JSONRPC.NewResponse(id, nil, rpcErr)
+	}
+	result, err := callMethod(ctx, server, request)
+	response := JSONRPC.NewResponse(request.ID, result, nil)
+	if err != nil {
+		response.Result, response.Error = nil, jsonRPCError(ctx, err)
+		if response.Error.Code == JSONRPC.INTERNAL_ERROR {
+			logError(ctx, err)
+		}
+	}
+	if request.IsNotification() {
+		return nil
+	}
+	return response
+}
+
+// callMethod calls the method of request with server, recovering from its panics, which are
+// logged along with their stack and recorded in ctx.Errors. http.ErrAbortHandler is panicked
+// again, to abort the response as net/http intends.
+func callMethod(ctx *context.Context, server *JSONRPC.Server, request *JSONRPC.Request) (result any, err error) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		if value == http.ErrAbortHandler {
+			panic(value)
+		}
+		panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+		ctx.Error(panicErr)
+		logger(ctx).Printf("[PANIC] %s %s: JSON-RPC method [%s]: %v\n%s", ctx.R.Method, ctx.R.URL.Path, request.Method, value, panicErr.Stack)
+		result, err = nil, panicErr
+	}()
+	return server.Call(ctx, request)
+}
+
+// jsonRPCError converts the error of a call into the error object it is answered with.
+func jsonRPCError(ctx *context.Context, err error) *JSONRPC.Error {
+	var rpcErr *JSONRPC.Error
+	if errors.As(err, &rpcErr) {
+		if rpcErr.Code == JSONRPC.INVALID_PARAMS && rpcErr.Data == nil && rpcErr.Err != nil {
+			invalid := *rpcErr
+			invalid.Data = errorDetails(unjoin(rpcErr.Err))
+			return &invalid
+		}
+		return rpcErr
+	}
+	httpErr := ToHTTPError(ctx, err)
+	if httpErr.Status >= http.StatusInternalServerError {
+		internal := JSONRPC.NewError(JSONRPC.INTERNAL_ERROR, "", nil)
+		internal.Err = err
+		return internal
+	}
+	return &JSONRPC.Error{Code: JSONRPC.SERVER_ERROR, Message: httpErr.Message, Data: httpErr, Err: err}
+}
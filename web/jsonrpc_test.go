@@ -0,0 +1,120 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/JSONRPC"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type rpcCalculator struct{}
+
+type rpcOperands struct {
+	A int `json:"a" gjango:"required"`
+	B int `json:"b" gjango:"required"`
+}
+
+func (rpcCalculator) Add(ctx *context.Context, params rpcOperands) (int, error) {
+	return params.A + params.B, nil
+}
+
+func (rpcCalculator) Divide(ctx *context.Context, params rpcOperands) (int, error) {
+	return params.A / params.B, nil
+}
+
+// rpcEngine returns an engine serving rpcCalculator on POST /api/rpc, behind a group
+// middleware counting the requests it lets through, and logging to logs.
+func rpcEngine(logs *bytes.Buffer) (*Engine, *JSONRPC.Server, *int) {
+	e := NewEngine()
+	e.Logger = log.New(logs, "", 0)
+	g := e.Router.NewGroup("api")
+	count := 0
+	g.MiddlewareRegister(func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			if ctx.R.Header.Get("Authorization") == "" {
+				ctx.HandleError(NewHTTPError(http.StatusUnauthorized, "unauthorized", ""))
+				return
+			}
+			count++
+			next(ctx)
+		}
+	})
+	return e, g.JSONRPC("/rpc", rpcCalculator{}), &count
+}
+
+func TestJSONRPC(t *testing.T) {
+	e, _, count := rpcEngine(&bytes.Buffer{})
+	headers := []string{"Content-Type", "application/json", "Authorization", "Bearer token"}
+	w := serve(e, http.MethodPost, "/api/rpc", strings.NewReader(`{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": 1}`), headers...)
+	if w.Code != http.StatusOK || w.Body.String() != `{"jsonrpc":"2.0","result":3,"id":1}` {
+		t.Errorf("unexpected answer %d %s", w.Code, w.Body)
+	}
+	if w = serve(e, http.MethodPost, "/api/rpc", strings.NewReader(`{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": 1}`)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the group middleware to answer 401, got %d %s", w.Code, w.Body)
+	}
+	if *count != 1 {
+		t.Errorf("expected 1 request through the group middleware, got %d", *count)
+	}
+	notifications := `[{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}}, {"jsonrpc": "2.0", "method": "unknown"}]`
+	if w = serve(e, http.MethodPost, "/api/rpc", strings.NewReader(notifications), headers...); w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("expected 204 for notifications only, got %d %s", w.Code, w.Body)
+	}
+	if w = serve(e, http.MethodPost, "/api/rpc", strings.NewReader(`{"jsonrpc": "2.0", "method": "add"`), headers...); !strings.Contains(w.Body.String(), `"code":-32700`) {
+		t.Errorf("expected a parse error, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestJSONRPCBatch(t *testing.T) {
+	logs := &bytes.Buffer{}
+	e, _, _ := rpcEngine(logs)
+	batch := `[
+		{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": 1},
+		{"jsonrpc": "2.0", "method": "divide", "params": {"a": 1, "b": 0}, "id": 2},
+		{"jsonrpc": "2.0", "method": "add", "params": {"a": 1}, "id": 3},
+		{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}},
+		{"jsonrpc": "2.0", "method": "unknown", "id": 4},
+		{"method": "add", "id": 5}
+	]`
+	w := serve(e, http.MethodPost, "/api/rpc", strings.NewReader(batch), "Content-Type", "application/json", "Authorization", "Bearer token")
+	var responses []struct {
+		Result any             `json:"result"`
+		Error  *JSONRPC.Error  `json:"error"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil || w.Code != http.StatusOK || len(responses) != 5 {
+		t.Fatalf("unexpected answer %d %s", w.Code, w.Body)
+	}
+	if responses[0].Result != float64(3) || responses[0].Error != nil {
+		t.Errorf("unexpected response %s", w.Body)
+	}
+	codes := []int{JSONRPC.INTERNAL_ERROR, JSONRPC.INVALID_PARAMS, JSONRPC.METHOD_NOT_FOUND, JSONRPC.INVALID_REQUEST}
+	for i, code := range codes {
+		if response := responses[i+1]; response.Error == nil || response.Error.Code != code || string(response.ID) != string(rune('2'+i)) {
+			t.Errorf("expected the error %d for the request %d, got %s", code, i+2, w.Body)
+		}
+	}
+	// the panic is logged with its stack, but its details are not answered
+	if !strings.Contains(logs.String(), "[PANIC] POST /api/rpc: JSON-RPC method [divide]: runtime error: integer divide by zero") || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("unexpected logs %s", logs)
+	}
+	if strings.Contains(w.Body.String(), "divide by zero") {
+		t.Errorf("the details of the panic were answered: %s", w.Body)
+	}
+}
+
+func TestJSONRPCBodySize(t *testing.T) {
+	e, server, _ := rpcEngine(&bytes.Buffer{})
+	headers := []string{"Content-Type", "application/json", "Authorization", "Bearer token"}
+	large := `{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2, "c": "` + strings.Repeat("a", 2<<20) + `"}, "id": 1}`
+	if w := serve(e, http.MethodPost, "/api/rpc", strings.NewReader(large), headers...); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 by default, got %d", w.Code)
+	}
+	server.Body.MaxSize = 16
+	if w := serve(e, http.MethodPost, "/api/rpc", strings.NewReader(`{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": 1}`), headers...); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d %s", w.Code, w.Body)
+	}
+}
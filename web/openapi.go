@@ -0,0 +1,358 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Binding"
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/OpenAPI"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"github.com/Jerry20000730/Gjango/web/Schema"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// jsonMediaType is the media type of the JSON bodies in the OpenAPI document.
+const jsonMediaType = "application/json"
+
+// RouteDoc documents a route in the OpenAPI document of the engine, see routerGroup.Describe
+type RouteDoc struct {
+	// Summary is a short summary of what the route does.
+	Summary string
+	// Description is a longer explanation of the route.
+	Description string
+	// Tags group the routes in the documentation; the name of the router group by default.
+	Tags []string
+	// OperationID identifies the route in the generated clients; it is derived from the method
+	// and the path by default, e.g. "getBlogArticlesById". The operations of a route of the
+	// method ANY get the method as a suffix, e.g. "pingGet" and "pingPost" for "ping".
+	OperationID string
+	// Deprecated marks the route as deprecated.
+	Deprecated bool
+	// Hidden leaves the route out of the document.
+	Hidden bool
+	// Request is a value of the type of the request body, e.g. User{}, for the routes not
+	// registered with Handle. Its fields tagged path, query or header are documented as
+	// parameters.
+	Request any
+	// Responses are values of the types of the response bodies by status code, e.g.
+	// {http.StatusOK: []User{}, http.StatusNotFound: web.HTTPError{}}. A nil value documents
+	// a response without a body.
+	Responses map[int]any
+}
+
+// Describe documents the route name of the HTTP request method of group in the OpenAPI
+// document of the engine (see Engine.OpenAPI). The routes registered with Handle already
+// document their request and response bodies.
+//
+// Example:
+//
+//	g.Get("/users", listUsers)
+//	g.Describe(http.MethodGet, "/users", web.RouteDoc{
+//		Summary:   "List the users",
+//		Responses: map[int]any{http.StatusOK: []User{}},
+//	})
+func (r *routerGroup) Describe(method string, name string, doc RouteDoc) {
+	if r.routeDocs == nil {
+		r.routeDocs = make(map[string]map[string]RouteDoc)
+	}
+	if r.routeDocs[name] == nil {
+		r.routeDocs[name] = make(map[string]RouteDoc)
+	}
+	r.routeDocs[name][method] = doc
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the routes of the engine:
+//   - the paths of the routes, the path parameters written {name},
+//   - for the routes registered with Handle, the parameters and the request body bound into
+//     their input, and their response, with the schemas of the Go types (see Schema.Generator),
+//   - the schemas of the request bodies validated with routerGroup.BodySchema,
+//   - the summaries and the bodies documented with routerGroup.Describe.
+//
+// The routes of the method ANY are documented for GET, POST, PUT, PATCH and DELETE, their
+// operation IDs suffixed with the method so that they stay unique.
+func (e *Engine) OpenAPI(info OpenAPI.Info) *OpenAPI.Document {
+	generator := Schema.NewGenerator(OpenAPI.SCHEMAS_PREFIX)
+	doc := &OpenAPI.Document{OpenAPI: OpenAPI.VERSION, Info: info, Paths: make(map[string]*OpenAPI.PathItem)}
+	for _, g := range e.Router.routerGroups {
+		names := make([]string, 0, len(g.handleFuncMap))
+		for name := range g.handleFuncMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			route := "/" + strings.Trim(g.groupName+name, "/")
+			template, params := OpenAPI.PathTemplate(route)
+			for method := range g.handleFuncMap[name] {
+				routeDoc := g.routeDocs[name][method]
+				if routeDoc.Hidden {
+					continue
+				}
+				methods := []string{method}
+				if method == Constant.ANY {
+					methods = []string{Constant.GET, Constant.POST, Constant.PUT, Constant.PATCH, Constant.DELETE}
+				}
+				for _, m := range methods {
+					methodDoc := routeDoc
+					if method == Constant.ANY && methodDoc.OperationID != "" {
+						methodDoc.OperationID += m[:1] + strings.ToLower(m[1:])
+					}
+					operation := e.operation(generator, g, route, params, m, g.typedRoutes[name][method], g.bodySchemas[name][method], methodDoc)
+					item := doc.Paths[template]
+					if item == nil {
+						item = &OpenAPI.PathItem{}
+						doc.Paths[template] = item
+					}
+					item.SetOperation(m, operation)
+				}
+			}
+		}
+	}
+	if len(generator.Defs) > 0 {
+		doc.Components = &OpenAPI.Components{Schemas: generator.Defs}
+	}
+	return doc
+}
+
+// OpenAPIHandler returns a handler serving the OpenAPI document of the engine (see
+// Engine.OpenAPI) as JSON. The document is generated on the first request, once all the
+// routes have been registered.
+//
+// Example:
+//
+//	docs := engine.Router.NewGroup("docs")
+//	docs.Get("/openapi.json", engine.OpenAPIHandler(OpenAPI.Info{Title: "Blog API", Version: "1.0.0"}))
+func (e *Engine) OpenAPIHandler(info OpenAPI.Info) Handler {
+	var once sync.Once
+	var data json.RawMessage
+	var err error
+	return func(ctx *context.Context) {
+		once.Do(func() {
+			data, err = Render.EncodeJSON(e.OpenAPI(info))
+		})
+		if err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		_ = ctx.JSON(http.StatusOK, data)
+	}
+}
+
+// operation returns the OpenAPI operation of the route of method.
+func (e *Engine) operation(generator *Schema.Generator, g *routerGroup, route string, params []string, method string, typed typedRoute, body *Schema.Schema, doc RouteDoc) *OpenAPI.Operation {
+	operation := &OpenAPI.Operation{
+		OperationID: doc.OperationID,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Responses:   make(map[string]*OpenAPI.Response),
+	}
+	if operation.OperationID == "" {
+		operation.OperationID = operationID(method, route)
+	}
+	if operation.Tags == nil && g.groupName != "" {
+		operation.Tags = []string{g.groupName}
+	}
+	in := typed.in
+	if in == nil && doc.Request != nil {
+		in = reflect.TypeOf(doc.Request)
+	}
+
+	// parameters: the path parameters of the route, then the fields of the input bound from the
+	// path, the query and the headers
+	parameters := make(map[string]*OpenAPI.Parameter)
+	for _, param := range params {
+		parameter := &OpenAPI.Parameter{Name: param, In: "path", Required: true, Schema: &Schema.Schema{Type: Schema.Types{"string"}}}
+		parameters["path:"+param] = parameter
+		operation.Parameters = append(operation.Parameters, parameter)
+	}
+	if in != nil {
+		for _, field := range sourceFields(in) {
+			source, key := Binding.SourceTag(field)
+			if source == "header" {
+				key = http.CanonicalHeaderKey(key)
+			}
+			parameter := parameters[source+":"+key]
+			if parameter == nil {
+				parameter = &OpenAPI.Parameter{Name: key, In: source}
+				operation.Parameters = append(operation.Parameters, parameter)
+			}
+			parameter.Schema = generator.Field(field)
+			parameter.Required = source == "path" || Binding.HasRule(Binding.ParseRules(field.Tag.Get("gjango")), "required")
+		}
+	}
+
+	// request body: the schema the body is validated against, or the one of the input
+	if body != nil {
+		operation.RequestBody = &OpenAPI.RequestBody{
+			Required: true,
+			Content:  map[string]*OpenAPI.MediaType{jsonMediaType: {Schema: componentSchema(generator, body)}},
+		}
+	} else if in != nil && method != Constant.GET && method != Constant.HEAD && hasBodyFields(in) {
+		operation.RequestBody = &OpenAPI.RequestBody{
+			Required: true,
+			Content:  map[string]*OpenAPI.MediaType{jsonMediaType: {Schema: generator.Generate(in)}},
+		}
+	}
+
+	// responses
+	if typed.out != nil {
+		status, body := typedResponse(typed.out)
+		response := &OpenAPI.Response{Description: http.StatusText(status)}
+		if body {
+			response.Content = map[string]*OpenAPI.MediaType{jsonMediaType: {Schema: generator.Generate(typed.out)}}
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+		operation.Responses["default"] = e.errorResponse(generator)
+	}
+	for status, value := range doc.Responses {
+		response := &OpenAPI.Response{Description: http.StatusText(status)}
+		if value != nil {
+			response.Content = map[string]*OpenAPI.MediaType{jsonMediaType: {Schema: generator.Generate(reflect.TypeOf(value))}}
+		}
+		switch value.(type) {
+		case HTTPError, *HTTPError, Render.Problem:
+			response = e.errorResponse(generator)
+			response.Description = http.StatusText(status)
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+	}
+	if len(operation.Responses) == 0 {
+		operation.Responses["default"] = &OpenAPI.Response{Description: "Default response"}
+	}
+	return operation
+}
+
+// errorResponse returns the response of the errors answered by the error handler of the
+// engine: an HTTPError, or a problem details object with ProblemDetails.
+func (e *Engine) errorResponse(generator *Schema.Generator) *OpenAPI.Response {
+	stringSchema := func() *Schema.Schema { return &Schema.Schema{Type: Schema.Types{"string"}} }
+	if e.ProblemDetails {
+		generator.Defs["Problem"] = &Schema.Schema{
+			Type: Schema.Types{"object"},
+			Properties: map[string]*Schema.Schema{
+				"type":     {Type: Schema.Types{"string"}, Format: "uri-reference"},
+				"title":    stringSchema(),
+				"status":   {Type: Schema.Types{"integer"}},
+				"detail":   stringSchema(),
+				"instance": {Type: Schema.Types{"string"}, Format: "uri-reference"},
+			},
+		}
+		return &OpenAPI.Response{
+			Description: "Error",
+			Content: map[string]*OpenAPI.MediaType{
+				Constant.PROBLEM_JSON_HEADER_CONTENT_TYPE: {Schema: &Schema.Schema{Ref: OpenAPI.SCHEMAS_PREFIX + "Problem"}},
+			},
+		}
+	}
+	generator.Defs["HTTPError"] = &Schema.Schema{
+		Type:       Schema.Types{"object"},
+		Properties: map[string]*Schema.Schema{"code": stringSchema(), "message": stringSchema()},
+		Required:   []string{"message"},
+	}
+	return &OpenAPI.Response{
+		Description: "Error",
+		Content: map[string]*OpenAPI.MediaType{
+			jsonMediaType: {Schema: &Schema.Schema{Ref: OpenAPI.SCHEMAS_PREFIX + "HTTPError"}},
+		},
+	}
+}
+
+// typedResponse returns the status code of the output type of a typed handler (see Typed),
+// and whether it has a body.
+func typedResponse(out reflect.Type) (int, bool) {
+	if out.Implements(reflect.TypeOf((*Render.Render)(nil)).Elem()) {
+		// rendered its own way
+		return http.StatusOK, false
+	}
+	if out.Kind() == reflect.Interface {
+		return http.StatusOK, true
+	}
+	value := reflect.New(out).Elem()
+	if out.Kind() == reflect.Pointer {
+		value = reflect.New(out.Elem())
+	}
+	if coder, ok := value.Interface().(StatusCoder); ok {
+		status := coder.StatusCode()
+		return status, status != http.StatusNoContent
+	}
+	return http.StatusOK, true
+}
+
+// sourceFields returns the fields of struct type t bound from the path, the query and the
+// headers, including those of embedded structs.
+func sourceFields(t reflect.Type) []reflect.StructField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fields := make([]reflect.StructField, 0)
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if source, _ := Binding.SourceTag(field); source != "" {
+			if field.IsExported() {
+				fields = append(fields, field)
+			}
+			continue
+		}
+		if field.Anonymous {
+			fields = append(fields, sourceFields(field.Type)...)
+		}
+	}
+	return fields
+}
+
+// hasBodyFields reports whether the struct type t has fields bound from the body.
+func hasBodyFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if source, _ := Binding.SourceTag(field); source != "" || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && hasBodyFields(field.Type) {
+			return true
+		}
+		if !field.Anonymous && field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// operationID derives the operation ID of a route from its method and its path,
+// e.g. "getBlogArticlesById" for GET /blog/articles/:id.
+func operationID(method string, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(route, "/") {
+		switch {
+		case segment == "":
+			continue
+		case strings.HasPrefix(segment, ":"):
+			b.WriteString("By")
+			segment = segment[1:]
+		case segment == "*" || segment == "**":
+			b.WriteString("Wildcard")
+			continue
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+		}) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
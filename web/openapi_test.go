@@ -0,0 +1,103 @@
+package web
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/OpenAPI"
+	"net/http"
+	"testing"
+)
+
+type documentedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func openAPIEngine() *Engine {
+	e := typedEngine()
+	g := e.Router.routerGroups[0]
+	ok := func(ctx *context.Context) {}
+	g.Get("/files/*/versions/*", ok)
+	g.Get("/users/:id", ok)
+	g.Describe(http.MethodGet, "/users/:id", RouteDoc{
+		Summary:   "Get a user",
+		Responses: map[int]any{http.StatusOK: documentedUser{}, http.StatusNotFound: HTTPError{}},
+	})
+	g.Any("/ping", ok)
+	g.Describe(Constant.ANY, "/ping", RouteDoc{OperationID: "ping"})
+	g.Any("/echo", ok)
+	g.Get("/internal", ok)
+	g.Describe(http.MethodGet, "/internal", RouteDoc{Hidden: true})
+	return e
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := openAPIEngine().OpenAPI(OpenAPI.Info{Title: "test", Version: "1"})
+	if doc.OpenAPI != OpenAPI.VERSION || doc.Paths["/api/internal"] != nil {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+
+	// the wildcards of a path template are numbered, and documented as path parameters
+	files := doc.Paths["/api/files/{wildcard1}/versions/{wildcard2}"]
+	if files == nil || files.Get == nil || len(files.Get.Parameters) != 2 || files.Get.Parameters[1].Name != "wildcard2" || !files.Get.Parameters[1].Required {
+		t.Fatalf("unexpected wildcard path %+v", files)
+	}
+
+	// typed handler: the parameters bound from the path, the query and the headers, the body and the response
+	create := doc.Paths["/api/orgs/{org}/users"].Post
+	if create == nil || create.OperationID != "postApiOrgsByOrgUsers" || len(create.Tags) != 1 || create.Tags[0] != "api" {
+		t.Fatalf("unexpected operation %+v", create)
+	}
+	in := make(map[string]string)
+	for _, parameter := range create.Parameters {
+		in[parameter.Name] = parameter.In
+	}
+	if len(in) != 3 || in["org"] != "path" || in["page"] != "query" || in["X-Version"] != "header" {
+		t.Errorf("unexpected parameters %v", in)
+	}
+	body := create.RequestBody.Content["application/json"].Schema
+	if body.Ref != OpenAPI.SCHEMAS_PREFIX+"typedUser" {
+		t.Fatalf("unexpected request body %+v", body)
+	}
+	body = doc.Components.Schemas["typedUser"]
+	if body == nil || len(body.Required) != 1 || body.Required[0] != "name" || body.Properties["org"] != nil {
+		t.Errorf("unexpected request body %+v", body)
+	}
+	if response := create.Responses["201"]; response == nil || response.Content["application/json"] == nil {
+		t.Errorf("unexpected responses %+v", create.Responses)
+	}
+	if response := create.Responses["default"]; response == nil || response.Content["application/json"].Schema.Ref != OpenAPI.SCHEMAS_PREFIX+"HTTPError" {
+		t.Errorf("unexpected error response %+v", create.Responses)
+	}
+	// a route registered without Handle only documents the parameters of its path
+	if remove := doc.Paths["/api/users/{id}"].Delete; remove == nil || len(remove.Parameters) != 1 || remove.Parameters[0].Name != "id" || remove.RequestBody != nil {
+		t.Errorf("unexpected operation %+v", remove)
+	}
+
+	// documented route: the responses by status code, the errors as HTTPError
+	get := doc.Paths["/api/users/{id}"].Get
+	if get == nil || get.Summary != "Get a user" || get.Responses["200"] == nil || get.Responses["200"].Content["application/json"] == nil {
+		t.Fatalf("unexpected operation %+v", get)
+	}
+	if notFound := get.Responses["404"]; notFound == nil || notFound.Description != "Not Found" || notFound.Content["application/json"].Schema.Ref != OpenAPI.SCHEMAS_PREFIX+"HTTPError" {
+		t.Errorf("unexpected error response %+v", get.Responses["404"])
+	}
+	if doc.Components == nil || doc.Components.Schemas["HTTPError"] == nil {
+		t.Errorf("unexpected components %+v", doc.Components)
+	}
+
+	// ANY routes: one operation per method, with unique operation IDs
+	ids := make(map[string]bool)
+	for _, path := range []string{"/api/ping", "/api/echo"} {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			operation := doc.Paths[path].Operation(method)
+			if operation == nil || ids[operation.OperationID] {
+				t.Fatalf("missing or repeated operation %s %s: %+v", method, path, operation)
+			}
+			ids[operation.OperationID] = true
+		}
+	}
+	if !ids["pingGet"] || !ids["pingDelete"] || !ids["getApiEcho"] || !ids["patchApiEcho"] {
+		t.Errorf("unexpected operation IDs %v", ids)
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Progress"
+	"net/http"
+)
+
+// UploadProgress returns a handler reporting the progress of an upload as JSON, for clients
+// polling it while the upload is sent. The upload is identified by the X-Progress-ID query
+// parameter or header, the same one sent with the upload itself; a request without it fails
+// with a 400 Bad Request HTTPError, and unknown or expired uploads with a 404 Not Found one.
+// The errors of the tracker are handed to ctx.HandleError, which logs them and answers 500
+// Internal Server Error without their details.
+//
+// Example:
+//
+//	engine.ProgressTracker = Progress.NewMemoryTracker(time.Minute)
+//	g.Get("/progress", web.UploadProgress(engine.ProgressTracker))
+//	// GET /progress?X-Progress-ID=42 -> {"id":"42","received":1048576,"total":4194304,"percent":25,...}
+func UploadProgress(tracker Progress.Tracker) Handler {
+	return func(ctx *context.Context) {
+		ctx.W.Header().Set("Cache-Control", "no-store")
+		id := ctx.GetQuery(Progress.HEADER)
+		if id == "" {
+			id = ctx.R.Header.Get(Progress.HEADER)
+		}
+		if id == "" {
+			ctx.HandleError(NewHTTPError(http.StatusBadRequest, "missing_progress_id", "missing "+Progress.HEADER))
+			return
+		}
+		progress, ok, err := tracker.Get(ctx.R.Context(), id)
+		if err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		if !ok {
+			ctx.HandleError(NewHTTPError(http.StatusNotFound, "upload_not_found", "upload "+id+" is not tracked"))
+			return
+		}
+		_ = ctx.JSON(http.StatusOK, struct {
+			Progress.Progress
+			Percent float64 `json:"percent"`
+		}{progress, progress.Percent()})
+	}
+}
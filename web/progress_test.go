@@ -0,0 +1,80 @@
+package web
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"github.com/Jerry20000730/Gjango/web/Progress"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingTracker is a Tracker whose store is down.
+type failingTracker struct{}
+
+func (failingTracker) Set(ctx stdcontext.Context, progress Progress.Progress) error {
+	return errors.New("redis: connection refused to 10.0.0.3:6379")
+}
+
+func (failingTracker) Get(ctx stdcontext.Context, id string) (Progress.Progress, bool, error) {
+	return Progress.Progress{}, false, errors.New("redis: connection refused to 10.0.0.3:6379")
+}
+
+func progressEngine(tracker Progress.Tracker) *Engine {
+	e := NewEngine()
+	e.Router.NewGroup("user").Get("/progress", UploadProgress(tracker))
+	return e
+}
+
+func TestUploadProgress(t *testing.T) {
+	tracker := Progress.NewMemoryTracker(time.Minute)
+	_ = tracker.Set(stdcontext.Background(), Progress.Progress{ID: "42", Received: 1024, Total: 4096})
+	e := progressEngine(tracker)
+
+	tests := []struct {
+		target string
+		header string
+		status int
+	}{
+		{"/user/progress?X-Progress-ID=42", "", http.StatusOK},
+		{"/user/progress", "42", http.StatusOK},
+		{"/user/progress?X-Progress-ID=43", "", http.StatusNotFound},
+		{"/user/progress", "", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if test.header != "" {
+			r.Header.Set(Progress.HEADER, test.header)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, r)
+		if w.Code != test.status || w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: unexpected answer %d %s", test.target, w.Code, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			if !strings.Contains(w.Body.String(), `"code":"`) {
+				t.Errorf("%s: expected an HTTPError, got %s", test.target, w.Body)
+			}
+			continue
+		}
+		var progress map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+			t.Fatal(err)
+		}
+		if progress["id"] != "42" || progress["received"] != float64(1024) || progress["total"] != float64(4096) || progress["done"] != false || progress["percent"] != float64(25) || progress["updatedAt"] == nil {
+			t.Errorf("unexpected progress %s", w.Body)
+		}
+	}
+}
+
+func TestUploadProgressTrackerError(t *testing.T) {
+	w := httptest.NewRecorder()
+	progressEngine(failingTracker{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/progress?X-Progress-ID=42", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "redis") {
+		t.Errorf("unexpected answer %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,213 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"html/template"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// PanicError is the error of a handler that panicked, recovered by Recovery.
+type PanicError struct {
+	// Value is the value the handler panicked with.
+	Value any
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error returns the value of the panic.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value of the panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Recovery returns a middleware that recovers from the panics of the handlers, so that a panic
+// fails its request with a 500 instead of the connection:
+//   - the panic and its stack are logged to the logger of the engine (Engine.Logger),
+//   - the request is answered by the ErrorHandler of the engine with a *PanicError, which is
+//     also recorded in ctx.Errors,
+//   - in debug mode (Engine.Debug), the clients accepting HTML get a page showing the stack,
+//     the request headers, the route and its parameters instead,
+//   - if the handler had already started writing its response, the *PanicError is only
+//     recorded in ctx.Errors, since another answer would be mixed up with the one under way,
+//   - panics caused by a client gone away (broken pipe, connection reset) are logged on a
+//     single line and not answered, since nobody is listening anymore,
+//   - http.ErrAbortHandler is panicked again, to abort the response as net/http intends.
+//
+// Example:
+//
+//	engine.Debug = os.Getenv("DEBUG") != ""
+//	g := engine.Router.NewGroup("user")
+//	g.MiddlewareRegister(web.Recovery())
+func Recovery() MiddlewareHandler {
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			w := &writeRecorder{ResponseWriter: ctx.W}
+			ctx.W = w
+			defer func() {
+				ctx.W = w.ResponseWriter
+				value := recover()
+				if value == nil {
+					return
+				}
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+				if isBrokenPipe(value) {
+					ctx.Error(fmt.Errorf("client gone: %v", value))
+					logger(ctx).Printf("[WARN] %s %s: client gone: %v", ctx.R.Method, ctx.R.URL.Path, value)
+					return
+				}
+				err := &PanicError{Value: value, Stack: debug.Stack()}
+				logger(ctx).Printf("[PANIC] %s %s: %v\n%s", ctx.R.Method, ctx.R.URL.Path, value, err.Stack)
+				if w.written {
+					ctx.Error(err)
+					return
+				}
+				if ctx.Debug && ctx.NegotiateFormat("text/html", "application/json") == "text/html" {
+					ctx.Error(err)
+					_ = renderDebugPage(ctx, err)
+					return
+				}
+				ctx.HandleError(err)
+			}()
+			next(ctx)
+		}
+	}
+}
+
+// writeRecorder records whether a handler has started writing its response.
+type writeRecorder struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeRecorder) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writeRecorder) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the writer of the response, so that http.NewResponseController can flush
+// and hijack it.
+func (w *writeRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isBrokenPipe reports whether a panic comes from writing to a connection the client closed.
+func isBrokenPipe(value any) bool {
+	err, ok := value.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
+
+// sensitiveHeaders are masked on the debug page.
+var sensitiveHeaders = map[string]bool{"Authorization": true, "Proxy-Authorization": true, "Cookie": true, "Set-Cookie": true}
+
+// debugTemplate is the page shown in debug mode, in the spirit of the technical 500 page of Django.
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex,nofollow">
+<title>{{.Value}} at {{.Path}}</title>
+<style>
+body { font: 14px sans-serif; margin: 0; color: #222; }
+header { background: #ffc; padding: 12px 20px; border-bottom: 1px solid #ddd; }
+header h1 { font-weight: normal; margin: 0 0 8px; }
+section { padding: 12px 20px; border-bottom: 1px solid #eee; }
+h2 { font-size: 16px; }
+table { border-collapse: collapse; }
+th { text-align: right; padding: 2px 12px 2px 0; vertical-align: top; color: #666; font-weight: normal; }
+td { font-family: monospace; }
+pre { background: #f7f7f7; padding: 12px; overflow: auto; }
+</style>
+</head>
+<body>
+<header>
+<h1>panic: {{.Value}}</h1>
+<table>
+<tr><th>Request Method:</th><td>{{.Method}}</td></tr>
+<tr><th>Request URL:</th><td>{{.URL}}</td></tr>
+<tr><th>Route:</th><td>{{if .Route}}{{.Route}}{{else}}-{{end}}</td></tr>
+<tr><th>Value Type:</th><td>{{printf "%T" .Value}}</td></tr>
+</table>
+</header>
+<section>
+<h2>Stack trace</h2>
+<pre>{{.Stack}}</pre>
+</section>
+<section>
+<h2>Path parameters</h2>
+{{if .Params}}<table>{{range .Params}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}</table>{{else}}<p>No path parameters.</p>{{end}}
+</section>
+<section>
+<h2>Request headers</h2>
+<table>{{range .Headers}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}</table>
+</section>
+<section>
+<p>You are seeing this page because Engine.Debug is enabled. Disable it in production: this page shows the internals of your application.</p>
+</section>
+</body>
+</html>
+`))
+
+type debugEntry struct {
+	Name  string
+	Value string
+}
+
+// renderDebugPage answers the request with the debug page of a panic.
+func renderDebugPage(ctx *context.Context, err *PanicError) error {
+	params := make([]debugEntry, 0, len(ctx.Params()))
+	for name, value := range ctx.Params() {
+		params = append(params, debugEntry{Name: name, Value: value})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	headers := make([]debugEntry, 0, len(ctx.R.Header))
+	for name, values := range ctx.R.Header {
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[name] {
+			value = "********"
+		}
+		headers = append(headers, debugEntry{Name: name, Value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	ctx.W.Header().Set("Cache-Control", "no-store")
+	return ctx.Render(http.StatusInternalServerError, Render.HTMLRender{
+		Template:   debugTemplate,
+		Name:       "debug",
+		IsTemplate: true,
+		Data: map[string]any{
+			"Value":   err.Value,
+			"Method":  ctx.R.Method,
+			"Path":    ctx.R.URL.Path,
+			"URL":     ctx.R.URL.String(),
+			"Route":   ctx.Route(),
+			"Stack":   string(err.Stack),
+			"Params":  params,
+			"Headers": headers,
+		},
+	})
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"log"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// panicking returns an engine whose route /api/panic/:id panics with the value of panics,
+// after writing written if any, along with the logs of the engine.
+func panicking(value any, written string) (*Engine, *bytes.Buffer) {
+	logs := &bytes.Buffer{}
+	e := NewEngine()
+	e.Logger = log.New(logs, "", 0)
+	g := e.Router.NewGroup("api")
+	g.MiddlewareRegister(Recovery())
+	g.Get("/panic/:id", func(ctx *context.Context) {
+		if written != "" {
+			_ = ctx.String(http.StatusOK, written)
+		}
+		panic(value)
+	})
+	return e, logs
+}
+
+func TestRecovery(t *testing.T) {
+	e, logs := panicking("boom", "")
+	var handled error
+	e.ErrorHandler = func(ctx *context.Context, err error) {
+		handled = err
+		DefaultErrorHandler(ctx, err)
+	}
+	w := serve(e, http.MethodGet, "/api/panic/1", nil)
+	var panicErr *PanicError
+	if w.Code != http.StatusInternalServerError || !errors.As(handled, &panicErr) || panicErr.Value != "boom" {
+		t.Errorf("expected a 500 answered by the error handler, got %d %v", w.Code, handled)
+	}
+	if strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("the stack should not be sent: %s", w.Body)
+	}
+	if !strings.Contains(logs.String(), "[PANIC] GET /api/panic/1: boom") || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("expected the panic and its stack to be logged, got %q", logs)
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	e, _ := panicking(http.ErrAbortHandler, "")
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to be panicked again, got %v", value)
+		}
+	}()
+	serve(e, http.MethodGet, "/api/panic/1", nil)
+	t.Error("expected a panic")
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	e, logs := panicking(fmt.Errorf("write tcp: %w", syscall.EPIPE), "")
+	handled := false
+	e.ErrorHandler = func(ctx *context.Context, err error) { handled = true }
+	w := serve(e, http.MethodGet, "/api/panic/1", nil)
+	if handled || w.Body.Len() != 0 {
+		t.Errorf("a client gone away should not be answered: %q", w.Body)
+	}
+	if logged := logs.String(); !strings.Contains(logged, "[WARN] GET /api/panic/1: client gone") || strings.Contains(logged, "goroutine") {
+		t.Errorf("expected a single line, got %q", logged)
+	}
+}
+
+func TestRecoveryDebugPage(t *testing.T) {
+	e, _ := panicking("boom", "")
+	e.Debug = true
+	w := serve(e, http.MethodGet, "/api/panic/42", nil, "Accept", "text/html", "Authorization", "Bearer secret", "Cookie", "session=secret", "X-Trace", "abc")
+	body := w.Body.String()
+	if w.Code != http.StatusInternalServerError || !strings.Contains(body, "panic: boom") || !strings.Contains(body, "goroutine") {
+		t.Fatalf("expected the debug page, got %d %s", w.Code, body)
+	}
+	if strings.Contains(body, "secret") || strings.Count(body, "********") != 2 || !strings.Contains(body, "abc") {
+		t.Errorf("expected the sensitive headers to be masked: %s", body)
+	}
+	if !strings.Contains(body, "<th>id</th><td>42</td>") {
+		t.Errorf("expected the path parameters: %s", body)
+	}
+
+	// clients not accepting HTML get the error handler
+	if w = serve(e, http.MethodGet, "/api/panic/42", nil, "Accept", "application/json"); strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("unexpected debug page: %s", w.Body)
+	}
+}
+
+func TestRecoveryWritten(t *testing.T) {
+	e, logs := panicking("boom", "partial")
+	e.Debug = true
+	handled := false
+	e.ErrorHandler = func(ctx *context.Context, err error) { handled = true }
+	w := serve(e, http.MethodGet, "/api/panic/1", nil, "Accept", "text/html")
+	if handled || w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected the response under way to be left alone, got %d %q", w.Code, w.Body)
+	}
+	if !strings.Contains(logs.String(), "[PANIC]") {
+		t.Errorf("expected the panic to be logged, got %q", logs)
+	}
+}
@@ -0,0 +1,211 @@
+package web
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Constant"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"strings"
+)
+
+// The actions of a resource, as named in ResourceOptions.Only and ResourceOptions.Except.
+const (
+	ACTION_INDEX   = "index"
+	ACTION_NEW     = "new"
+	ACTION_CREATE  = "create"
+	ACTION_SHOW    = "show"
+	ACTION_EDIT    = "edit"
+	ACTION_UPDATE  = "update"
+	ACTION_DESTROY = "destroy"
+)
+
+// Indexer is implemented by the controllers listing their resource: GET /articles
+type Indexer interface {
+	Index(ctx *context.Context)
+}
+
+// Newer is implemented by the controllers serving the page creating a resource: GET /articles/new
+type Newer interface {
+	New(ctx *context.Context)
+}
+
+// Creator is implemented by the controllers creating a resource: POST /articles
+type Creator interface {
+	Create(ctx *context.Context)
+}
+
+// Shower is implemented by the controllers showing a resource: GET /articles/:id
+type Shower interface {
+	Show(ctx *context.Context)
+}
+
+// Editor is implemented by the controllers serving the page editing a resource: GET /articles/:id/edit
+type Editor interface {
+	Edit(ctx *context.Context)
+}
+
+// Updater is implemented by the controllers updating a resource: PUT and PATCH /articles/:id
+type Updater interface {
+	Update(ctx *context.Context)
+}
+
+// Destroyer is implemented by the controllers deleting a resource: DELETE /articles/:id
+type Destroyer interface {
+	Destroy(ctx *context.Context)
+}
+
+// ResourceOptions are the options of a resource registered with Resource.
+type ResourceOptions struct {
+	// Only restricts the routes registered to these actions, e.g. ACTION_INDEX and ACTION_SHOW.
+	Only []string
+	// Except leaves out the routes of these actions.
+	Except []string
+	// Param is the name of the path parameter identifying a resource, "id" by default.
+	// The nested resources name it after the singular of the resource, e.g. "article_id".
+	Param string
+	// Middlewares are applied to every route of the resource.
+	Middlewares []MiddlewareHandler
+}
+
+// Resource is a resource registered on a router group, see routerGroup.Resource
+type Resource struct {
+	group *routerGroup
+	// path is the path of the collection, e.g. "/articles" or "/articles/:article_id/comments".
+	path string
+	// nestedParam is the path parameter identifying the resource in nested resources, e.g. "article_id".
+	nestedParam string
+}
+
+// Resource registers the conventional routes of a resource, from the actions its controller
+// implements (see Indexer, Newer, Creator, Shower, Editor, Updater and Destroyer):
+//
+//	GET    /articles           Index
+//	GET    /articles/new       New
+//	POST   /articles           Create
+//	GET    /articles/:id       Show
+//	GET    /articles/:id/edit  Edit
+//	PUT    /articles/:id       Update
+//	PATCH  /articles/:id       Update
+//	DELETE /articles/:id       Destroy
+//
+// The routes can be restricted with ResourceOptions.Only and ResourceOptions.Except; at most
+// one ResourceOptions is expected. It panics if an action of the options is unknown, or if the
+// controller implements none of the actions allowed.
+//
+// Example:
+//
+//	type ArticleController struct{}
+//
+//	func (ArticleController) Index(ctx *context.Context) { ... }
+//	func (ArticleController) Show(ctx *context.Context)  { ... }
+//
+//	g := engine.Router.NewGroup("blog")
+//	articles := g.Resource("/articles", ArticleController{})
+//	// GET /blog/articles/:article_id/comments, POST /blog/articles/:article_id/comments, ...
+//	articles.Resource("/comments", CommentController{}, web.ResourceOptions{Except: []string{web.ACTION_NEW, web.ACTION_EDIT}})
+func (r *routerGroup) Resource(name string, controller any, options ...ResourceOptions) *Resource {
+	return registerResource(r, "/"+strings.Trim(name, "/"), controller, options)
+}
+
+// Resource registers a resource nested in res, below the path identifying one of its
+// resources, e.g. /articles/:article_id/comments. See routerGroup.Resource
+func (res *Resource) Resource(name string, controller any, options ...ResourceOptions) *Resource {
+	return registerResource(res.group, res.path+"/:"+res.nestedParam+"/"+strings.Trim(name, "/"), controller, options)
+}
+
+// Path returns the path of the collection of the resource, e.g. "/articles/:article_id/comments".
+func (res *Resource) Path() string {
+	return res.path
+}
+
+func registerResource(group *routerGroup, path string, controller any, options []ResourceOptions) *Resource {
+	var option ResourceOptions
+	if len(options) > 0 {
+		option = options[0]
+	}
+	param := option.Param
+	if param == "" {
+		param = "id"
+	}
+	actions := resourceActions(option)
+	member := path + "/:" + param
+	registered := 0
+	register := func(action string, route string, methods []string, handler Handler) {
+		if !actions[action] {
+			return
+		}
+		for _, method := range methods {
+			group.bind(route, method, handler, option.Middlewares...)
+		}
+		registered++
+	}
+	if c, ok := controller.(Indexer); ok {
+		register(ACTION_INDEX, path, []string{Constant.GET}, c.Index)
+	}
+	if c, ok := controller.(Newer); ok {
+		register(ACTION_NEW, path+"/new", []string{Constant.GET}, c.New)
+	}
+	if c, ok := controller.(Creator); ok {
+		register(ACTION_CREATE, path, []string{Constant.POST}, c.Create)
+	}
+	if c, ok := controller.(Shower); ok {
+		register(ACTION_SHOW, member, []string{Constant.GET}, c.Show)
+	}
+	if c, ok := controller.(Editor); ok {
+		register(ACTION_EDIT, member+"/edit", []string{Constant.GET}, c.Edit)
+	}
+	if c, ok := controller.(Updater); ok {
+		register(ACTION_UPDATE, member, []string{Constant.PUT, Constant.PATCH}, c.Update)
+	}
+	if c, ok := controller.(Destroyer); ok {
+		register(ACTION_DESTROY, member, []string{Constant.DELETE}, c.Destroy)
+	}
+	if registered == 0 {
+		panic("[ERROR] The controller of resource [" + path + "] implements none of the actions allowed")
+	}
+	return &Resource{group: group, path: path, nestedParam: singular(path[strings.LastIndex(path, "/")+1:]) + "_" + param}
+}
+
+// resourceActions returns the set of the actions registered with options.
+func resourceActions(options ResourceOptions) map[string]bool {
+	all := []string{ACTION_INDEX, ACTION_NEW, ACTION_CREATE, ACTION_SHOW, ACTION_EDIT, ACTION_UPDATE, ACTION_DESTROY}
+	known := make(map[string]bool, len(all))
+	for _, action := range all {
+		known[action] = true
+	}
+	for _, action := range append(append([]string{}, options.Only...), options.Except...) {
+		if !known[action] {
+			panic("[ERROR] Unknown resource action [" + action + "]")
+		}
+	}
+	actions := known
+	if len(options.Only) > 0 {
+		actions = make(map[string]bool, len(options.Only))
+		for _, action := range options.Only {
+			actions[action] = true
+		}
+	}
+	for _, action := range options.Except {
+		delete(actions, action)
+	}
+	return actions
+}
+
+// uncountable are the nouns ending in "s" that are their own singular.
+var uncountable = map[string]bool{"news": true, "series": true, "species": true, "status": true}
+
+// singular returns the singular of an English plural noun, for the common cases:
+// "categories" gives "category", "boxes" gives "box", "articles" gives "article" and "news"
+// stays "news".
+func singular(noun string) string {
+	switch {
+	case uncountable[noun]:
+		return noun
+	case strings.HasSuffix(noun, "ies") && len(noun) > 3:
+		return noun[:len(noun)-3] + "y"
+	case strings.HasSuffix(noun, "sses"), strings.HasSuffix(noun, "shes"), strings.HasSuffix(noun, "ches"),
+		strings.HasSuffix(noun, "xes"), strings.HasSuffix(noun, "zzes"):
+		return noun[:len(noun)-2]
+	case strings.HasSuffix(noun, "s") && !strings.HasSuffix(noun, "ss"):
+		return noun[:len(noun)-1]
+	}
+	return noun
+}
@@ -0,0 +1,131 @@
+package web
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fullController implements every action, answering with the name of the action and the
+// path parameters.
+type fullController struct{}
+
+func answer(ctx *context.Context, action string) {
+	_ = ctx.String(http.StatusOK, action+" "+ctx.Param("id")+ctx.Param("article_id"))
+}
+
+func (fullController) Index(ctx *context.Context)   { answer(ctx, ACTION_INDEX) }
+func (fullController) New(ctx *context.Context)     { answer(ctx, ACTION_NEW) }
+func (fullController) Create(ctx *context.Context)  { answer(ctx, ACTION_CREATE) }
+func (fullController) Show(ctx *context.Context)    { answer(ctx, ACTION_SHOW) }
+func (fullController) Edit(ctx *context.Context)    { answer(ctx, ACTION_EDIT) }
+func (fullController) Update(ctx *context.Context)  { answer(ctx, ACTION_UPDATE) }
+func (fullController) Destroy(ctx *context.Context) { answer(ctx, ACTION_DESTROY) }
+
+type indexController struct{}
+
+func (indexController) Index(ctx *context.Context) { answer(ctx, ACTION_INDEX) }
+
+func TestResource(t *testing.T) {
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	articles := g.Resource("/articles", fullController{})
+	g.Resource("/drafts", fullController{}, ResourceOptions{Only: []string{ACTION_INDEX, ACTION_SHOW}})
+	g.Resource("/archives", fullController{}, ResourceOptions{Except: []string{ACTION_NEW, ACTION_EDIT, ACTION_DESTROY}})
+	comments := articles.Resource("/comments", fullController{}, ResourceOptions{Only: []string{ACTION_INDEX, ACTION_SHOW}})
+	if comments.Path() != "/articles/:article_id/comments" {
+		t.Errorf("unexpected nested path %s", comments.Path())
+	}
+
+	cases := []struct {
+		method string
+		target string
+		want   string // want is the body expected, or empty if the route should not exist
+	}{
+		{http.MethodGet, "/api/articles", "index "},
+		{http.MethodGet, "/api/articles/new", "new "},
+		{http.MethodPost, "/api/articles", "create "},
+		{http.MethodGet, "/api/articles/7", "show 7"},
+		{http.MethodGet, "/api/articles/7/edit", "edit 7"},
+		{http.MethodPut, "/api/articles/7", "update 7"},
+		{http.MethodPatch, "/api/articles/7", "update 7"},
+		{http.MethodDelete, "/api/articles/7", "destroy 7"},
+		{http.MethodGet, "/api/drafts/7", "show 7"},
+		{http.MethodPost, "/api/drafts", ""},
+		{http.MethodDelete, "/api/drafts/7", ""},
+		{http.MethodPatch, "/api/archives/7", "update 7"},
+		{http.MethodDelete, "/api/archives/7", ""},
+		{http.MethodGet, "/api/articles/7/comments", "index 7"},
+		{http.MethodGet, "/api/articles/7/comments/3", "show 37"},
+		{http.MethodPost, "/api/articles/7/comments", ""},
+	}
+	for _, c := range cases {
+		w := serve(e, c.method, c.target, nil)
+		if c.want == "" {
+			if w.Code == http.StatusOK {
+				t.Errorf("%s %s: expected no route, got %q", c.method, c.target, w.Body)
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || w.Body.String() != c.want {
+			t.Errorf("%s %s: expected %q, got %d %q", c.method, c.target, c.want, w.Code, w.Body)
+		}
+	}
+}
+
+func TestResourcePanics(t *testing.T) {
+	cases := []struct {
+		name       string
+		controller any
+		options    ResourceOptions
+		message    string
+	}{
+		{"unknown only", fullController{}, ResourceOptions{Only: []string{"list"}}, "Unknown resource action [list]"},
+		{"unknown except", fullController{}, ResourceOptions{Except: []string{"delete"}}, "Unknown resource action [delete]"},
+		{"no action", struct{}{}, ResourceOptions{}, "implements none of the actions allowed"},
+		{"no action allowed", indexController{}, ResourceOptions{Except: []string{ACTION_INDEX}}, "implements none of the actions allowed"},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if message, _ := recover().(string); !strings.Contains(message, c.message) {
+					t.Errorf("%s: expected a panic with %q, got %q", c.name, c.message, message)
+				}
+			}()
+			NewEngine().Router.NewGroup("api").Resource("/articles", c.controller, c.options)
+		}()
+	}
+}
+
+func TestResourceNestedParam(t *testing.T) {
+	g := NewEngine().Router.NewGroup("api")
+	cases := []struct {
+		name    string
+		options ResourceOptions
+		want    string
+	}{
+		{"/articles", ResourceOptions{}, "/articles/:article_id/comments"},
+		{"/categories", ResourceOptions{}, "/categories/:category_id/comments"},
+		{"/boxes", ResourceOptions{}, "/boxes/:box_id/comments"},
+		{"/news", ResourceOptions{}, "/news/:news_id/comments"},
+		{"/users", ResourceOptions{Param: "name"}, "/users/:user_name/comments"},
+	}
+	for _, c := range cases {
+		parent := g.Resource(c.name, indexController{}, c.options)
+		if nested := parent.Resource("/comments", indexController{}); nested.Path() != c.want {
+			t.Errorf("%s: expected %s, got %s", c.name, c.want, nested.Path())
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	for plural, want := range map[string]string{
+		"articles": "article", "categories": "category", "boxes": "box", "news": "news",
+		"addresses": "address", "wishes": "wish", "matches": "match", "class": "class", "data": "data",
+	} {
+		if got := singular(plural); got != want {
+			t.Errorf("%s: expected %s, got %s", plural, want, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tag returns a route middleware appending name to the X-Middlewares header of the response.
+func tag(name string) MiddlewareHandler {
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			ctx.W.Header().Add("X-Middlewares", name)
+			next(ctx)
+		}
+	}
+}
+
+func TestRouteParams(t *testing.T) {
+	e := NewEngine()
+	g := e.Router.NewGroup("user")
+	g.Any("/:id", func(ctx *context.Context) {
+		_ = ctx.String(http.StatusOK, "%s %s", ctx.R.Method, ctx.Param("id"))
+	}, tag("any"))
+	g.Get("/:id/orders/:order", func(ctx *context.Context) {
+		_ = ctx.String(http.StatusOK, "%s %s %d", ctx.Param("id"), ctx.Param("order"), len(ctx.Params()))
+	}, tag("orders"))
+	g.Get("/me", func(ctx *context.Context) {
+		_ = ctx.String(http.StatusOK, "me %d", len(ctx.Params()))
+	}, tag("me"))
+
+	tests := []struct {
+		method      string
+		target      string
+		body        string
+		middlewares string
+	}{
+		{http.MethodGet, "/user/42/orders/7", "42 7 2", "orders"},
+		{http.MethodGet, "/user/43/orders/8", "43 8 2", "orders"},
+		{http.MethodDelete, "/user/42", "DELETE 42", "any"},
+		{http.MethodGet, "/user/me", "me 0", "me"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Code != http.StatusOK || w.Body.String() != test.body {
+			t.Errorf("%s %s: unexpected answer %d %s", test.method, test.target, w.Code, w.Body)
+		}
+		if middlewares := strings.Join(w.Header().Values("X-Middlewares"), ","); middlewares != test.middlewares {
+			t.Errorf("%s %s: unexpected middlewares %q", test.method, test.target, middlewares)
+		}
+	}
+}
+
+func TestRouteMiddlewareOrder(t *testing.T) {
+	e := NewEngine()
+	g := e.Router.NewGroup("user")
+	g.MiddlewareRegister(tag("group"))
+	g.Get("/:id", func(ctx *context.Context) {
+		_ = ctx.String(http.StatusOK, "%s", ctx.Param("id"))
+	}, tag("first"), tag("second"))
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/42", nil))
+	// the middlewares registered last wrap the others, and the route ones wrap the group ones
+	if middlewares := strings.Join(w.Header().Values("X-Middlewares"), ","); w.Body.String() != "42" || middlewares != "second,first,group" {
+		t.Errorf("unexpected answer %s with middlewares %q", w.Body, middlewares)
+	}
+}
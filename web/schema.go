@@ -0,0 +1,135 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"github.com/Jerry20000730/Gjango/web/Schema"
+	"mime"
+	"net/http"
+)
+
+// SchemaValidation configures ValidateSchema and routerGroup.BodySchema.
+type SchemaValidation struct {
+	// Body caches the request body, so that the handler can bind it after its validation;
+	// see context.CacheBody. A Body.MaxSize of zero means Constant.DEFAULT_VALIDATED_BODY_MAX_SIZE,
+	// and a negative one no limit.
+	Body context.BodyCacheConfig
+}
+
+// ValidateSchema returns a middleware validating the JSON body of the requests against s,
+// e.g. a schema loaded from a file, or generated from a struct with Schema.For. The body is
+// cached (see context.CacheBody), so that the handler can still bind it.
+//
+// Requests whose body does not match s are answered by the ErrorHandler of the engine with a
+// 400 Bad Request HTTPError listing every violation in Errors, requests without a body with
+// a VALIDATE_REQUIRED error on the field "body", bodies that are not JSON with 415
+// Unsupported Media Type, and bodies larger than the limit of the options with 413 Request
+// Entity Too Large. See routerGroup.BodySchema to also document the schema of a route in the
+// OpenAPI document of the engine. At most one SchemaValidation is expected.
+func ValidateSchema(s *Schema.Schema, options ...SchemaValidation) MiddlewareHandler {
+	var option SchemaValidation
+	if len(options) > 0 {
+		option = options[0]
+	}
+	resolve := s.DefsResolver()
+	body := validatedBody(option.Body)
+	return func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			if err := validateBody(ctx, s, resolve, body); err != nil {
+				ctx.HandleError(err)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// validateBody validates the JSON body of a request against s.
+func validateBody(ctx *context.Context, s *Schema.Schema, resolve Schema.Resolver, body context.BodyCacheConfig) error {
+	if err := ctx.CacheBody(body); err != nil {
+		return err
+	}
+	data, err := ctx.Body()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return validationFailed(ctx, "the request body is missing",
+			[]error{I18n.NewError(I18n.VALIDATE_REQUIRED, map[string]any{"field": "body"})})
+	}
+	contentType := ctx.R.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || !isJSON(mediaType) {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"unsupported Content-Type "+contentType+", expected application/json")
+	}
+	value, err := Schema.Decode(data)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, "bad_request", "malformed request body").Wrap(err)
+	}
+	if err := s.ValidateAt(value, "", resolve); err != nil {
+		return validationFailed(ctx, "the request body does not match its schema", unjoin(err))
+	}
+	return nil
+}
+
+// BodySchema validates the JSON body of the requests of the route name of the HTTP request
+// method of group against s (see ValidateSchema), and documents s as the request body of the
+// route in the OpenAPI document of the engine, its definitions becoming components. The route
+// must be registered first. The body is validated right before the handler, after all the
+// middlewares of the group and of the route, e.g. the authentication and Recovery.
+//
+// Example:
+//
+//	g.Post("/users", createUser)
+//	g.BodySchema(http.MethodPost, "/users", Schema.For(User{}))
+//
+// At most one SchemaValidation is expected.
+func (r *routerGroup) BodySchema(method string, name string, s *Schema.Schema, options ...SchemaValidation) {
+	if _, ok := r.handleFuncMap[name][method]; !ok {
+		panic("[ERROR] No binding of request method [" + method + "] for the route [" + name + "]")
+	}
+	if r.bodySchemas == nil {
+		r.bodySchemas = make(map[string]map[string]*Schema.Schema)
+	}
+	if r.bodySchemas[name] == nil {
+		r.bodySchemas[name] = make(map[string]*Schema.Schema)
+	}
+	if _, ok := r.bodySchemas[name][method]; ok {
+		panic("[ERROR] Repeated body schema of request method [" + method + "] for the route [" + name + "]")
+	}
+	r.bodySchemas[name][method] = s
+	r.handleFuncMap[name][method] = ValidateSchema(s, options...)(r.handleFuncMap[name][method])
+}
+
+// SchemaHandler returns a handler serving s as JSON, e.g. the schema of a form for the
+// front-end generated with Schema.For.
+//
+// Example:
+//
+//	schemas := engine.Router.NewGroup("schemas")
+//	schemas.Get("/user.json", web.SchemaHandler(Schema.For(User{})))
+func SchemaHandler(s *Schema.Schema) Handler {
+	data, err := Render.EncodeJSON(s)
+	return func(ctx *context.Context) {
+		if err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		_ = ctx.JSON(http.StatusOK, json.RawMessage(data))
+	}
+}
+
+// componentSchema returns the schema of s in an OpenAPI document, its definitions added to
+// the components of generator unless a component of the same name is already defined.
+func componentSchema(generator *Schema.Generator, s *Schema.Schema) *Schema.Schema {
+	rebased := s.Rebase(generator.RefPrefix)
+	for name, def := range rebased.Defs {
+		if _, ok := generator.Defs[name]; !ok {
+			generator.Defs[name] = def
+		}
+	}
+	rebased.Schema, rebased.Defs = "", nil
+	return rebased
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Schema"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type signup struct {
+	Name  string `json:"name" gjango:"required,min=2"`
+	Email string `json:"email" gjango:"required"`
+}
+
+// schemaEngine returns an engine validating the bodies of POST /api/signup against the schema
+// of signup, behind a group middleware rejecting the requests without an Authorization header.
+func schemaEngine(options ...SchemaValidation) *Engine {
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	g.MiddlewareRegister(func(next Handler) Handler {
+		return func(ctx *context.Context) {
+			if ctx.R.Header.Get("Authorization") == "" {
+				ctx.HandleError(NewHTTPError(http.StatusUnauthorized, "unauthorized", ""))
+				return
+			}
+			next(ctx)
+		}
+	})
+	g.Post("/signup", func(ctx *context.Context) {
+		in := &signup{}
+		if err := ctx.ParseJSON(in, false, true); err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		_ = ctx.String(http.StatusCreated, in.Name)
+	})
+	g.BodySchema(http.MethodPost, "/signup", Schema.For(signup{}), options...)
+	return e
+}
+
+func TestBodySchema(t *testing.T) {
+	e := schemaEngine()
+	headers := []string{"Content-Type", "application/json", "Authorization", "Bearer token"}
+	if w := serve(e, http.MethodPost, "/api/signup", strings.NewReader(`{"name": "jo", "email": "jo@example.com"}`), headers...); w.Code != http.StatusCreated || w.Body.String() != "jo" {
+		t.Errorf("expected the handler to bind the body, got %d %s", w.Code, w.Body)
+	}
+
+	w := serve(e, http.MethodPost, "/api/signup", strings.NewReader(`{"name": "j"}`), headers...)
+	var answer struct {
+		Code   string           `json:"code"`
+		Errors []map[string]any `json:"errors"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &answer)
+	if w.Code != http.StatusBadRequest || answer.Code != "validation_failed" || len(answer.Errors) != 2 {
+		t.Fatalf("expected 400 with 2 errors, got %d %s", w.Code, w.Body)
+	}
+	fields := map[any]bool{answer.Errors[0]["field"]: true, answer.Errors[1]["field"]: true}
+	if !fields["name"] || !fields["email"] {
+		t.Errorf("unexpected errors %v", answer.Errors)
+	}
+
+	if w = serve(e, http.MethodPost, "/api/signup", nil, headers...); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"body"`) {
+		t.Errorf("expected a missing body, got %d %s", w.Code, w.Body)
+	}
+	if w = serve(e, http.MethodPost, "/api/signup", strings.NewReader(`name=jo`), "Content-Type", "text/plain", "Authorization", "Bearer token"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d %s", w.Code, w.Body)
+	}
+	large := `{"name": "` + strings.Repeat("a", 2<<20) + `", "email": "jo@example.com"}`
+	if w = serve(e, http.MethodPost, "/api/signup", strings.NewReader(large), headers...); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 by default, got %d", w.Code)
+	}
+	// the body is validated after the middlewares of the group
+	if w = serve(e, http.MethodPost, "/api/signup", strings.NewReader(large), "Content-Type", "application/json"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestBodySchemaMaxSize(t *testing.T) {
+	e := schemaEngine(SchemaValidation{Body: context.BodyCacheConfig{MaxSize: 16}})
+	w := serve(e, http.MethodPost, "/api/signup", strings.NewReader(`{"name": "jo", "email": "jo@example.com"}`), "Content-Type", "application/json", "Authorization", "Bearer token")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestSchemaHandler(t *testing.T) {
+	e := NewEngine()
+	e.Router.NewGroup("schemas").Get("/signup.json", SchemaHandler(Schema.For(signup{})))
+	w := serve(e, http.MethodGet, "/schemas/signup.json", nil)
+	var s struct {
+		Type       string         `json:"type"`
+		Required   []string       `json:"required"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected answer %d %s", w.Code, w.Body)
+	}
+	if s.Type != "object" || len(s.Required) != 2 || s.Properties["name"] == nil {
+		t.Errorf("unexpected schema %s", w.Body)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+}
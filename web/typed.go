@@ -0,0 +1,198 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/Jerry20000730/Gjango/web/Binding"
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/Render"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// TypedHandler the backend logic function of a route taking its input as In, bound from the
+// request, and returning its output as Out, see Typed
+type TypedHandler[In any, Out any] func(ctx *context.Context, in In) (Out, error)
+
+// StatusCoder is implemented by the outputs of typed handlers answered with another status
+// code than 200 OK, e.g. 201 Created.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// TypedOptions are the options of a typed handler, see Typed.
+type TypedOptions struct {
+	// DisallowUnknownFields rejects the JSON bodies with keys that do not match a field of In,
+	// like context.ParseJSON with disallowUnknownField.
+	DisallowUnknownFields bool
+}
+
+// HandleOptions are the options of a route registered with Handle.
+type HandleOptions struct {
+	TypedOptions
+	// Middlewares are applied to the route.
+	Middlewares []MiddlewareHandler
+	// Doc documents the route in the OpenAPI document of the engine, like routerGroup.Describe,
+	// e.g. with its summary and its error responses.
+	Doc RouteDoc
+}
+
+// typedRoute is the input and output types of a route registered with Handle.
+type typedRoute struct {
+	in  reflect.Type
+	out reflect.Type
+}
+
+// Typed adapts a typed handler to a Handler, doing the binding, the validation and the
+// rendering the handler would otherwise do itself:
+//   - In, a struct or a pointer to a struct, is bound from the body of the request, as JSON
+//     or as a form depending on its Content-Type, and from the path parameters, the query
+//     string and the headers for the fields tagged path, query and header (see
+//     Binding.BindSources). The gjango rules of all the fields are checked, and the keys of
+//     a JSON body not matching a field are ignored unless TypedOptions.DisallowUnknownFields,
+//   - Out is answered as JSON or XML depending on the Accept header of the request, with
+//     200 OK, or the status code of Out if it implements StatusCoder. An Out implementing
+//     Render.Render is rendered as it is, and a nil pointer is answered with 204 No Content,
+//   - binding and validation errors, and the error returned by the handler, are answered
+//     by the ErrorHandler of the engine (see context.HandleError).
+//
+// Example:
+//
+//	type GetUser struct {
+//		ID     int    `path:"id" gjango:"required"`
+//		Fields string `query:"fields"`
+//	}
+//
+//	g.Get("/users/:id", web.Typed(func(ctx *context.Context, in GetUser) (*User, error) {
+//		user, ok := users[in.ID]
+//		if !ok {
+//			return nil, web.NewHTTPError(http.StatusNotFound, "user_not_found", "")
+//		}
+//		return user, nil
+//	}))
+//
+// At most one TypedOptions is expected. Typed panics if the gjango tags of In are invalid
+// (see Binding.CheckTags), so that a bad tag fails at the registration of the route.
+func Typed[In any, Out any](handler TypedHandler[In, Out], options ...TypedOptions) Handler {
+	var option TypedOptions
+	if len(options) > 0 {
+		option = options[0]
+	}
+	if err := Binding.CheckTags(reflect.TypeOf((*In)(nil)).Elem()); err != nil {
+		panic("[ERROR] Invalid input of a typed handler: " + err.Error())
+	}
+	return func(ctx *context.Context) {
+		in, err := bindTyped[In](ctx, option)
+		if err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		out, err := handler(ctx, in)
+		if err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		if err := renderTyped(ctx, out); err != nil {
+			ctx.Error(err)
+		}
+	}
+}
+
+// Handle registers a typed handler (see Typed) for the route name and the HTTP request method
+// of group, and records the types of its input and output for the documentation of the route.
+//
+// Example:
+//
+//	web.Handle(g, http.MethodPost, "/users", func(ctx *context.Context, in CreateUser) (*User, error) {
+//		return createUser(in)
+//	}, web.HandleOptions{
+//		TypedOptions: web.TypedOptions{DisallowUnknownFields: true},
+//		Doc:          web.RouteDoc{Summary: "Create a user"},
+//	})
+//
+// At most one HandleOptions is expected.
+func Handle[In any, Out any](group *routerGroup, method string, name string, handler TypedHandler[In, Out], options ...HandleOptions) {
+	var option HandleOptions
+	if len(options) > 0 {
+		option = options[0]
+	}
+	group.bind(name, method, Typed(handler, option.TypedOptions), option.Middlewares...)
+	if group.typedRoutes == nil {
+		group.typedRoutes = make(map[string]map[string]typedRoute)
+	}
+	if group.typedRoutes[name] == nil {
+		group.typedRoutes[name] = make(map[string]typedRoute)
+	}
+	group.typedRoutes[name][method] = typedRoute{
+		in:  reflect.TypeOf((*In)(nil)).Elem(),
+		out: reflect.TypeOf((*Out)(nil)).Elem(),
+	}
+	if !reflect.ValueOf(option.Doc).IsZero() {
+		group.Describe(method, name, option.Doc)
+	}
+}
+
+// bindTyped binds the input of a typed handler from the request.
+func bindTyped[In any](ctx *context.Context, options TypedOptions) (In, error) {
+	var in In
+	target := any(&in)
+	if t := reflect.TypeOf(in); t != nil && t.Kind() == reflect.Pointer {
+		// In is a pointer: bind into a new value rather than into a nil pointer
+		value := reflect.New(t.Elem())
+		reflect.ValueOf(&in).Elem().Set(value)
+		target = in
+	}
+	if err := bindTypedBody(ctx, target, options); err != nil {
+		return in, err
+	}
+	err := Binding.BindSources(target, Binding.Sources{Path: ctx.Params(), Query: ctx.R.URL.Query(), Header: ctx.R.Header})
+	return in, ctx.LocalizeError(err)
+}
+
+// bindTypedBody binds the body of the request into target, according to its Content-Type.
+// A request without a body only has the gjango rules of target checked, so that required
+// fields are reported missing.
+func bindTypedBody(ctx *context.Context, target any, options TypedOptions) error {
+	mediaType, _, _ := mime.ParseMediaType(ctx.R.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return ctx.ParseJSON(target, options.DisallowUnknownFields, true)
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		return ctx.BindForm(target)
+	case ctx.R.Body != nil && ctx.R.Body != http.NoBody && ctx.R.ContentLength != 0:
+		return NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"unsupported Content-Type "+mediaType+", expected application/json or a form")
+	}
+	if t := reflect.TypeOf(target).Elem(); t.Kind() == reflect.Struct {
+		// decoded as an empty object, so that the required fields of the body are missing
+		return ctx.LocalizeError(Binding.DecodeJSON(json.NewDecoder(strings.NewReader("{}")), target, false))
+	}
+	return ctx.LocalizeError(Binding.Validate(target))
+}
+
+// renderTyped answers the output of a typed handler.
+func renderTyped(ctx *context.Context, out any) error {
+	if isNil(out) {
+		ctx.W.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	status := http.StatusOK
+	if coder, ok := out.(StatusCoder); ok {
+		status = coder.StatusCode()
+	}
+	if render, ok := out.(Render.Render); ok {
+		return ctx.Render(status, render)
+	}
+	return ctx.Negotiate(status, context.OfferJSON(out), context.OfferXML(out))
+}
+
+// isNil reports whether value is nil or a nil pointer. Nil slices and maps are rendered,
+// as null.
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
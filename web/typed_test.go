@@ -0,0 +1,145 @@
+package web
+
+import (
+	"github.com/Jerry20000730/Gjango/web/Context"
+	"github.com/Jerry20000730/Gjango/web/I18n"
+	"github.com/Jerry20000730/Gjango/web/OpenAPI"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type typedUser struct {
+	Org     string `path:"org" json:"-"`
+	Version int    `header:"X-Version" json:"-"`
+	Page    int    `query:"page" json:"-" gjango:"min=1"`
+	Name    string `json:"name" gjango:"required"`
+}
+
+type typedCreated struct {
+	Org  string `json:"org"`
+	Name string `json:"name"`
+	Page int    `json:"page"`
+}
+
+func (typedCreated) StatusCode() int {
+	return http.StatusCreated
+}
+
+func typedEngine() *Engine {
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	Handle(g, http.MethodPost, "/orgs/:org/users", func(ctx *context.Context, in typedUser) (typedCreated, error) {
+		if in.Name == "taken" {
+			return typedCreated{}, NewHTTPError(http.StatusConflict, "name_taken", "")
+		}
+		return typedCreated{Org: in.Org, Name: in.Name, Page: in.Page}, nil
+	})
+	g.Delete("/users/:id", Typed(func(ctx *context.Context, in *struct {
+		ID int `path:"id" gjango:"required"`
+	}) (*typedCreated, error) {
+		return nil, nil
+	}))
+	return e
+}
+
+func TestTyped(t *testing.T) {
+	e := typedEngine()
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		contentType string
+		status      int
+		contains    string
+	}{
+		{"json", http.MethodPost, "/api/orgs/acme/users?page=2", `{"name": "jo"}`, "application/json", http.StatusCreated, `{"org":"acme","name":"jo","page":2}`},
+		{"form", http.MethodPost, "/api/orgs/acme/users", `name=jo`, "application/x-www-form-urlencoded", http.StatusCreated, `"name":"jo"`},
+		{"missing field", http.MethodPost, "/api/orgs/acme/users", `{}`, "application/json", http.StatusBadRequest, I18n.VALIDATE_REQUIRED},
+		{"missing body", http.MethodPost, "/api/orgs/acme/users", ``, "", http.StatusBadRequest, I18n.VALIDATE_REQUIRED},
+		{"invalid query", http.MethodPost, "/api/orgs/acme/users?page=0", `{"name": "jo"}`, "application/json", http.StatusBadRequest, I18n.VALIDATE_MIN},
+		{"unsupported media type", http.MethodPost, "/api/orgs/acme/users", `name: jo`, "text/yaml", http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"handler error", http.MethodPost, "/api/orgs/acme/users", `{"name": "taken"}`, "application/json", http.StatusConflict, "name_taken"},
+		{"no content", http.MethodDelete, "/api/users/3", ``, "", http.StatusNoContent, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(e, test.method, test.target, strings.NewReader(test.body), "Content-Type", test.contentType)
+			if w.Code != test.status || !strings.Contains(w.Body.String(), test.contains) {
+				t.Errorf("expected %d %s, got %d %s", test.status, test.contains, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTypedNegotiation(t *testing.T) {
+	e := typedEngine()
+	w := serve(e, http.MethodPost, "/api/orgs/acme/users", strings.NewReader(`{"name": "jo"}`),
+		"Content-Type", "application/json", "Accept", "application/xml", "X-Version", "2")
+	if w.Code != http.StatusCreated || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xml") ||
+		!strings.Contains(w.Body.String(), "<Name>jo</Name>") {
+		t.Errorf("unexpected response %d %s %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
+func TestHandleRecordsTypes(t *testing.T) {
+	e := typedEngine()
+	g := e.Router.routerGroups[0]
+	route, ok := g.typedRoutes["/orgs/:org/users"][http.MethodPost]
+	if !ok || route.in.Name() != "typedUser" || route.out.Name() != "typedCreated" {
+		t.Errorf("unexpected typed route %+v", route)
+	}
+}
+
+func TestHandleDoc(t *testing.T) {
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	echo := func(ctx *context.Context, in typedUser) (typedCreated, error) {
+		return typedCreated{Name: in.Name}, nil
+	}
+	Handle(g, http.MethodPost, "/users", echo, HandleOptions{Doc: RouteDoc{Summary: "Create a user", Tags: []string{"users"}}})
+	Handle(g, http.MethodPut, "/users", echo)
+	if doc := g.routeDocs["/users"][http.MethodPost]; doc.Summary != "Create a user" || len(doc.Tags) != 1 {
+		t.Errorf("unexpected route doc %+v", doc)
+	}
+	if _, ok := g.routeDocs["/users"][http.MethodPut]; ok {
+		t.Error("a route without a doc should not be described")
+	}
+	item := e.OpenAPI(OpenAPI.Info{Title: "test", Version: "1"}).Paths["/api/users"]
+	if item == nil || item.Operation(http.MethodPost) == nil || item.Operation(http.MethodPost).Summary != "Create a user" {
+		t.Errorf("unexpected path item %+v", item)
+	}
+}
+
+func TestTypedDisallowUnknownFields(t *testing.T) {
+	e := NewEngine()
+	g := e.Router.NewGroup("api")
+	echo := func(ctx *context.Context, in typedUser) (typedCreated, error) {
+		return typedCreated{Name: in.Name}, nil
+	}
+	Handle(g, http.MethodPost, "/strict", echo, HandleOptions{TypedOptions: TypedOptions{DisallowUnknownFields: true}})
+	Handle(g, http.MethodPost, "/lenient", echo)
+	body := `{"name": "jo", "role": "admin"}`
+	if w := serve(e, http.MethodPost, "/api/strict", strings.NewReader(body), "Content-Type", "application/json"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(e, http.MethodPost, "/api/lenient", strings.NewReader(body), "Content-Type", "application/json"); w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTypedInvalidTags(t *testing.T) {
+	type coupon struct {
+		Code string `json:"code" gjango:"pattern=^[A-Z+$"`
+	}
+	defer func() {
+		if value := recover(); value == nil || !strings.Contains(value.(string), "the field [Code] of web.coupon has an invalid pattern rule") {
+			t.Errorf("unexpected panic %v", value)
+		}
+	}()
+	g := NewEngine().Router.NewGroup("api")
+	Handle(g, http.MethodPost, "/coupons", func(ctx *context.Context, in coupon) (*coupon, error) {
+		return &in, nil
+	})
+}